@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/config"
+)
+
+// subcommand runs the subcommands of neosearch-cli:
+//
+//	backup <index> <file>     writes a backup of the index to file
+//	restore <index> <file>    creates the index from the backup file
+//	fsck <index>              checks the posting lists, repaired with -r
+//
+// The file "-" is the standard output or input. The indices of the data
+// directory can't be open by other processes, backups of a running
+// server are taken with POST /:index/_backup.
+func subcommand(dataDir string, args []string, repair bool) error {
+	var (
+		file     *os.File
+		manifest *neosearch.BackupManifest
+		err      error
+	)
+
+	if len(args) > 0 && args[0] == "fsck" {
+		return fsck(dataDir, args[1:], repair)
+	}
+
+	if len(args) != 3 || (args[0] != "backup" && args[0] != "restore") {
+		return fmt.Errorf("Usage: neosearch-cli -d <data-dir> backup|restore <index> <file>")
+	}
+
+	neo := openNeoSearch(dataDir)
+
+	defer neo.Close()
+
+	indexName, fileName := args[1], args[2]
+
+	if args[0] == "backup" {
+		var writer io.Writer = os.Stdout
+
+		if fileName != "-" {
+			if file, err = os.Create(fileName); err != nil {
+				return err
+			}
+
+			defer file.Close()
+			writer = file
+		}
+
+		if manifest, err = neo.Backup(indexName, writer); err != nil {
+			return err
+		}
+	} else {
+		var reader io.Reader = os.Stdin
+
+		if fileName != "-" {
+			if file, err = os.Open(fileName); err != nil {
+				return err
+			}
+
+			defer file.Close()
+			reader = file
+		}
+
+		if manifest, err = neo.Restore(indexName, reader); err != nil {
+			return err
+		}
+	}
+
+	for _, storage := range manifest.Storages {
+		fmt.Fprintf(os.Stderr, "%s: %d keys\n", storage.Name, storage.Keys)
+	}
+
+	fmt.Fprintf(os.Stderr, "%s of index '%s' done: %d storages\n", args[0], indexName, len(manifest.Storages))
+	return nil
+}
+
+func openNeoSearch(dataDir string) *neosearch.NeoSearch {
+	cfg := config.NewConfig()
+	cfg.Option(config.DataDir(dataDir))
+
+	return neosearch.New(cfg)
+}
@@ -2,29 +2,116 @@ package main
 
 import (
 	"fmt"
+	"hash/fnv"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sync"
 
 	"github.com/NeowayLabs/neosearch/cmd/cli/parser"
 	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/index"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/store"
 )
 
-func batch(ng *engine.Engine, filePath string) error {
+// batch executes the commands of the file by parallel workers, each one
+// with its own engine. The commands of a database are always executed by
+// the same worker, in the order of the file, then dumps of
+// neosearch-dump are restored in parallel by database. The config files
+// dumped to <file>.config are restored to the indices after the commands.
+func batch(kvConfig store.KVConfig, filePath string, parallel int) error {
 	file, err := os.Open(filePath)
 
 	if err != nil {
-		panic(err)
+		return err
 	}
 
+	defer file.Close()
+
 	commands := []engine.Command{}
 
-	err = parser.FromReader(file, &commands)
+	if err = parser.FromReader(file, &commands); err != nil {
+		return err
+	}
+
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	workers := make([][]engine.Command, parallel)
 
 	for _, cmd := range commands {
-		d, err := ng.Execute(cmd)
-		if err != nil {
-			fmt.Println(err)
-		} else {
-			fmt.Println("Data: ", d)
+		hash := fnv.New32a()
+		hash.Write([]byte(cmd.Index + "." + cmd.Database))
+		worker := hash.Sum32() % uint32(parallel)
+		workers[worker] = append(workers[worker], cmd)
+	}
+
+	var wg sync.WaitGroup
+
+	for _, cmds := range workers {
+		wg.Add(1)
+
+		go func(cmds []engine.Command) {
+			defer wg.Done()
+
+			ng := engine.New(&engine.Config{
+				KVConfig: kvConfig,
+			})
+
+			defer ng.Close()
+
+			for _, cmd := range cmds {
+				result, err := ng.Execute(cmd)
+				if err != nil {
+					fmt.Println(err)
+				} else {
+					printResult(cmd, result)
+				}
+			}
+		}(cmds)
+	}
+
+	wg.Wait()
+
+	dataDir, _ := kvConfig["dataDir"].(string)
+	return restoreConfig(dataDir, filePath+".config")
+}
+
+// restoreConfig copies the config files of each index of configDir, the
+// <index>/<file> written by neosearch-dump, to the index directories
+func restoreConfig(dataDir, configDir string) error {
+	indices, err := ioutil.ReadDir(configDir)
+
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, indexDir := range indices {
+		if !indexDir.IsDir() {
+			continue
+		}
+
+		if err = os.MkdirAll(filepath.Join(dataDir, indexDir.Name()), 0755); err != nil {
+			return err
+		}
+
+		for _, name := range index.ConfigFiles {
+			content, err := ioutil.ReadFile(filepath.Join(configDir, indexDir.Name(), name))
+
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			err = ioutil.WriteFile(filepath.Join(dataDir, indexDir.Name(), name), content, 0644)
+
+			if err != nil {
+				return err
+			}
 		}
 	}
 
@@ -4,17 +4,18 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/NeowayLabs/neosearch/cmd/cli/parser"
 	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
-	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
 	"github.com/peterh/liner"
 )
 
 var (
 	historyFile = "cli.history.txt"
-	keywords    = []string{"using", "set", "get", "mergeset", "delete"}
+	keywords    = []string{"using", "set", "get", "mergeset", "mergedel", "incr", "decr",
+		"delete", "scan", "keys", "count", "stats", "limit"}
 )
 
 func setupNeosearchDir(homePath string) error {
@@ -77,21 +78,12 @@ func cli(ng *engine.Engine, homePath string) error {
 			fmt.Println(err)
 		} else {
 			for _, cmd := range command {
-				data, err := ng.Execute(cmd)
+				result, err := ng.Execute(cmd)
 				if err != nil {
 					fmt.Println("ERROR: ", err)
 				} else {
 					fmt.Printf("%s: Success\n", cmd.Command)
-
-					if data != nil {
-						ext := cmd.Index[len(cmd.Index)-3 : len(cmd.Index)]
-						if ext == "idx" {
-							uints := utils.GetUint64Array(data)
-							fmt.Printf("Result[%s]: %v\n", ext, uints)
-						} else {
-							fmt.Printf("Result: %s\n", string(data))
-						}
-					}
+					printResult(cmd, result)
 				}
 
 			}
@@ -110,3 +102,62 @@ func cli(ng *engine.Engine, homePath string) error {
 	fmt.Println("Exiting...")
 	return nil
 }
+
+// printResult prints the result of cmd decoded by the key and value
+// types of the result.
+func printResult(cmd engine.Command, result *engine.Result) {
+	switch cmd.Command {
+	case "get":
+		if result.Data == nil {
+			return
+		}
+
+		value, err := result.DataValue()
+
+		if err != nil {
+			fmt.Println("ERROR: ", err)
+			return
+		}
+
+		fmt.Printf("Result: %v\n", value)
+	case "scan", "keys":
+		for i := range result.Entries {
+			key, err := result.Key(i)
+
+			if err != nil {
+				fmt.Println("ERROR: ", err)
+				return
+			}
+
+			if cmd.Command == "keys" {
+				fmt.Printf("%v\n", key)
+				continue
+			}
+
+			value, err := result.Value(i)
+
+			if err != nil {
+				fmt.Println("ERROR: ", err)
+				return
+			}
+
+			fmt.Printf("%v: %v\n", key, value)
+		}
+
+		fmt.Printf("(%d entries)\n", result.Count)
+	case "count":
+		fmt.Printf("Count: %d\n", result.Count)
+	case "stats":
+		var names []string
+
+		for name := range result.Stats {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			fmt.Printf("%s: %d\n", name, result.Stats[name])
+		}
+	}
+}
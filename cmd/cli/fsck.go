@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// fsck checks the posting lists of an index against its documents and,
+// when repair is true, rewrites the inconsistent ones. It fails when problems
+// are found and not repaired.
+func fsck(dataDir string, args []string, repair bool) error {
+	if len(args) != 1 {
+		return fmt.Errorf("Usage: neosearch-cli -d <data-dir> [-r] fsck <index>")
+	}
+
+	neo := openNeoSearch(dataDir)
+
+	defer neo.Close()
+
+	ind, err := neo.OpenIndex(args[0])
+
+	if err != nil {
+		return err
+	}
+
+	report, err := ind.Fsck(repair)
+
+	if err != nil {
+		return err
+	}
+
+	for _, problem := range report.Problems {
+		fmt.Fprintf(os.Stderr, "%s: %s %v", problem.Storage, problem.Kind, problem.Key)
+
+		if len(problem.IDs) > 0 {
+			fmt.Fprintf(os.Stderr, " ids %v", problem.IDs)
+		}
+
+		if problem.Message != "" {
+			fmt.Fprintf(os.Stderr, ": %s", problem.Message)
+		}
+
+		fmt.Fprintln(os.Stderr)
+	}
+
+	fmt.Fprintf(os.Stderr, "fsck of index '%s' done: %d documents, %d storages, %d posting lists, %d problems\n",
+		ind.Name, report.Documents, len(report.Storages), report.Postings, len(report.Problems))
+
+	if len(report.Problems) > 0 && !report.Repaired {
+		return fmt.Errorf("%d problems found, run with -r to repair them", len(report.Problems))
+	}
+
+	return nil
+}
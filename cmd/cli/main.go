@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
@@ -10,13 +11,16 @@ import (
 
 func main() {
 	var fileOpt, dataDirOpt, homeOpt string
-	var helpOpt, debugOpt bool
+	var helpOpt, debugOpt, repairOpt bool
+	var parallelOpt int
 
 	optarg.Add("f", "from-file", "Read NeoSearch low-level instructions from file", "")
 	optarg.Add("d", "data-dir", "Data directory", "")
 	optarg.Add("t", "trace-debug", "Enable trace for debug", false)
 	optarg.Add("h", "help", "Display this help", false)
 	optarg.Add("m", "home", "User home for store command history", "")
+	optarg.Add("p", "parallel", "Number of parallel workers executing the commands from file", 1)
+	optarg.Add("r", "repair", "Repair the problems found by fsck", false)
 
 	for opt := range optarg.Parse() {
 		switch opt.ShortName {
@@ -29,6 +33,12 @@ func main() {
 		case "m":
 			homeOpt = opt.String()
 			break
+		case "p":
+			parallelOpt = opt.Int()
+			break
+		case "r":
+			repairOpt = true
+			break
 		case "t":
 			debugOpt = true
 			break
@@ -53,18 +63,34 @@ func main() {
 		dataDirOpt, _ = os.Getwd()
 	}
 
+	kvConfig := store.KVConfig{
+		"dataDir": dataDirOpt,
+		"debug":   debugOpt,
+	}
+
+	if len(optarg.Remainder) > 0 {
+		if err := subcommand(dataDirOpt, optarg.Remainder, repairOpt); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if fileOpt != "" {
+		if err := batch(kvConfig, fileOpt, parallelOpt); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	ng := engine.New(&engine.Config{
-		KVConfig: store.KVConfig{
-			"dataDir": dataDirOpt,
-			"debug":   debugOpt,
-		},
+		KVConfig: kvConfig,
 	})
 
 	defer ng.Close()
 
-	if fileOpt != "" {
-		batch(ng, fileOpt)
-	} else {
-		cli(ng, homeOpt)
-	}
+	cli(ng, homeOpt)
 }
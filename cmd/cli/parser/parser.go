@@ -8,7 +8,6 @@ import (
 	"strings"
 
 	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
-	"github.com/NeowayLabs/neosearch/lib/neosearch/index"
 	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
 	"github.com/iNamik/go_lexer"
 )
@@ -22,7 +21,10 @@ type parserState struct {
 	IsEscapedDoubleQuotedString bool
 	IsEscapedSingleQuotedString bool
 	IsCastOpen                  bool
+	IsLimit                     bool
+	IsNegative                  bool
 	KVType                      uint8
+	Using                       string
 }
 
 // We define our lexer tokens starting from the pre-defined EOF token
@@ -43,6 +45,8 @@ const (
 	TokenGet
 	TokenMergeSet
 	TokenDelete
+	TokenEscapedBackslash
+	TokenEscapedByte
 )
 
 var bytesNonWord = []byte{' ', '\t', '\f', '\v', '\n', '\r', ';', '"', '\'', '\\', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9'}
@@ -68,14 +72,23 @@ const charSemicolon = ';'
 var commandsAvailable = []string{
 	"set",
 	"mergeset",
+	"mergedel",
+	"incr",
+	"decr",
 	"get",
 	"delete",
 	"batch",
 	"flushbatch",
+	"scan",
+	"keys",
+	"count",
+	"stats",
 }
 
-// Checks if the given command is valid.
+// Checks if the given command is valid. Commands are case insensitive.
 func isValidCommand(cmd string) bool {
+	cmd = strings.ToLower(cmd)
+
 	for i := 0; i < len(commandsAvailable); i++ {
 		if cmd == commandsAvailable[i] {
 			return true
@@ -92,11 +105,135 @@ func setQuotedString(token string, command *engine.Command, pState *parserState)
 		command.Key = []byte(string(command.Key) + token)
 		command.KeyType = engine.TypeString
 	} else if pState.IsValue {
+		if command.Command == "scan" {
+			command.EndKey = []byte(string(command.EndKey) + token)
+			return
+		}
+
 		command.Value = []byte(string(command.Value) + token)
 		command.ValueType = engine.TypeString
 	}
 }
 
+// setEmptyString sets the key or value of command to the empty string
+// "", that has no token between the quotes.
+func setEmptyString(command *engine.Command, pState *parserState) {
+	if pState.IsCommand {
+		command.Key = []byte{}
+		command.KeyType = engine.TypeString
+		pState.IsCommand = false
+		pState.IsValue = true
+	} else if pState.IsValue {
+		if command.Command == "scan" {
+			command.EndKey = []byte{}
+		} else {
+			command.Value = []byte{}
+			command.ValueType = engine.TypeString
+		}
+
+		pState.IsValue = false
+	}
+}
+
+// setUsing sets the index and database of command from the
+// <index>.<database> name of USING.
+func setUsing(command *engine.Command, pState *parserState) error {
+	indexDbParts := strings.Split(pState.Using, ".")
+
+	if len(indexDbParts) < 2 {
+		return fmt.Errorf("Invalid USING <index>.<database>: %s", pState.Using)
+	}
+
+	command.Index = indexDbParts[0]
+	command.Database = strings.Join(indexDbParts[1:], ".")
+	pState.IsUsing = false
+	pState.Using = ""
+	return nil
+}
+
+// unescape returns the byte of the escape sequences \\ and \xNN
+func unescape(token []byte) (string, error) {
+	if len(token) == 4 && token[1] == 'x' {
+		b, err := strconv.ParseUint(string(token[2:]), 16, 8)
+
+		if err != nil {
+			return "", fmt.Errorf("Invalid escape sequence: %s", token)
+		}
+
+		return string([]byte{byte(b)}), nil
+	}
+
+	return string(token[1:]), nil
+}
+
+// parseCast returns the type of the cast opened by token, like uint( and
+// float(. A minus sign after the parenthesis is the sign of the number.
+func parseCast(token string) (uint8, bool, bool) {
+	negative := strings.HasSuffix(token, "(-")
+
+	switch strings.TrimSuffix(token, "-") {
+	case "uint(":
+		return engine.TypeUint, negative, true
+	case "int(":
+		return engine.TypeInt, negative, true
+	case "float(":
+		return engine.TypeFloat, negative, true
+	}
+
+	return 0, false, false
+}
+
+// parseBool returns the bytes of the boolean literals bool(true) and
+// bool(false)
+func parseBool(token string) ([]byte, bool) {
+	switch token {
+	case "bool(true)":
+		return utils.BoolToBytes(true), true
+	case "bool(false)":
+		return utils.BoolToBytes(false), true
+	}
+
+	return nil, false
+}
+
+// acceptsLimit returns true if the next LIMIT keyword is part of command
+func acceptsLimit(command engine.Command) bool {
+	return (command.Command == "scan" && command.EndKey != nil) ||
+		(command.Command == "keys" && command.Key != nil)
+}
+
+// parseKeyNumber converts a number token to bytes of type kvType.
+// Integers are parsed as int by default.
+func parseKeyNumber(tokenValue string, kvType uint8) ([]byte, uint8, error) {
+	if kvType == engine.TypeFloat || strings.Contains(tokenValue, ".") {
+		tokenFloatValue, err := strconv.ParseFloat(tokenValue, 64)
+
+		if err != nil {
+			return nil, 0, fmt.Errorf("Failed to convert %s to float", tokenValue)
+		}
+
+		return utils.Float64ToBytes(tokenFloatValue), engine.TypeFloat, nil
+	}
+
+	if kvType == engine.TypeUint {
+		tokenUintValue, err := strconv.ParseUint(tokenValue, 10, 64)
+
+		if err != nil {
+			return nil, 0, fmt.Errorf("Failed to convert %s to unsigned integer", tokenValue)
+		}
+
+		return utils.Uint64ToBytes(tokenUintValue), engine.TypeUint, nil
+	}
+
+	tokenIntValue, err := strconv.ParseInt(tokenValue, 10, 64)
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("Failed to convert %s to integer", tokenValue)
+	}
+
+	return utils.Int64ToBytes(tokenIntValue), engine.TypeInt, nil
+}
+
 func validateBatch(cmd engine.Command) bool {
 	if cmd.Command == "batch" && cmd.Index != "" &&
 		cmd.Key == nil && cmd.Value == nil {
@@ -115,8 +252,18 @@ func validateFlushBatch(cmd engine.Command) bool {
 	return false
 }
 
+// isUintSetter reports if the command value is a unsigned integer
+func isUintSetter(command string) bool {
+	switch command {
+	case "mergeset", "mergedel", "incr", "decr":
+		return true
+	}
+
+	return false
+}
+
 func validateSetters(cmd engine.Command) bool {
-	if cmd.Command == "set" || cmd.Command == "mergeset" {
+	if cmd.Command == "set" || isUintSetter(cmd.Command) {
 		if cmd.Index != "" && cmd.Key != nil &&
 			cmd.Value != nil {
 			return true
@@ -126,6 +273,34 @@ func validateSetters(cmd engine.Command) bool {
 	return false
 }
 
+func validateInspectors(cmd engine.Command) bool {
+	if cmd.Command == "count" || cmd.Command == "stats" {
+		if cmd.Index != "" && cmd.Key == nil && cmd.Value == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validateScan(cmd engine.Command) bool {
+	if cmd.Command == "scan" && cmd.Index != "" && cmd.Key != nil &&
+		cmd.EndKey != nil && cmd.Value == nil {
+		return true
+	}
+
+	return false
+}
+
+func validateKeys(cmd engine.Command) bool {
+	if cmd.Command == "keys" && cmd.Index != "" && cmd.Key != nil &&
+		cmd.Value == nil {
+		return true
+	}
+
+	return false
+}
+
 func validateGetters(cmd engine.Command) bool {
 	if cmd.Command == "get" || cmd.Command == "delete" {
 		if cmd.Index != "" && cmd.Key != nil &&
@@ -142,7 +317,7 @@ func validateCommand(cmd engine.Command) bool {
 		return validateSetters(cmd)
 	} else if cmd.Command == "get" {
 		return validateGetters(cmd)
-	} else if cmd.Command == "mergeset" {
+	} else if isUintSetter(cmd.Command) {
 		return validateSetters(cmd)
 	} else if cmd.Command == "delete" {
 		return validateGetters(cmd)
@@ -150,6 +325,12 @@ func validateCommand(cmd engine.Command) bool {
 		return validateBatch(cmd)
 	} else if cmd.Command == "flushbatch" {
 		return validateFlushBatch(cmd)
+	} else if cmd.Command == "scan" {
+		return validateScan(cmd)
+	} else if cmd.Command == "keys" {
+		return validateKeys(cmd)
+	} else if cmd.Command == "count" || cmd.Command == "stats" {
+		return validateInspectors(cmd)
 	}
 
 	return false
@@ -186,31 +367,31 @@ func FromReader(file io.Reader, listCommands *[]engine.Command) error {
 
 					setQuotedString(tokenValue, &command, &pState)
 
+					// TokenWord is the LIMIT keyword of scan and keys?
+					// using <index>.<database> keys <key> limit <N>
+				} else if strings.ToLower(tokenValue) == "limit" &&
+					!pState.IsCastOpen && acceptsLimit(command) {
+					pState.IsLimit = true
+					pState.IsValue = false
+
 					// TokenWord is the Index name?
 					// using <TokenWord> ...
+					// names with digits are split in several tokens
 				} else if pState.IsUsing {
-					indexDbParts := strings.Split(tokenValue, ".")
-
-					if len(indexDbParts) < 2 {
-						return fmt.Errorf("Invalid USING <index>.<database>: %s", tokenValue)
-					}
-
-					command.Index = indexDbParts[0]
-					command.Database = strings.Join(indexDbParts[1:], ".")
-					pState.IsUsing = false
+					pState.Using += tokenValue
 
 					// TokenWord is the key of command?
 					// using document.db mergeset <TokenWord> ...
 				} else if pState.IsCommand {
-					if strings.HasPrefix(tokenValue, "int(") {
-						pState.KVType = engine.TypeInt
-						pState.IsCastOpen = true
-					} else if strings.HasPrefix(tokenValue, "uint(") {
-						pState.KVType = engine.TypeUint
-						pState.IsCastOpen = true
-					} else if strings.HasPrefix(tokenValue, "float(") {
-						pState.KVType = engine.TypeFloat
+					if kvType, negative, ok := parseCast(tokenValue); ok {
+						pState.KVType = kvType
+						pState.IsNegative = negative
 						pState.IsCastOpen = true
+					} else if key, ok := parseBool(tokenValue); ok {
+						command.Key = key
+						command.KeyType = engine.TypeBool
+						pState.IsCommand = false
+						pState.IsValue = true
 					} else {
 						command.Key = []byte(tokenValue)
 						command.KeyType = engine.TypeString
@@ -223,15 +404,17 @@ func FromReader(file io.Reader, listCommands *[]engine.Command) error {
 				} else if pState.IsValue {
 					if tokenValue == ")" && pState.IsCastOpen {
 						pState.IsCastOpen = false
-					} else if strings.HasPrefix(tokenValue, "uint(") {
-						pState.IsCastOpen = true
-						pState.KVType = engine.TypeUint
-					} else if strings.HasPrefix(tokenValue, "int(") {
+					} else if kvType, negative, ok := parseCast(tokenValue); ok {
 						pState.IsCastOpen = true
-						pState.KVType = engine.TypeInt
-					} else if strings.HasPrefix(tokenValue, "float(") {
-						pState.IsCastOpen = true
-						pState.KVType = engine.TypeFloat
+						pState.IsNegative = negative
+						pState.KVType = kvType
+					} else if value, ok := parseBool(tokenValue); ok && command.Command != "scan" {
+						command.Value = value
+						command.ValueType = engine.TypeBool
+						pState.IsValue = false
+					} else if command.Command == "scan" {
+						command.EndKey = []byte(tokenValue)
+						pState.IsValue = false
 					} else {
 						command.Value = []byte(tokenValue)
 						command.ValueType = engine.TypeString
@@ -262,6 +445,11 @@ func FromReader(file io.Reader, listCommands *[]engine.Command) error {
 				}
 			}
 		case TokenDoubleQuotedString:
+			if !pState.IsDoubleQuotedString && !pState.IsSingleQuotedString && len(t.Bytes()) == 2 {
+				setEmptyString(&command, &pState)
+				break
+			}
+
 			if pState.IsDoubleQuotedString {
 				if pState.IsCommand {
 					pState.IsCommand = false
@@ -311,21 +499,35 @@ func FromReader(file io.Reader, listCommands *[]engine.Command) error {
 			} else if pState.IsSingleQuotedString {
 				setQuotedString(string(t.Bytes()), &command, &pState)
 			}
-		case TokenSpace:
-			// Spaces only makes difference inside quotes
-			if pState.IsDoubleQuotedString || pState.IsSingleQuotedString {
-				setQuotedString(string(t.Bytes()), &command, &pState)
+		case TokenEscapedBackslash, TokenEscapedByte:
+			if !pState.IsDoubleQuotedString && !pState.IsSingleQuotedString {
+				return errors.New("Escape sequence outside of quoted string: " + string(t.Bytes()))
 			}
 
-		case TokenNewline:
-			// New lines only makes difference inside quotes
+			token, err := unescape(t.Bytes())
+
+			if err != nil {
+				return err
+			}
+
+			setQuotedString(token, &command, &pState)
+		case TokenSpace, TokenNewline:
+			// Spaces and new lines only makes difference inside quotes
 			if pState.IsDoubleQuotedString || pState.IsSingleQuotedString {
 				setQuotedString(string(t.Bytes()), &command, &pState)
+			} else if pState.IsUsing && pState.Using != "" {
+				if err := setUsing(&command, &pState); err != nil {
+					return err
+				}
 			}
 		case TokenSemiColon:
 			if pState.IsSingleQuotedString || pState.IsDoubleQuotedString {
 				setQuotedString(string(t.Bytes()), &command, &pState)
 			} else {
+				if !validateCommand(command) {
+					return fmt.Errorf("Invalid command: %v", command)
+				}
+
 				*listCommands = append(*listCommands, command)
 				command = engine.Command{}
 				pState = parserState{}
@@ -333,48 +535,32 @@ func FromReader(file io.Reader, listCommands *[]engine.Command) error {
 		case TokenNumbers:
 			tokenValue := string(t.Bytes())
 
+			if pState.IsNegative {
+				tokenValue = "-" + tokenValue
+				pState.IsNegative = false
+			}
+
 			if pState.IsSingleQuotedString || pState.IsDoubleQuotedString {
 				setQuotedString(tokenValue, &command, &pState)
-			} else if pState.IsUsing {
-				if index.ValidateIndexName(tokenValue) {
-					command.Index = tokenValue
-					pState.IsUsing = false
+			} else if pState.IsLimit {
+				limit, err := strconv.ParseUint(tokenValue, 10, 64)
 
-					// TokenNumbers is the key of command?
-					// using document.db mergeset <TokenNumbers> ...
+				if err != nil || strings.Contains(tokenValue, ".") {
+					return fmt.Errorf("Invalid limit: %s", tokenValue)
 				}
-			} else if pState.IsCommand {
-				var (
-					keyBytes []byte
-					keyType  uint8
-				)
-
-				if strings.Contains(tokenValue, ".") {
-					tokenFloatValue, err := strconv.ParseFloat(tokenValue, 64)
 
-					if err != nil {
-						return fmt.Errorf("Failed to convert %s to float", tokenValue)
-					}
-
-					keyBytes = utils.Float64ToBytes(tokenFloatValue)
-					keyType = engine.TypeFloat
-				} else {
-					tokenIntValue, err := strconv.Atoi(tokenValue)
+				command.Limit = limit
+				pState.IsLimit = false
+			} else if pState.IsUsing {
+				pState.Using += tokenValue
 
-					if err != nil {
-						return fmt.Errorf("Failed to convert %s to integer", tokenValue)
-					}
+				// TokenNumbers is the key of command?
+				// using document.db mergeset <TokenNumbers> ...
+			} else if pState.IsCommand {
+				keyBytes, keyType, err := parseKeyNumber(tokenValue, pState.KVType)
 
-					if pState.KVType == engine.TypeUint {
-						keyBytes = utils.Uint64ToBytes(uint64(tokenIntValue))
-						keyType = engine.TypeUint
-					} else if pState.KVType == engine.TypeFloat {
-						keyBytes = utils.Float64ToBytes(float64(tokenIntValue))
-						keyType = engine.TypeFloat
-					} else {
-						keyBytes = utils.Int64ToBytes(int64(tokenIntValue))
-						keyType = engine.TypeInt
-					}
+				if err != nil {
+					return err
 				}
 
 				command.Key = keyBytes
@@ -384,52 +570,45 @@ func FromReader(file io.Reader, listCommands *[]engine.Command) error {
 
 				pState.KVType = 0
 
+				// TokenNumbers is the end key of scan?
+				// using document.db scan <key> <TokenNumbers>
+			} else if pState.IsValue && command.Command == "scan" {
+				endBytes, endType, err := parseKeyNumber(tokenValue, pState.KVType)
+
+				if err != nil {
+					return err
+				}
+
+				if endType != command.KeyType {
+					return fmt.Errorf("Scan keys must have the same type: %s", tokenValue)
+				}
+
+				command.EndKey = endBytes
+				pState.IsValue = false
+				pState.KVType = 0
+
 				// TokenNumbers is the command value?
 				// using document.db mergeset name <TokenNumbers>
 			} else if pState.IsValue {
-				var (
-					valueBytes      []byte
-					valueType       uint8
-					tokenIntValue   int64
-					tokenFloatValue float64
-					err             error
-				)
-
-				if strings.Contains(tokenValue, ".") {
-					tokenFloatValue, err = strconv.ParseFloat(tokenValue, 64)
-
-					if err != nil {
-						return fmt.Errorf("Failed to convert %s to float", tokenValue)
-					}
+				kvType := pState.KVType
 
-					valueBytes = utils.Float64ToBytes(tokenFloatValue)
-					valueType = engine.TypeFloat
-				} else {
-					tokenInt, err := strconv.Atoi(tokenValue)
-
-					if err != nil {
-						return fmt.Errorf("Failed to convert %s to integer", tokenValue)
-					}
-
-					tokenIntValue = int64(tokenInt)
-					valueBytes = utils.Int64ToBytes(int64(tokenIntValue))
-					valueType = engine.TypeInt
+				// integers are unsigned by default in the commands of sets
+				if kvType == 0 && isUintSetter(command.Command) {
+					kvType = engine.TypeUint
 				}
 
-				if command.Command == "mergeset" {
-					if valueType == engine.TypeFloat {
-						return fmt.Errorf("Failed to parse command. "+
-							"MergeSet value shall be a unsigned integer "+
-							"value: %v", tokenValue)
-					}
+				valueBytes, valueType, err := parseKeyNumber(tokenValue, kvType)
 
-					command.Value = utils.Uint64ToBytes(uint64(tokenIntValue))
-					command.ValueType = engine.TypeUint
-				} else {
-					command.Value = valueBytes
-					command.ValueType = valueType
+				if isUintSetter(command.Command) && (err != nil || valueType != engine.TypeUint) {
+					return fmt.Errorf("Failed to parse command. "+
+						"%s value shall be a unsigned integer "+
+						"value: %v", strings.ToUpper(command.Command), tokenValue)
+				} else if err != nil {
+					return err
 				}
 
+				command.Value = valueBytes
+				command.ValueType = valueType
 				pState.IsValue = false
 			}
 		default:
@@ -462,10 +641,23 @@ func lexFunc(l lexer.Lexer) lexer.StateFn {
 		return nil // We're done here
 	}
 
-	if l.MatchMinMaxBytes(bytesEscapedDoubleQuotedString, 2, 2) {
+	if l.PeekRune(0) == '\\' && l.PeekRune(1) == '\\' {
+		l.NextRune()
+		l.NextRune()
+		l.EmitTokenWithBytes(TokenEscapedBackslash)
+
+		// Escaped byte \xNN
+	} else if l.PeekRune(0) == '\\' && l.PeekRune(1) == 'x' &&
+		isHexDigit(l.PeekRune(2)) && isHexDigit(l.PeekRune(3)) {
+		for i := 0; i < 4; i++ {
+			l.NextRune()
+		}
+
+		l.EmitTokenWithBytes(TokenEscapedByte)
+	} else if l.PeekRune(0) == '\\' && l.MatchMinMaxBytes(bytesEscapedDoubleQuotedString, 2, 2) {
 		l.EmitTokenWithBytes(TokenEscapedDoubleQuotedString)
 
-	} else if l.MatchMinMaxBytes(bytesEscapedSingleQuotedString, 2, 2) {
+	} else if l.PeekRune(0) == '\\' && l.MatchMinMaxBytes(bytesEscapedSingleQuotedString, 2, 2) {
 		l.EmitTokenWithBytes(TokenEscapedSingleQuotedString)
 
 	} else if l.MatchOneOrMoreBytes(bytesDoubleQuotedStrings) {
@@ -478,6 +670,12 @@ func lexFunc(l lexer.Lexer) lexer.StateFn {
 		l.EmitTokenWithBytes(TokenWord)
 
 	} else if l.MatchOneOrMoreBytes(bytesIntegers) {
+		// Fraction of float numbers
+		if l.PeekRune(0) == '.' && l.PeekRune(1) >= '0' && l.PeekRune(1) <= '9' {
+			l.NextRune()
+			l.MatchOneOrMoreBytes(bytesIntegers)
+		}
+
 		l.EmitTokenWithBytes(TokenNumbers)
 
 		// Space run
@@ -508,3 +706,7 @@ func lexFunc(l lexer.Lexer) lexer.StateFn {
 
 	return lexFunc
 }
+
+func isHexDigit(r rune) bool {
+	return (r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+}
@@ -139,6 +139,161 @@ func TestCliParserFromReader(t *testing.T) {
 
 }
 
+func TestCliParserInspectCommands(t *testing.T) {
+	compareArray(`using sample.name_string.idx scan 'a' 'c' limit 10;
+             using sample.id_uint.idx scan uint(1) uint(100);
+             using sample.name_string.idx keys "neo" limit 2;
+             using sample.name_string.idx keys neo;
+             using sample.document.db count;
+             using sample.document.db stats
+        `, []engine.Command{
+		engine.Command{
+			Index:    "sample",
+			Database: "name_string.idx",
+			Command:  "scan",
+			Key:      []byte("a"),
+			KeyType:  engine.TypeString,
+			EndKey:   []byte("c"),
+			Limit:    10,
+		},
+		engine.Command{
+			Index:    "sample",
+			Database: "id_uint.idx",
+			Command:  "scan",
+			Key:      utils.Uint64ToBytes(1),
+			KeyType:  engine.TypeUint,
+			EndKey:   utils.Uint64ToBytes(100),
+		},
+		engine.Command{
+			Index:    "sample",
+			Database: "name_string.idx",
+			Command:  "keys",
+			Key:      []byte("neo"),
+			KeyType:  engine.TypeString,
+			Limit:    2,
+		},
+		engine.Command{
+			Index:    "sample",
+			Database: "name_string.idx",
+			Command:  "keys",
+			Key:      []byte("neo"),
+			KeyType:  engine.TypeString,
+		},
+		engine.Command{
+			Index:    "sample",
+			Database: "document.db",
+			Command:  "count",
+		},
+		engine.Command{
+			Index:    "sample",
+			Database: "document.db",
+			Command:  "stats",
+		},
+	}, t)
+
+	// scan requires the end key
+	shouldThrowError(`using sample.name_string.idx scan 'a';`, t)
+
+	// scan keys must have the same type
+	shouldThrowError(`using sample.id_uint.idx scan uint(1) 10;`, t)
+
+	// count doesn't have keys
+	shouldThrowError(`using sample.document.db count 'a';`, t)
+
+	// invalid limit
+	shouldThrowError(`using sample.name_string.idx keys 'a' limit 1.5;`, t)
+}
+
+// TestCliParserReverse checks that the commands written by
+// engine.Command.Reverse, as neosearch-dump does, are parsed back.
+func TestCliParserReverse(t *testing.T) {
+	commands := []engine.Command{
+		engine.Command{
+			Index:     "sample",
+			Database:  "document.db",
+			Command:   "set",
+			Key:       utils.Uint64ToBytes(1),
+			KeyType:   engine.TypeUint,
+			Value:     []byte(`{"name": "Neoway \"BR\"", "path": "c:\\", "desc": "a;b\n'c'"}`),
+			ValueType: engine.TypeString,
+		},
+		engine.Command{
+			Index:     "sample",
+			Database:  "address2_string.bm25",
+			Command:   "set",
+			Key:       []byte("tneoway\x00\x00\x00\x00\x00\x00\x00\x22\xff"),
+			KeyType:   engine.TypeString,
+			Value:     utils.Uint64ToBytes(18446744073709551615),
+			ValueType: engine.TypeUint,
+		},
+		engine.Command{
+			Index:     "sample",
+			Database:  "value_float.dv",
+			Command:   "set",
+			Key:       utils.Uint64ToBytes(2),
+			KeyType:   engine.TypeUint,
+			Value:     utils.Float64ToBytes(-1.5),
+			ValueType: engine.TypeFloat,
+		},
+		engine.Command{
+			Index:     "sample",
+			Database:  "value_float.dv",
+			Command:   "set",
+			Key:       utils.Uint64ToBytes(3),
+			KeyType:   engine.TypeUint,
+			Value:     utils.Float64ToBytes(3),
+			ValueType: engine.TypeFloat,
+		},
+		engine.Command{
+			Index:     "sample",
+			Database:  "year_int.idx",
+			Command:   "set",
+			Key:       utils.Int64ToBytes(-2015),
+			KeyType:   engine.TypeInt,
+			Value:     append(utils.Uint64ToBytes(3), utils.Uint64ToBytes(4294967295)...),
+			ValueType: engine.TypeString,
+		},
+		engine.Command{
+			Index:     "sample",
+			Database:  "active_bool.dv",
+			Command:   "set",
+			Key:       utils.Uint64ToBytes(3),
+			KeyType:   engine.TypeUint,
+			Value:     utils.BoolToBytes(false),
+			ValueType: engine.TypeBool,
+		},
+		engine.Command{
+			Index:     "sample",
+			Database:  "active_bool.idx",
+			Command:   "mergeset",
+			Key:       utils.BoolToBytes(true),
+			KeyType:   engine.TypeBool,
+			Value:     utils.Uint64ToBytes(4),
+			ValueType: engine.TypeUint,
+		},
+		engine.Command{
+			Index:     "sample",
+			Database:  "name_string.dv",
+			Command:   "set",
+			Key:       utils.Uint64ToBytes(5),
+			KeyType:   engine.TypeUint,
+			Value:     []byte{},
+			ValueType: engine.TypeString,
+		},
+	}
+
+	lines := make([]string, len(commands))
+
+	for i, cmd := range commands {
+		lines[i] = cmd.Reverse()
+	}
+
+	compareArray(strings.Join(lines, "\n"), commands, t)
+
+	// escape sequences only exist inside quotes
+	shouldThrowError(`using sample.test.idx set \x00 "a";`, t)
+}
+
 func compareCommand(cmd engine.Command, expected engine.Command, t *testing.T) {
 	if !reflect.DeepEqual(cmd, expected) {
 		t.Errorf("Unexpected parsed command: %v !== %v", cmd.Reverse(), expected.Reverse())
@@ -0,0 +1,199 @@
+// neosearch-dump writes the storages of NeoSearch indices as neosearch-cli
+// commands, and the settings, mapping and joins of the indices to the
+// config directory <dump-file>.config. The dump is restored in a fresh
+// data directory with:
+//
+//	neosearch-cli -d <data-dir> -f <dump-file> -p <workers>
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/index"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/store"
+	"github.com/jteeuwen/go-pkg-optarg"
+)
+
+func main() {
+	var (
+		dataDirOpt, indicesOpt, outputOpt, configOpt string
+		helpOpt, debugOpt                            bool
+		output                                       io.Writer = os.Stdout
+	)
+
+	optarg.Add("d", "data-dir", "Data directory", "")
+	optarg.Add("i", "indices", "Comma separated list of indices. Defaults to every index of the data directory", "")
+	optarg.Add("o", "output", "Write the dump to file instead of stdout", "")
+	optarg.Add("c", "config-dir", "Directory of the config files of the indices. Defaults to <output>.config", "")
+	optarg.Add("t", "trace-debug", "Enable trace for debug", false)
+	optarg.Add("h", "help", "Display this help", false)
+
+	for opt := range optarg.Parse() {
+		switch opt.ShortName {
+		case "d":
+			dataDirOpt = opt.String()
+		case "i":
+			indicesOpt = opt.String()
+		case "o":
+			outputOpt = opt.String()
+		case "c":
+			configOpt = opt.String()
+		case "t":
+			debugOpt = true
+		case "h":
+			helpOpt = true
+		}
+	}
+
+	if helpOpt {
+		optarg.Usage()
+		os.Exit(0)
+	}
+
+	if dataDirOpt == "" {
+		dataDirOpt, _ = os.Getwd()
+	}
+
+	indices, err := listIndices(dataDirOpt, indicesOpt)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if configOpt == "" && outputOpt != "" {
+		configOpt = outputOpt + ".config"
+	}
+
+	if configOpt == "" {
+		log.Println("The config files of the indices aren't dumped to stdout, use -o or -c to dump them")
+	}
+
+	if outputOpt != "" {
+		file, err := os.Create(outputOpt)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		defer file.Close()
+		output = file
+	}
+
+	ng := engine.New(&engine.Config{
+		KVConfig: store.KVConfig{
+			"dataDir": dataDirOpt,
+			"debug":   debugOpt,
+		},
+	})
+
+	defer ng.Close()
+
+	writer := bufio.NewWriter(output)
+
+	for _, indexName := range indices {
+		if err = dump(ng, dataDirOpt, indexName, writer); err != nil {
+			break
+		}
+
+		if configOpt != "" {
+			if err = dumpConfig(dataDirOpt, indexName, configOpt); err != nil {
+				break
+			}
+		}
+	}
+
+	if err == nil {
+		err = writer.Flush()
+	}
+
+	if err != nil {
+		ng.Close()
+		log.Fatal(err)
+	}
+}
+
+// listIndices returns the indices of the comma separated list or, when
+// it's empty, every index of dataDir
+func listIndices(dataDir, list string) ([]string, error) {
+	if list != "" {
+		return strings.Split(list, ","), nil
+	}
+
+	var indices []string
+
+	files, err := ioutil.ReadDir(dataDir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if file.IsDir() {
+			indices = append(indices, file.Name())
+		}
+	}
+
+	return indices, nil
+}
+
+// dump writes the commands of every storage of the index. Each storage
+// is a directory of the index, the settings, mapping and joins files
+// aren't storages and are written by dumpConfig.
+func dump(ng *engine.Engine, dataDir, indexName string, writer io.Writer) error {
+	files, err := ioutil.ReadDir(filepath.Join(dataDir, indexName))
+
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+
+		err = ng.Dump(indexName, file.Name(), func(cmd engine.Command) error {
+			_, err := fmt.Fprintln(writer, cmd.Reverse())
+			return err
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dumpConfig copies the config files of the index to <configDir>/<index>,
+// where neosearch-cli restores them from
+func dumpConfig(dataDir, indexName, configDir string) error {
+	indexConfigDir := filepath.Join(configDir, indexName)
+
+	if err := os.MkdirAll(indexConfigDir, 0755); err != nil {
+		return err
+	}
+
+	for _, name := range index.ConfigFiles {
+		content, err := ioutil.ReadFile(filepath.Join(dataDir, indexName, name))
+
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if err = ioutil.WriteFile(filepath.Join(indexConfigDir, name), content, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
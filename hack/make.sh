@@ -7,6 +7,7 @@ DEFAULT_BUNDLES=(
     server
     cli
     import
+    dump
 )
 
 bundle() {
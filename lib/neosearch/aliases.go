@@ -0,0 +1,207 @@
+package neosearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/index"
+)
+
+const aliasesFile = "aliases.json"
+
+// Alias actions
+const (
+	AliasAdd    = "add"
+	AliasRemove = "remove"
+)
+
+// AliasAction adds the index to the alias or removes it from the alias.
+// Aliases without indices are removed.
+type AliasAction struct {
+	Action string
+	Index  string
+	Alias  string
+}
+
+// Aliases returns the indices of every alias
+func (neo *NeoSearch) Aliases() (map[string][]string, error) {
+	neo.aliasesMutex.Lock()
+	defer neo.aliasesMutex.Unlock()
+
+	if err := neo.loadAliases(); err != nil {
+		return nil, err
+	}
+
+	aliases := make(map[string][]string, len(neo.aliases))
+
+	for alias, indices := range neo.aliases {
+		aliases[alias] = append([]string{}, indices...)
+	}
+
+	return aliases, nil
+}
+
+// UpdateAliases applies the actions to the aliases at once: either every
+// action is applied and persisted or none is.
+func (neo *NeoSearch) UpdateAliases(actions []AliasAction) error {
+	neo.aliasesMutex.Lock()
+	defer neo.aliasesMutex.Unlock()
+
+	if err := neo.loadAliases(); err != nil {
+		return err
+	}
+
+	aliases := make(map[string][]string, len(neo.aliases))
+
+	for alias, indices := range neo.aliases {
+		aliases[alias] = append([]string{}, indices...)
+	}
+
+	for _, action := range actions {
+		if !index.ValidateIndexName(action.Alias) {
+			return fmt.Errorf("Invalid alias name '%s'", action.Alias)
+		}
+
+		switch action.Action {
+		case AliasAdd:
+			if exists, err := neo.indexDirExists(action.Alias); err != nil {
+				return err
+			} else if exists {
+				return fmt.Errorf("Alias '%s' is the name of an index", action.Alias)
+			}
+
+			if exists, err := neo.indexDirExists(action.Index); err != nil {
+				return err
+			} else if !exists || !index.ValidateIndexName(action.Index) {
+				return fmt.Errorf("Index '%s' not found in directory '%s'.", action.Index, neo.config.DataDir)
+			}
+
+			if pos := indexOf(aliases[action.Alias], action.Index); pos < 0 {
+				aliases[action.Alias] = append(aliases[action.Alias], action.Index)
+				sort.Strings(aliases[action.Alias])
+			}
+		case AliasRemove:
+			pos := indexOf(aliases[action.Alias], action.Index)
+
+			if pos < 0 {
+				return fmt.Errorf("Index '%s' isn't in alias '%s'", action.Index, action.Alias)
+			}
+
+			indices := aliases[action.Alias]
+			aliases[action.Alias] = append(indices[:pos:pos], indices[pos+1:]...)
+
+			if len(aliases[action.Alias]) == 0 {
+				delete(aliases, action.Alias)
+			}
+		default:
+			return fmt.Errorf("Invalid alias action '%s'", action.Action)
+		}
+	}
+
+	return neo.saveAliases(aliases)
+}
+
+// resolveAlias returns the indices of the alias name, or nil if name isn't
+// an alias
+func (neo *NeoSearch) resolveAlias(name string) ([]string, error) {
+	neo.aliasesMutex.Lock()
+	defer neo.aliasesMutex.Unlock()
+
+	if err := neo.loadAliases(); err != nil {
+		return nil, err
+	}
+
+	return neo.aliases[name], nil
+}
+
+// removeIndexAliases removes the index name from every alias
+func (neo *NeoSearch) removeIndexAliases(name string) error {
+	neo.aliasesMutex.Lock()
+	defer neo.aliasesMutex.Unlock()
+
+	if err := neo.loadAliases(); err != nil {
+		return err
+	}
+
+	aliases := make(map[string][]string, len(neo.aliases))
+	changed := false
+
+	for alias, indices := range neo.aliases {
+		if pos := indexOf(indices, name); pos >= 0 {
+			indices = append(indices[:pos:pos], indices[pos+1:]...)
+			changed = true
+		}
+
+		if len(indices) > 0 {
+			aliases[alias] = indices
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return neo.saveAliases(aliases)
+}
+
+// loadAliases reads the aliases file on first use. Callers hold
+// aliasesMutex.
+func (neo *NeoSearch) loadAliases() error {
+	if neo.aliases != nil {
+		return nil
+	}
+
+	content, err := ioutil.ReadFile(neo.config.DataDir + "/" + aliasesFile)
+
+	if os.IsNotExist(err) {
+		neo.aliases = make(map[string][]string)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	aliases := make(map[string][]string)
+
+	if err = json.Unmarshal(content, &aliases); err != nil {
+		return fmt.Errorf("Invalid aliases file: %s", err)
+	}
+
+	neo.aliases = aliases
+	return nil
+}
+
+// saveAliases replaces the aliases file and the loaded aliases. Callers
+// hold aliasesMutex.
+func (neo *NeoSearch) saveAliases(aliases map[string][]string) error {
+	content, err := json.Marshal(aliases)
+
+	if err != nil {
+		return err
+	}
+
+	tmpFile := neo.config.DataDir + "/" + aliasesFile + ".tmp"
+
+	if err = ioutil.WriteFile(tmpFile, content, 0644); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmpFile, neo.config.DataDir+"/"+aliasesFile); err != nil {
+		return err
+	}
+
+	neo.aliases = aliases
+	return nil
+}
+
+func indexOf(names []string, name string) int {
+	for pos, v := range names {
+		if v == name {
+			return pos
+		}
+	}
+
+	return -1
+}
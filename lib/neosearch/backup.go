@@ -0,0 +1,441 @@
+package neosearch
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/index"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/store"
+)
+
+// BackupVersion is the version of the backup format written by Backup.
+// Restore only accepts backups of this version.
+const BackupVersion = 1
+
+// Entries of the backup archive. The manifest is the first entry, followed
+// by the config files of the index and by its storages. Each storage entry
+// is a sequence of records: the uvarint length of the key, the key, the
+// uvarint length of the value and the value.
+const (
+	backupManifest = "manifest.json"
+	backupFiles    = "files/"
+	backupStorages = "storages/"
+
+	// restoreBatchSize is the number of records written by batch
+	restoreBatchSize = 5000
+)
+
+// BackupManifest describes the content of a backup
+type BackupManifest struct {
+	Version  int           `json:"version"`
+	Index    string        `json:"index"`
+	Created  time.Time     `json:"created"`
+	Files    []BackupEntry `json:"files"`
+	Storages []BackupEntry `json:"storages"`
+}
+
+// BackupEntry is a file or storage of the backup. Size and Checksum, the
+// hex encoded SHA-256, are of the archive entry.
+type BackupEntry struct {
+	Name     string `json:"name"`
+	Keys     uint64 `json:"keys,omitempty"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"`
+}
+
+// checksumWriter counts and hashes the bytes written
+type checksumWriter struct {
+	hash hash.Hash
+	size int64
+}
+
+func newChecksumWriter() *checksumWriter {
+	return &checksumWriter{hash: sha256.New()}
+}
+
+func (w *checksumWriter) Write(p []byte) (int, error) {
+	w.size += int64(len(p))
+	return w.hash.Write(p)
+}
+
+func (w *checksumWriter) Checksum() string {
+	return hex.EncodeToString(w.hash.Sum(nil))
+}
+
+// Backup writes to writer a tar archive of every storage of the index,
+// taken from a snapshot while the index is still written. It returns the
+// manifest of the backup, also written as the first entry of the archive.
+func (neo *NeoSearch) Backup(name string, writer io.Writer) (*BackupManifest, error) {
+	ind, err := neo.OpenIndex(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, err := ind.Snapshot()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer snapshot.Close()
+
+	manifest := &BackupManifest{
+		Version: BackupVersion,
+		Index:   ind.Name,
+		Created: time.Now().UTC(),
+	}
+
+	for _, file := range index.ConfigFiles {
+		content, ok := snapshot.Files[file]
+
+		if !ok {
+			continue
+		}
+
+		checksum := newChecksumWriter()
+		checksum.Write(content)
+
+		manifest.Files = append(manifest.Files, BackupEntry{
+			Name:     file,
+			Size:     checksum.size,
+			Checksum: checksum.Checksum(),
+		})
+	}
+
+	// the storages are read twice: the sizes of the tar entries are
+	// written before their content
+	for _, storage := range snapshot.Storages {
+		checksum := newChecksumWriter()
+		keys, err := writeRecords(snapshot.Reader(storage), checksum)
+
+		if err != nil {
+			return nil, fmt.Errorf("Backup of storage '%s' failed: %s", storage, err)
+		}
+
+		manifest.Storages = append(manifest.Storages, BackupEntry{
+			Name:     storage,
+			Keys:     keys,
+			Size:     checksum.size,
+			Checksum: checksum.Checksum(),
+		})
+	}
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	tw := tar.NewWriter(writer)
+
+	if err = writeTarEntry(tw, backupManifest, int64(len(content)), manifest.Created); err == nil {
+		_, err = tw.Write(content)
+	}
+
+	for _, file := range manifest.Files {
+		if err != nil {
+			break
+		}
+
+		if err = writeTarEntry(tw, backupFiles+file.Name, file.Size, manifest.Created); err == nil {
+			_, err = tw.Write(snapshot.Files[file.Name])
+		}
+	}
+
+	for _, storage := range manifest.Storages {
+		if err != nil {
+			break
+		}
+
+		if err = writeTarEntry(tw, backupStorages+storage.Name, storage.Size, manifest.Created); err != nil {
+			break
+		}
+
+		checksum := newChecksumWriter()
+
+		if _, err = writeRecords(snapshot.Reader(storage.Name), io.MultiWriter(tw, checksum)); err == nil &&
+			checksum.Checksum() != storage.Checksum {
+			err = fmt.Errorf("Storage '%s' changed during the backup", storage.Name)
+		}
+	}
+
+	if err == nil {
+		err = tw.Close()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+func writeTarEntry(tw *tar.Writer, name string, size int64, modTime time.Time) error {
+	return tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     size,
+		ModTime:  modTime,
+		Typeflag: tar.TypeReg,
+	})
+}
+
+// writeRecords writes every key and value of reader and returns the
+// number of keys
+func writeRecords(reader store.KVReader, writer io.Writer) (uint64, error) {
+	var (
+		keys   uint64
+		header [binary.MaxVarintLen64]byte
+	)
+
+	it := reader.GetIterator()
+
+	defer it.Close()
+
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		for _, data := range [][]byte{it.Key(), it.Value()} {
+			n := binary.PutUvarint(header[:], uint64(len(data)))
+
+			if _, err := writer.Write(header[:n]); err != nil {
+				return keys, err
+			}
+
+			if _, err := writer.Write(data); err != nil {
+				return keys, err
+			}
+		}
+
+		keys++
+	}
+
+	return keys, it.GetError()
+}
+
+// readRecords writes to writer the records read from r, an entry of size
+// bytes, and returns the number of keys
+func readRecords(r io.Reader, size int64, writer store.KVWriter) (uint64, error) {
+	var keys uint64
+
+	reader := &byteReader{Reader: r}
+
+	writer.StartBatch()
+
+	for {
+		var record [2][]byte
+
+		for i := range record {
+			length, err := binary.ReadUvarint(reader)
+
+			if err == io.EOF && i == 0 {
+				return keys, writer.FlushBatch()
+			} else if err != nil || length > uint64(size) {
+				return keys, errors.New("Truncated storage record")
+			}
+
+			record[i] = make([]byte, length)
+
+			if _, err = io.ReadFull(reader, record[i]); err != nil {
+				return keys, errors.New("Truncated storage record")
+			}
+		}
+
+		if err := writer.Set(record[0], record[1]); err != nil {
+			return keys, err
+		}
+
+		keys++
+
+		if keys%restoreBatchSize == 0 {
+			if err := writer.FlushBatch(); err != nil {
+				return keys, err
+			}
+
+			writer.StartBatch()
+		}
+	}
+}
+
+// Restore creates the index name from a backup written by Backup, of
+// this or another index. Every entry is verified against the manifest and
+// the index is only created when the whole backup is valid. The joins of
+// an index restored under another name are dropped.
+func (neo *NeoSearch) Restore(name string, reader io.Reader) (*BackupManifest, error) {
+	if !index.ValidateIndexName(name) {
+		return nil, errors.New("Invalid index name: " + name)
+	}
+
+	if exists, err := neo.IndexExists(name); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, fmt.Errorf("Index '%s' already exists.", name)
+	}
+
+	// the restored index is written to a directory that isn't a valid
+	// index name and renamed when done
+	tmpDir, err := ioutil.TempDir(neo.config.DataDir, "_restore-"+name+"-")
+
+	if err != nil {
+		return nil, err
+	}
+
+	ngConfig := &engine.Config{
+		KVConfig: store.KVConfig{
+			"dataDir": neo.config.DataDir,
+		},
+	}
+
+	if neo.config.Engine != nil {
+		ngConfig.KVStore = neo.config.Engine.KVStore
+	}
+
+	ng := engine.New(ngConfig)
+
+	manifest, err := restoreEntries(ng, tmpDir, tar.NewReader(reader))
+	ng.Close()
+
+	// the other indices of the joins only know the index by its name
+	if err == nil && manifest.Index != name {
+		err = dropJoins(tmpDir)
+	}
+
+	if err == nil {
+		err = os.Rename(tmpDir, neo.config.DataDir+"/"+name)
+	}
+
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// dropJoins removes the materialized joins of the restored index dir
+func dropJoins(dir string) error {
+	storages, err := filepath.Glob(filepath.Join(dir, "*.join"))
+
+	if err != nil {
+		return err
+	}
+
+	for _, path := range append(storages, filepath.Join(dir, "joins.json")) {
+		if err = os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restoreEntries verifies and writes the entries of the backup to the
+// index directory dir
+func restoreEntries(ng *engine.Engine, dir string, tr *tar.Reader) (*BackupManifest, error) {
+	manifest := &BackupManifest{}
+	header, err := tr.Next()
+
+	if err != nil || header.Name != backupManifest {
+		return nil, errors.New("Invalid backup: manifest not found")
+	}
+
+	if err = json.NewDecoder(tr).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("Invalid backup manifest: %s", err)
+	}
+
+	if manifest.Version != BackupVersion {
+		return nil, fmt.Errorf("Unsupported backup version %d", manifest.Version)
+	}
+
+	expected := make(map[string]BackupEntry)
+
+	for _, file := range manifest.Files {
+		expected[backupFiles+file.Name] = file
+	}
+
+	for _, storage := range manifest.Storages {
+		expected[backupStorages+storage.Name] = storage
+	}
+
+	for {
+		header, err = tr.Next()
+
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		entry, ok := expected[header.Name]
+
+		if !ok {
+			return nil, fmt.Errorf("Unexpected backup entry '%s'", header.Name)
+		}
+
+		delete(expected, header.Name)
+
+		var keys uint64
+
+		checksum := newChecksumWriter()
+		body := io.TeeReader(tr, checksum)
+
+		switch {
+		case strings.HasPrefix(header.Name, backupFiles) && indexOf(index.ConfigFiles, entry.Name) >= 0:
+			content, err := ioutil.ReadAll(body)
+
+			if err == nil {
+				err = ioutil.WriteFile(filepath.Join(dir, entry.Name), content, 0644)
+			}
+
+			if err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(header.Name, backupStorages) && store.ValidateDatabaseName(entry.Name):
+			storekv, err := ng.GetStore(filepath.Base(dir), entry.Name)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if keys, err = readRecords(body, header.Size, storekv.Writer()); err != nil {
+				return nil, fmt.Errorf("Invalid storage '%s': %s", entry.Name, err)
+			}
+		default:
+			return nil, fmt.Errorf("Invalid backup entry '%s'", header.Name)
+		}
+
+		if checksum.size != entry.Size || checksum.Checksum() != entry.Checksum || keys != entry.Keys {
+			return nil, fmt.Errorf("Backup entry '%s' doesn't match the manifest", header.Name)
+		}
+	}
+
+	for name := range expected {
+		return nil, fmt.Errorf("Backup entry '%s' not found", name)
+	}
+
+	return manifest, nil
+}
+
+// byteReader is an io.ByteReader of r that doesn't read ahead, then the
+// bytes read are exactly the bytes of the records.
+type byteReader struct {
+	io.Reader
+	buf [1]byte
+}
+
+func (b *byteReader) ReadByte() (byte, error) {
+	_, err := io.ReadFull(b.Reader, b.buf[:])
+	return b.buf[0], err
+}
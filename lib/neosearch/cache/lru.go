@@ -133,23 +133,16 @@ func (lru *LRUCache) Clean() {
 	var (
 		cacheLen int = len(lru.cache)
 		elem     *list.Element
-		key      string
-		value    interface{}
+		next     *list.Element
 	)
 
 	if lru.cache == nil || cacheLen == 0 {
 		return
 	}
 
-	for elem = lru.ll.Front(); elem != nil; elem = elem.Next() {
-		ee := elem.Value.(*entry)
-		key = ee.key
-		value = ee.value
-
-		if lru.onRemove != nil {
-			lru.onRemove(key, value)
-		}
-
+	// removed elements lose their links to the list
+	for elem = lru.ll.Front(); elem != nil; elem = next {
+		next = elem.Next()
 		lru.removeElement(elem)
 	}
 }
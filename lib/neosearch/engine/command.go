@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
 )
@@ -17,7 +18,12 @@ import (
 //   - KeyType
 //   - Value
 //   - ValueType
+//   - EndKey
+//   - Limit
 //   - Batch
+//
+// EndKey and Limit are only used by the range commands (scan and keys).
+// EndKey has the same type of Key (KeyType).
 type Command struct {
 	Index     string
 	Database  string
@@ -26,6 +32,8 @@ type Command struct {
 	KeyType   uint8
 	Value     []byte
 	ValueType uint8
+	EndKey    []byte
+	Limit     uint64
 
 	Batch bool
 }
@@ -43,48 +51,96 @@ func (c Command) Reverse() string {
 	)
 
 	if c.Key != nil {
-		if c.KeyType == TypeString {
-			keyStr = `'` + string(c.Key) + `'`
-		} else if c.KeyType == TypeUint {
-			keyStr = `uint(` + strconv.Itoa(int(utils.BytesToUint64(c.Key))) + `)`
-		} else if c.KeyType == TypeInt {
-			keyStr = `int(` + strconv.Itoa(int(utils.BytesToInt64(c.Key))) + `)`
-		} else if c.KeyType == TypeFloat {
-			keyStr = `float(` + strconv.FormatFloat(utils.BytesToFloat64(c.Key), 'f', -1, 64) + `)`
-		} else if c.KeyType == TypeBool {
-			keyStr = `bool(` + string(c.Key) + `)`
-		} else {
-			fmt.Printf("Command error: %+v", c)
-			panic(fmt.Errorf("Invalid command key type: %d - %+v", c.KeyType, string(c.Key)))
-		}
+		keyStr = reverseLiteral(c.Key, c.KeyType)
 	}
 
 	if c.Value != nil {
-		if c.ValueType == TypeString {
-			valStr = `'` + string(c.Value) + `'`
-		} else if c.ValueType == TypeUint {
-			valStr = `uint(` + strconv.Itoa(int(utils.BytesToUint64(c.Value))) + `)`
-		} else if c.ValueType == TypeInt {
-			valStr = `int(` + strconv.Itoa(int(utils.BytesToInt64(c.Value))) + `)`
-		} else if c.ValueType == TypeFloat {
-			valStr = `float(` + strconv.FormatFloat(utils.BytesToFloat64(c.Value), 'f', -1, 64) + `)`
-		} else if c.ValueType == TypeBool {
-			valStr = `bool(` + string(c.Value) + `)`
-		} else {
-			panic(fmt.Errorf("Invalid command key type: %d", c.ValueType))
-		}
+		valStr = reverseLiteral(c.Value, c.ValueType)
 	}
 
 	switch strings.ToUpper(c.Command) {
-	case "SET", "MERGESET":
+	case "SET", "MERGESET", "MERGEDEL", "INCR", "DECR":
 		line = fmt.Sprintf("USING %s.%s %s %s %s;", c.Index, c.Database, strings.ToUpper(c.Command), keyStr, valStr)
-	case "BATCH", "flushbatch":
+	case "BATCH", "FLUSHBATCH":
 		line = fmt.Sprintf("USING %s.%s %s;", c.Index, c.Database, strings.ToUpper(c.Command))
 	case "GET", "DELETE":
 		line = fmt.Sprintf("USING %s.%s %s %s;", c.Index, c.Database, strings.ToUpper(c.Command), keyStr)
+	case "SCAN":
+		line = fmt.Sprintf("USING %s.%s SCAN %s %s", c.Index, c.Database, keyStr, reverseLiteral(c.EndKey, c.KeyType))
+
+		if c.Limit > 0 {
+			line += fmt.Sprintf(" LIMIT %d", c.Limit)
+		}
+
+		line += ";"
+	case "KEYS":
+		line = fmt.Sprintf("USING %s.%s KEYS %s", c.Index, c.Database, keyStr)
+
+		if c.Limit > 0 {
+			line += fmt.Sprintf(" LIMIT %d", c.Limit)
+		}
+
+		line += ";"
+	case "COUNT", "STATS":
+		line = fmt.Sprintf("USING %s.%s %s;", c.Index, c.Database, strings.ToUpper(c.Command))
 	default:
 		panic(fmt.Errorf("Invalid command: %s: %v", strings.ToUpper(c.Command), c))
 	}
 
 	return line
 }
+
+// reverseLiteral returns the NeoSearch syntax representation of data
+func reverseLiteral(data []byte, dataType uint8) string {
+	switch dataType {
+	case TypeString:
+		return quoteString(data)
+	case TypeUint:
+		return `uint(` + strconv.FormatUint(utils.BytesToUint64(data), 10) + `)`
+	case TypeInt:
+		return `int(` + strconv.FormatInt(utils.BytesToInt64(data), 10) + `)`
+	case TypeFloat:
+		return `float(` + strconv.FormatFloat(utils.BytesToFloat64(data), 'f', -1, 64) + `)`
+	case TypeBool:
+		return `bool(` + strconv.FormatBool(utils.BytesToBool(data)) + `)`
+	}
+
+	panic(fmt.Errorf("Invalid command data type: %d - %+v", dataType, string(data)))
+}
+
+// quoteString returns data single quoted when it's plain text. Otherwise
+// it's double quoted with quotes and backslashes escaped and with the
+// control characters and invalid UTF-8 bytes as \xNN escapes, so keys and
+// values are parsed back byte by byte.
+func quoteString(data []byte) string {
+	plain := len(data) > 0 && utf8.Valid(data)
+
+	for i := 0; plain && i < len(data); i++ {
+		plain = data[i] >= 0x20 && data[i] != 0x7f &&
+			data[i] != '\'' && data[i] != '"' && data[i] != '\\'
+	}
+
+	if plain {
+		return `'` + string(data) + `'`
+	}
+
+	buf := make([]byte, 0, len(data)+2)
+	buf = append(buf, '"')
+
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+
+		switch {
+		case r == '"' || r == '\\':
+			buf = append(buf, '\\', data[0])
+		case r == utf8.RuneError && size == 1, r < 0x20, r == 0x7f:
+			buf = append(buf, fmt.Sprintf("\\x%02x", data[0])...)
+		default:
+			buf = append(buf, data[:size]...)
+		}
+
+		data = data[size:]
+	}
+
+	return string(append(buf, '"'))
+}
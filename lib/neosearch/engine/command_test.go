@@ -47,6 +47,36 @@ func TestCommand(t *testing.T) {
 			},
 			expected: `USING empresas.name.idx GET 'teste';`,
 		},
+		{
+			cmd: Command{
+				Database: "name.idx",
+				Index:    "empresas",
+				Command:  "scan",
+				Key:      []byte("a"),
+				KeyType:  TypeString,
+				EndKey:   []byte("c"),
+				Limit:    10,
+			},
+			expected: `USING empresas.name.idx SCAN 'a' 'c' LIMIT 10;`,
+		},
+		{
+			cmd: Command{
+				Database: "name.idx",
+				Index:    "empresas",
+				Command:  "keys",
+				Key:      []byte("neo"),
+				KeyType:  TypeString,
+			},
+			expected: `USING empresas.name.idx KEYS 'neo';`,
+		},
+		{
+			cmd: Command{
+				Database: "name.idx",
+				Index:    "empresas",
+				Command:  "count",
+			},
+			expected: `USING empresas.name.idx COUNT;`,
+		},
 	} {
 		cmdRev := testTable.cmd.Reverse()
 
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// storageIsSet reports if the values of the storage given by database are
+// ordered sets of uint64 ids written with MergeSet.
+func storageIsSet(database string) bool {
+	return strings.HasSuffix(database, ".idx") || strings.HasSuffix(database, ".join")
+}
+
+// Dump iterates every entry of the database of the index and calls fn with
+// the commands that write it again, a set by key. The posting lists are set
+// to their encoded ids, big-endian uint64 written as a binary string. Keys and values are typed by the naming
+// convention of the storages, then the commands are restored by Execute
+// or, reversed with Command.Reverse, by neosearch-cli.
+func (ng *Engine) Dump(indexName, databaseName string, fn func(cmd Command) error) error {
+	storekv, err := ng.GetStore(indexName, databaseName)
+
+	if err != nil {
+		return err
+	}
+
+	reader := storekv.Reader()
+
+	defer reader.Close()
+
+	it := reader.GetIterator()
+
+	defer it.Close()
+
+	keyType := storageKeyType(databaseName)
+	valueType := storageValueType(databaseName)
+	isSet := storageIsSet(databaseName)
+
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		key := copyBytes(it.Key())
+		value := it.Value()
+
+		if _, err = Decode(key, keyType, false); err != nil {
+			return fmt.Errorf("%s.%s: invalid key %q: %s", indexName, databaseName, key, err)
+		}
+
+		if !isSet {
+			if _, err = Decode(value, valueType, false); err != nil {
+				return fmt.Errorf("%s.%s: invalid value of key %q: %s", indexName, databaseName, key, err)
+			}
+
+			err = fn(Command{
+				Index:     indexName,
+				Database:  databaseName,
+				Command:   "set",
+				Key:       key,
+				KeyType:   keyType,
+				Value:     copyBytes(value),
+				ValueType: valueType,
+			})
+
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if len(value)%8 != 0 {
+			return fmt.Errorf("%s.%s: invalid uint set of length %d", indexName, databaseName, len(value))
+		}
+
+		// empty sets left by mergedel aren't dumped
+		if len(value) == 0 {
+			continue
+		}
+
+		err = fn(Command{
+			Index:     indexName,
+			Database:  databaseName,
+			Command:   "set",
+			Key:       key,
+			KeyType:   keyType,
+			Value:     copyBytes(value),
+			ValueType: TypeString,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return it.GetError()
+}
@@ -1,7 +1,10 @@
 package engine
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"sync"
 
 	"github.com/NeowayLabs/neosearch/lib/neosearch/cache"
 	"github.com/NeowayLabs/neosearch/lib/neosearch/store"
@@ -13,6 +16,21 @@ type Engine struct {
 	stores cache.Cache
 	config *Config
 	debug  bool
+
+	// openMutex serializes the lookups and openings of the cached stores
+	openMutex sync.Mutex
+
+	// counters are the states of the incr and decr commands by store
+	counters      map[string]*counters
+	countersMutex sync.Mutex
+}
+
+// counters serializes the read-modify-write of the counters of a store.
+// The counters written by the current batch are kept in pending, because
+// the write batch isn't read by Get.
+type counters struct {
+	sync.Mutex
+	pending map[string]uint64
 }
 
 const (
@@ -49,8 +67,9 @@ func New(cfg *Config) *Engine {
 	}
 
 	ng := &Engine{
-		config: cfg,
-		stores: cache.NewLRUCache(cfg.OpenCacheSize),
+		config:   cfg,
+		stores:   cache.NewLRUCache(cfg.OpenCacheSize),
+		counters: make(map[string]*counters),
 	}
 
 	if debug, ok := cfg.KVConfig["debug"].(bool); ok {
@@ -81,6 +100,9 @@ func (ng *Engine) open(indexName, databaseName string) (store.KVStore, error) {
 		value   interface{}
 	)
 
+	ng.openMutex.Lock()
+	defer ng.openMutex.Unlock()
+
 	value, ok = ng.stores.Get(indexName + "." + databaseName)
 
 	if ok == false || value == nil {
@@ -109,8 +131,8 @@ func (ng *Engine) open(indexName, databaseName string) (store.KVStore, error) {
 	return nil, errors.New("Failed to convert cache entry to KVStore")
 }
 
-// Execute the given command
-func (ng *Engine) Execute(cmd Command) ([]byte, error) {
+// Execute the given command and returns its Result.
+func (ng *Engine) Execute(cmd Command) (*Result, error) {
 	var err error
 
 	store, err := ng.GetStore(cmd.Index, cmd.Database)
@@ -124,35 +146,267 @@ func (ng *Engine) Execute(cmd Command) ([]byte, error) {
 	}
 
 	writer := store.Writer()
-
 	reader := store.Reader()
+
 	defer func() {
 		reader.Close()
 	}()
 
+	result := &Result{
+		KeyType:   cmd.KeyType,
+		ValueType: cmd.ValueType,
+	}
+
+	if result.KeyType == TypeNil || result.KeyType == 0 {
+		result.KeyType = storageKeyType(cmd.Database)
+	}
+
+	if result.ValueType == TypeNil || result.ValueType == 0 {
+		result.ValueType = storageValueType(cmd.Database)
+	}
+
 	switch cmd.Command {
 	case "batch":
-		writer.StartBatch()
-		return nil, nil
+		ng.resetCounters(cmd.Index, cmd.Database, func() {
+			writer.StartBatch()
+		})
+		return result, nil
 	case "flushbatch":
-		err = writer.FlushBatch()
-		return nil, err
+		ng.resetCounters(cmd.Index, cmd.Database, func() {
+			err = writer.FlushBatch()
+		})
+		return result, err
 	case "set":
 		err = writer.Set(cmd.Key, cmd.Value)
-		return nil, err
+		return result, err
 	case "get":
-		return reader.Get(cmd.Key)
+		result.Data, err = reader.Get(cmd.Key)
+		return result, err
 	case "mergeset":
 		v := utils.BytesToUint64(cmd.Value)
-		return nil, writer.MergeSet(cmd.Key, v)
+		return result, writer.MergeSet(cmd.Key, v)
+	case "mergedel":
+		err = mergeDel(writer, cmd)
+		return result, err
+	case "incr", "decr":
+		c := ng.storeCounters(cmd.Index, cmd.Database)
+		c.Lock()
+		err = c.incr(writer, cmd)
+		c.Unlock()
+		return result, err
 	case "delete":
 		err = writer.Delete(cmd.Key)
-		return nil, err
+		return result, err
+	case "scan":
+		err = scan(reader, cmd, result)
+		return result, err
+	case "keys":
+		err = keys(reader, cmd, result)
+		return result, err
+	case "count", "stats":
+		err = count(reader, cmd, result)
+		return result, err
 	}
 
 	return nil, errors.New("Failed to execute command.")
 }
 
+// storeCounters returns the counters of the database of the index
+func (ng *Engine) storeCounters(indexName, databaseName string) *counters {
+	ng.countersMutex.Lock()
+	defer ng.countersMutex.Unlock()
+
+	c, ok := ng.counters[indexName+"."+databaseName]
+
+	if !ok {
+		c = &counters{pending: make(map[string]uint64)}
+		ng.counters[indexName+"."+databaseName] = c
+	}
+
+	return c
+}
+
+// resetCounters calls fn, starting or flushing the write batch of the
+// database, and discards the counters of the previous batch
+func (ng *Engine) resetCounters(indexName, databaseName string, fn func()) {
+	c := ng.storeCounters(indexName, databaseName)
+	c.Lock()
+	defer c.Unlock()
+
+	fn()
+	c.pending = make(map[string]uint64)
+}
+
+// incr adds the uint64 cmd.Value to the counter stored in cmd.Key, or
+// subtracts it for decr commands. Counters never go below zero. In batch
+// mode the counter is read from the increments of the batch, the
+// counters must be locked.
+func (c *counters) incr(writer store.KVWriter, cmd Command) error {
+	var counter uint64
+
+	if len(cmd.Value) != 8 {
+		return fmt.Errorf("Invalid increment of length %d", len(cmd.Value))
+	}
+
+	pending, isPending := c.pending[string(cmd.Key)]
+
+	if writer.IsBatch() && isPending {
+		counter = pending
+	} else {
+		current, err := writer.Get(cmd.Key)
+
+		if err != nil {
+			return err
+		}
+
+		if len(current) == 8 {
+			counter = utils.BytesToUint64(current)
+		} else if len(current) != 0 {
+			return fmt.Errorf("Invalid counter of length %d", len(current))
+		}
+	}
+
+	delta := utils.BytesToUint64(cmd.Value)
+
+	if cmd.Command == "decr" {
+		if delta > counter {
+			delta = counter
+		}
+
+		counter -= delta
+	} else {
+		counter += delta
+	}
+
+	if writer.IsBatch() {
+		c.pending[string(cmd.Key)] = counter
+	}
+
+	return writer.Set(cmd.Key, utils.Uint64ToBytes(counter))
+}
+
+// mergeDel removes the uint64 cmd.Value from the set stored in cmd.Key, the
+// opposite of MergeSet. Empty sets are kept as empty values.
+func mergeDel(writer store.KVWriter, cmd Command) error {
+	if len(cmd.Value) != 8 {
+		return fmt.Errorf("Invalid set value of length %d", len(cmd.Value))
+	}
+
+	current, err := writer.Get(cmd.Key)
+
+	if err != nil {
+		return err
+	}
+
+	if len(current)%8 != 0 {
+		return fmt.Errorf("Invalid uint set of length %d", len(current))
+	}
+
+	for i := 0; i < len(current); i += 8 {
+		if bytes.Equal(current[i:i+8], cmd.Value) {
+			set := make([]byte, 0, len(current)-8)
+			set = append(set, current[:i]...)
+			set = append(set, current[i+8:]...)
+			return writer.Set(cmd.Key, set)
+		}
+	}
+
+	return nil
+}
+
+// scan returns the entries with key in the range [cmd.Key, cmd.EndKey)
+// limited by cmd.Limit entries. A limit of 0 (zero) means no limit.
+func scan(reader store.KVReader, cmd Command, result *Result) error {
+	it := reader.GetIterator()
+
+	defer it.Close()
+
+	if cmd.Key == nil {
+		it.SeekToFirst()
+	} else {
+		it.Seek(cmd.Key)
+	}
+
+	for ; it.Valid(); it.Next() {
+		if cmd.EndKey != nil && bytes.Compare(it.Key(), cmd.EndKey) >= 0 {
+			break
+		}
+
+		result.Entries = append(result.Entries, Entry{
+			Key:   copyBytes(it.Key()),
+			Value: copyBytes(it.Value()),
+		})
+
+		if cmd.Limit > 0 && uint64(len(result.Entries)) == cmd.Limit {
+			break
+		}
+	}
+
+	result.Count = uint64(len(result.Entries))
+	return it.GetError()
+}
+
+// keys returns the keys prefixed by cmd.Key limited by cmd.Limit entries.
+func keys(reader store.KVReader, cmd Command, result *Result) error {
+	it := reader.GetIterator()
+
+	defer it.Close()
+
+	for it.Seek(cmd.Key); it.Valid(); it.Next() {
+		if !bytes.HasPrefix(it.Key(), cmd.Key) {
+			break
+		}
+
+		result.Entries = append(result.Entries, Entry{
+			Key: copyBytes(it.Key()),
+		})
+
+		if cmd.Limit > 0 && uint64(len(result.Entries)) == cmd.Limit {
+			break
+		}
+	}
+
+	result.Count = uint64(len(result.Entries))
+	return it.GetError()
+}
+
+// count visits every key of the storage. The stats command also sums the
+// length of keys and values.
+func count(reader store.KVReader, cmd Command, result *Result) error {
+	var keysSize, valuesSize uint64
+
+	it := reader.GetIterator()
+
+	defer it.Close()
+
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		result.Count++
+
+		if cmd.Command == "stats" {
+			keysSize += uint64(len(it.Key()))
+			valuesSize += uint64(len(it.Value()))
+		}
+	}
+
+	if cmd.Command == "stats" {
+		result.Stats = map[string]uint64{
+			"keys":       result.Count,
+			"keysSize":   keysSize,
+			"valuesSize": valuesSize,
+		}
+	}
+
+	return it.GetError()
+}
+
+// copyBytes returns a copy of b. Iterators can reuse the underlying
+// buffers of keys and values.
+func copyBytes(b []byte) []byte {
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
+
 // GetStore returns a instance of KVStore for the given index name
 // If the given index name isn't open, then this method will open
 // and cache the index for next use.
@@ -1,8 +1,11 @@
 package engine
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
+	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/NeowayLabs/neosearch/lib/neosearch/store"
@@ -172,3 +175,389 @@ func TestEngineIntegerKeyOrder(t *testing.T) {
 	defer ng.Close()
 	os.RemoveAll(DataDirTmp)
 }
+
+func TestEngineInspectCommands(t *testing.T) {
+	ng := New(&Config{
+		KVConfig: store.KVConfig{
+			"dataDir": DataDirTmp,
+		},
+		OpenCacheSize: 1,
+	})
+
+	defer func() {
+		ng.Close()
+		os.RemoveAll(DataDirTmp)
+	}()
+
+	for _, key := range []string{"apple", "banana", "neoway", "neosearch", "zebra"} {
+		_, err := ng.Execute(Command{
+			Index:     sampleIndex,
+			Database:  "name_string.idx",
+			Command:   "mergeset",
+			Key:       []byte(key),
+			KeyType:   TypeString,
+			Value:     utils.Uint64ToBytes(uint64(len(key))),
+			ValueType: TypeUint,
+		})
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	result, err := ng.Execute(Command{
+		Index:    sampleIndex,
+		Database: "name_string.idx",
+		Command:  "scan",
+		Key:      []byte("b"),
+		KeyType:  TypeString,
+		EndKey:   []byte("z"),
+		Limit:    2,
+	})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if result.Count != 2 || string(result.Entries[0].Key) != "banana" ||
+		string(result.Entries[1].Key) != "neosearch" {
+		t.Errorf("Unexpected scan result: %+v", result)
+		return
+	}
+
+	value, err := result.Value(1)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !reflect.DeepEqual(value, []uint64{9}) {
+		t.Errorf("Unexpected decoded value: %v", value)
+		return
+	}
+
+	result, err = ng.Execute(Command{
+		Index:    sampleIndex,
+		Database: "name_string.idx",
+		Command:  "keys",
+		Key:      []byte("neo"),
+		KeyType:  TypeString,
+	})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if result.Count != 2 {
+		t.Errorf("Unexpected keys result: %+v", result)
+		return
+	}
+
+	result, err = ng.Execute(Command{
+		Index:    sampleIndex,
+		Database: "name_string.idx",
+		Command:  "stats",
+	})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if result.Count != 5 || result.Stats["keys"] != 5 ||
+		result.Stats["keysSize"] != 31 || result.Stats["valuesSize"] != 40 {
+		t.Errorf("Unexpected stats result: %+v", result)
+	}
+}
+
+func TestEngineIncr(t *testing.T) {
+	ng := New(&Config{
+		KVConfig: store.KVConfig{
+			"dataDir": DataDirTmp,
+		},
+		OpenCacheSize: 1,
+	})
+
+	defer func() {
+		ng.Close()
+		os.RemoveAll(DataDirTmp)
+	}()
+
+	for _, delta := range []uint64{1, 2, 39} {
+		_, err := ng.Execute(Command{
+			Index:     sampleIndex,
+			Database:  "stats.db",
+			Command:   "incr",
+			Key:       []byte("counter"),
+			KeyType:   TypeString,
+			Value:     utils.Uint64ToBytes(delta),
+			ValueType: TypeUint,
+		})
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	result, err := ng.Execute(Command{
+		Index:    sampleIndex,
+		Database: "stats.db",
+		Command:  "get",
+		Key:      []byte("counter"),
+		KeyType:  TypeString,
+	})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if utils.BytesToUint64(result.Data) != 42 {
+		t.Errorf("Unexpected counter: %v", result.Data)
+	}
+
+	_, err = ng.Execute(Command{
+		Index:    sampleIndex,
+		Database: "stats.db",
+		Command:  "incr",
+		Key:      []byte("counter"),
+		KeyType:  TypeString,
+		Value:    []byte("x"),
+	})
+
+	if err == nil {
+		t.Error("Invalid increment should fail")
+	}
+}
+
+func TestEngineIncrBatchAndConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+
+	ng := New(&Config{
+		KVConfig: store.KVConfig{
+			"dataDir": DataDirTmp,
+		},
+		OpenCacheSize: 1,
+	})
+
+	defer func() {
+		ng.Close()
+		os.RemoveAll(DataDirTmp)
+	}()
+
+	incr := func(key string, delta uint64) error {
+		_, err := ng.Execute(Command{
+			Index:     sampleIndex,
+			Database:  "stats.db",
+			Command:   "incr",
+			Key:       []byte(key),
+			KeyType:   TypeString,
+			Value:     utils.Uint64ToBytes(delta),
+			ValueType: TypeUint,
+		})
+
+		return err
+	}
+
+	get := func(key string) uint64 {
+		result, err := ng.Execute(Command{
+			Index:    sampleIndex,
+			Database: "stats.db",
+			Command:  "get",
+			Key:      []byte(key),
+			KeyType:  TypeString,
+		})
+
+		if err != nil || len(result.Data) != 8 {
+			return 0
+		}
+
+		return utils.BytesToUint64(result.Data)
+	}
+
+	for n := 0; n < 20; n++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 10; j++ {
+				if err := incr("concurrent", 1); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if counter := get("concurrent"); counter != 200 {
+		t.Errorf("Concurrent increments lost: %d", counter)
+		return
+	}
+
+	if _, err := ng.Execute(Command{Index: sampleIndex, Database: "stats.db", Command: "batch"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, delta := range []uint64{1, 2, 3} {
+		if err := incr("batched", delta); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	if _, err := ng.Execute(Command{Index: sampleIndex, Database: "stats.db", Command: "flushbatch"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if counter := get("batched"); counter != 6 {
+		t.Errorf("Batched increments lost: %d", counter)
+	}
+}
+
+func TestEngineMergeDelAndDecr(t *testing.T) {
+	ng := New(&Config{
+		KVConfig: store.KVConfig{
+			"dataDir": DataDirTmp,
+		},
+		OpenCacheSize: 1,
+	})
+
+	defer func() {
+		ng.Close()
+		os.RemoveAll(DataDirTmp)
+	}()
+
+	for _, cmd := range []Command{
+		{Command: "mergeset", Key: []byte("neoway"), Value: utils.Uint64ToBytes(1)},
+		{Command: "mergeset", Key: []byte("neoway"), Value: utils.Uint64ToBytes(2)},
+		{Command: "mergeset", Key: []byte("neoway"), Value: utils.Uint64ToBytes(3)},
+		{Command: "mergedel", Key: []byte("neoway"), Value: utils.Uint64ToBytes(2)},
+		{Command: "mergedel", Key: []byte("neoway"), Value: utils.Uint64ToBytes(5)},
+		{Command: "incr", Key: []byte("counter"), Value: utils.Uint64ToBytes(10)},
+		{Command: "decr", Key: []byte("counter"), Value: utils.Uint64ToBytes(3)},
+		{Command: "decr", Key: []byte("zero"), Value: utils.Uint64ToBytes(3)},
+	} {
+		cmd.Index = sampleIndex
+		cmd.Database = "sample.idx"
+		cmd.KeyType = TypeString
+		cmd.ValueType = TypeUint
+
+		if _, err := ng.Execute(cmd); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	for key, expected := range map[string][]byte{
+		"neoway":  append(utils.Uint64ToBytes(1), utils.Uint64ToBytes(3)...),
+		"counter": utils.Uint64ToBytes(7),
+		"zero":    utils.Uint64ToBytes(0),
+	} {
+		result, err := ng.Execute(Command{
+			Index:    sampleIndex,
+			Database: "sample.idx",
+			Command:  "get",
+			Key:      []byte(key),
+			KeyType:  TypeString,
+		})
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if !bytes.Equal(result.Data, expected) {
+			t.Errorf("Unexpected value of %s: %v", key, result.Data)
+		}
+	}
+}
+
+func TestEngineDump(t *testing.T) {
+	ng := New(&Config{
+		KVConfig: store.KVConfig{
+			"dataDir": DataDirTmp,
+		},
+		OpenCacheSize: 1,
+	})
+
+	defer func() {
+		ng.Close()
+		os.RemoveAll(DataDirTmp)
+	}()
+
+	execSequence(t, ng, []Command{
+		{Index: sampleIndex, Database: "name_string.idx", Command: "mergeset",
+			Key: []byte("neoway"), Value: utils.Uint64ToBytes(3)},
+		{Index: sampleIndex, Database: "name_string.idx", Command: "mergeset",
+			Key: []byte("neoway"), Value: utils.Uint64ToBytes(1)},
+		{Index: sampleIndex, Database: "name_string.idx", Command: "mergeset",
+			Key: []byte("empty"), Value: utils.Uint64ToBytes(1)},
+		{Index: sampleIndex, Database: "name_string.idx", Command: "mergedel",
+			Key: []byte("empty"), Value: utils.Uint64ToBytes(1)},
+		{Index: sampleIndex, Database: "document.db", Command: "set",
+			Key: utils.Uint64ToBytes(1), Value: []byte(`{"name": "neoway"}`)},
+	})
+
+	for database, expected := range map[string][]string{
+		"name_string.idx": []string{
+			`USING sample.name_string.idx SET 'neoway' "\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x03";`,
+		},
+		"document.db": []string{
+			`USING sample.document.db SET uint(1) "{\"name\": \"neoway\"}";`,
+		},
+	} {
+		var lines []string
+
+		err := ng.Dump(sampleIndex, database, func(cmd Command) error {
+			lines = append(lines, cmd.Reverse())
+			return nil
+		})
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if !reflect.DeepEqual(lines, expected) {
+			t.Errorf("Unexpected dump of %s: %v", database, lines)
+		}
+	}
+
+	// posting lists are restored by a single set
+	err := ng.Dump(sampleIndex, "name_string.idx", func(cmd Command) error {
+		cmd.Index = sampleIndex + "-restored"
+		_, err := ng.Execute(cmd)
+		return err
+	})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	result, err := ng.Execute(Command{Index: sampleIndex + "-restored", Database: "name_string.idx",
+		Command: "get", Key: []byte("neoway")})
+
+	if err != nil || !reflect.DeepEqual(result.Data, append(utils.Uint64ToBytes(1), utils.Uint64ToBytes(3)...)) {
+		t.Errorf("Unexpected restored postings: %v, %v", result, err)
+	}
+
+	execSequence(t, ng, []Command{
+		{Index: sampleIndex, Database: "id_uint.idx", Command: "set",
+			Key: []byte("bad"), Value: utils.Uint64ToBytes(1)},
+	})
+
+	if err = ng.Dump(sampleIndex, "id_uint.idx", func(cmd Command) error { return nil }); err == nil {
+		t.Error("Dump of invalid keys should fail")
+	}
+}
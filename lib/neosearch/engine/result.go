@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
+)
+
+// Entry is a single key/value pair returned by the engine.
+type Entry struct {
+	Key   []byte
+	Value []byte
+}
+
+// Result stores the output of a executed Command.
+// KeyType and ValueType describe how the raw bytes of Data and Entries
+// must be decoded. See Decode.
+type Result struct {
+	// Data is the value returned by the get command
+	Data []byte
+
+	// Entries are the key/value pairs returned by scan and keys commands
+	Entries []Entry
+
+	// Count is the number of keys visited by count and stats commands
+	Count uint64
+
+	// Stats stores the storage statistics returned by stats command
+	Stats map[string]uint64
+
+	KeyType   uint8
+	ValueType uint8
+}
+
+// Key returns the decoded key of the entry at position i
+func (r *Result) Key(i int) (interface{}, error) {
+	return Decode(r.Entries[i].Key, r.KeyType, false)
+}
+
+// Value returns the decoded value of the entry at position i
+func (r *Result) Value(i int) (interface{}, error) {
+	return Decode(r.Entries[i].Value, r.ValueType, true)
+}
+
+// DataValue returns the decoded value returned by the get command
+func (r *Result) DataValue() (interface{}, error) {
+	if r.Data == nil {
+		return nil, nil
+	}
+
+	return Decode(r.Data, r.ValueType, true)
+}
+
+// Decode converts the raw bytes of a key or value to the Go type
+// represented by typ. When isValue is true, uint data is decoded as a
+// ordered set of uint64 ids, because that's the way NeoSearch stores
+// posting lists (see store.MergeSet).
+func Decode(data []byte, typ uint8, isValue bool) (interface{}, error) {
+	switch typ {
+	case TypeUint:
+		if isValue {
+			if len(data)%8 != 0 {
+				return nil, fmt.Errorf("Invalid uint set of length %d", len(data))
+			}
+
+			return utils.GetUint64Array(data), nil
+		}
+
+		if len(data) != 8 {
+			return nil, fmt.Errorf("Invalid uint of length %d", len(data))
+		}
+
+		return utils.BytesToUint64(data), nil
+	case TypeInt, TypeDate:
+		if len(data) != 8 {
+			return nil, fmt.Errorf("Invalid int of length %d", len(data))
+		}
+
+		return utils.BytesToInt64(data), nil
+	case TypeFloat:
+		if len(data) != 8 {
+			return nil, fmt.Errorf("Invalid float of length %d", len(data))
+		}
+
+		return utils.BytesToFloat64(data), nil
+	case TypeBool:
+		if len(data) != 1 {
+			return nil, fmt.Errorf("Invalid bool of length %d", len(data))
+		}
+
+		return utils.BytesToBool(data), nil
+	case TypeString:
+		return string(data), nil
+	}
+
+	return data, nil
+}
+
+// StorageKeyType returns the engine type of the keys of the storage given
+// by database, eg.: TypeInt for name_int.idx.
+func StorageKeyType(database string) uint8 {
+	return storageKeyType(database)
+}
+
+// storageKeyType returns the key type of the index storage given by
+// database, based on the naming convention used by the index package:
+// <field>_<type>.idx for fields and document.db for documents.
+func storageKeyType(database string) uint8 {
+	if database == "document.db" {
+		return TypeUint
+	}
+
+	// doc values (.dv) and join (.join) storages are keyed by document id
+	if strings.HasSuffix(database, ".dv") || strings.HasSuffix(database, ".join") {
+		return TypeUint
+	}
+
+	if !strings.HasSuffix(database, ".idx") {
+		return TypeString
+	}
+
+	return storageFieldType(database[0 : len(database)-len(".idx")])
+}
+
+// storageFieldType returns the type of the field storage name
+// (<field>_<type>) without extension.
+func storageFieldType(name string) uint8 {
+	switch {
+	case strings.HasSuffix(name, "_uint"):
+		return TypeUint
+	case strings.HasSuffix(name, "_int"):
+		return TypeInt
+	case strings.HasSuffix(name, "_float"):
+		return TypeFloat
+	case strings.HasSuffix(name, "_bool"):
+		return TypeBool
+	}
+
+	return TypeString
+}
+
+// storageValueType returns the value type of the storage given by
+// database. Index (.idx) and join (.join) storages always store sets of
+// document ids.
+func storageValueType(database string) uint8 {
+	if strings.HasSuffix(database, ".idx") || strings.HasSuffix(database, ".join") {
+		return TypeUint
+	}
+
+	// scoring storages (.bm25) store frequencies and counters
+	if strings.HasSuffix(database, ".bm25") {
+		return TypeUint
+	}
+
+	// doc values storages (.dv) store a single value of the field type
+	if strings.HasSuffix(database, ".dv") {
+		return storageFieldType(database[0 : len(database)-len(".dv")])
+	}
+
+	return TypeString
+}
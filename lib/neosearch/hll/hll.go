@@ -0,0 +1,197 @@
+// Package hll implements the HyperLogLog++ cardinality estimator.
+//
+// Sketches use 64-bit hashes, so no large range correction is needed, and
+// keep the exact set of hashes (sparse representation) while it's smaller
+// than the dense registers. Small cardinalities of the dense
+// representation are estimated with linear counting, using the thresholds
+// of the HyperLogLog++ paper. Sketches with the same precision can be
+// merged, eg.: to count distinct values across indices.
+package hll
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+)
+
+// Precision limits and default
+const (
+	MinPrecision     uint8 = 4
+	MaxPrecision     uint8 = 18
+	DefaultPrecision uint8 = 14
+)
+
+// linearCountingThreshold are the cardinalities up to which linear
+// counting is more accurate than the raw estimate, by precision.
+var linearCountingThreshold = [...]float64{
+	10, 20, 40, 80, 220, 400, 900, 1800, 3100, 6500, 11500, 20000, 50000, 120000, 350000,
+}
+
+// Sketch estimates the number of distinct values added to it. The
+// standard error is about 1.04/sqrt(2^precision).
+type Sketch struct {
+	precision uint8
+
+	// sparse is the set of hashes added while the sketch is small
+	sparse map[uint64]struct{}
+
+	// registers is the dense representation, nil while sparse
+	registers []uint8
+}
+
+// New creates a sketch with 2^precision registers
+func New(precision uint8) (*Sketch, error) {
+	if precision < MinPrecision || precision > MaxPrecision {
+		return nil, fmt.Errorf("Invalid precision %d. Precision must be between %d and %d",
+			precision, MinPrecision, MaxPrecision)
+	}
+
+	return &Sketch{
+		precision: precision,
+		sparse:    make(map[uint64]struct{}),
+	}, nil
+}
+
+// Precision returns the precision of the sketch
+func (s *Sketch) Precision() uint8 {
+	return s.precision
+}
+
+// Hash returns the 64-bit hash of data used by the sketches
+func Hash(data []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(data)
+
+	// finalizer of splitmix64 to spread the bits of FNV
+	x := h.Sum64()
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// Add adds the value data to the sketch
+func (s *Sketch) Add(data []byte) {
+	s.AddHash(Hash(data))
+}
+
+// AddUint64 adds the uint64 value to the sketch
+func (s *Sketch) AddUint64(value uint64) {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, value)
+	s.Add(data)
+}
+
+// AddHash adds a hash returned by Hash to the sketch
+func (s *Sketch) AddHash(hash uint64) {
+	if s.registers == nil {
+		s.sparse[hash] = struct{}{}
+
+		// the dense registers use one byte per register
+		if len(s.sparse)*8 > 1<<s.precision {
+			s.toDense()
+		}
+
+		return
+	}
+
+	s.addDense(hash)
+}
+
+func (s *Sketch) addDense(hash uint64) {
+	idx := hash >> (64 - s.precision)
+
+	// rank of the first 1 bit of the remaining bits
+	var rank uint8 = 1
+
+	for w := hash << s.precision; rank <= 64-s.precision && w&(1<<63) == 0; w <<= 1 {
+		rank++
+	}
+
+	if rank > s.registers[idx] {
+		s.registers[idx] = rank
+	}
+}
+
+func (s *Sketch) toDense() {
+	s.registers = make([]uint8, 1<<s.precision)
+
+	for hash := range s.sparse {
+		s.addDense(hash)
+	}
+
+	s.sparse = nil
+}
+
+// Merge adds the values of other to s. Both must have the same precision.
+func (s *Sketch) Merge(other *Sketch) error {
+	if other.precision != s.precision {
+		return fmt.Errorf("Can't merge sketches of precisions %d and %d", s.precision, other.precision)
+	}
+
+	if other.registers == nil {
+		for hash := range other.sparse {
+			s.AddHash(hash)
+		}
+
+		return nil
+	}
+
+	if s.registers == nil {
+		s.toDense()
+	}
+
+	for idx, rank := range other.registers {
+		if rank > s.registers[idx] {
+			s.registers[idx] = rank
+		}
+	}
+
+	return nil
+}
+
+// Count returns the estimated number of distinct values. It's exact while
+// the sketch is sparse, except for hash collisions.
+func (s *Sketch) Count() uint64 {
+	if s.registers == nil {
+		return uint64(len(s.sparse))
+	}
+
+	var (
+		m     = float64(len(s.registers))
+		sum   float64
+		zeros int
+	)
+
+	for _, rank := range s.registers {
+		sum += 1 / float64(uint64(1)<<rank)
+
+		if rank == 0 {
+			zeros++
+		}
+	}
+
+	if zeros > 0 {
+		estimate := m * math.Log(m/float64(zeros))
+
+		if estimate <= linearCountingThreshold[s.precision-MinPrecision] {
+			return uint64(estimate + 0.5)
+		}
+	}
+
+	return uint64(alpha(m)*m*m/sum + 0.5)
+}
+
+// alpha is the bias correction constant of m registers
+func alpha(m float64) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	}
+
+	return 0.7213 / (1 + 1.079/m)
+}
@@ -0,0 +1,103 @@
+package hll
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestSketchPrecision(t *testing.T) {
+	if _, err := New(MinPrecision - 1); err == nil {
+		t.Error("Precision below the minimum should fail")
+	}
+
+	if _, err := New(MaxPrecision + 1); err == nil {
+		t.Error("Precision above the maximum should fail")
+	}
+
+	s, err := New(DefaultPrecision)
+
+	if err != nil || s.Precision() != DefaultPrecision {
+		t.Errorf("Failed to create sketch: %v", err)
+	}
+}
+
+func TestSketchSparseIsExact(t *testing.T) {
+	s, _ := New(DefaultPrecision)
+
+	for i := 0; i < 1000; i++ {
+		s.Add([]byte(strconv.Itoa(i % 100)))
+	}
+
+	if count := s.Count(); count != 100 {
+		t.Errorf("Sparse sketch should be exact: %d", count)
+	}
+}
+
+func TestSketchEstimate(t *testing.T) {
+	for _, tc := range []struct {
+		precision uint8
+		distinct  int
+	}{
+		{10, 500},
+		{10, 100000},
+		{DefaultPrecision, 50000},
+		{DefaultPrecision, 300000},
+	} {
+		s, _ := New(tc.precision)
+
+		for i := 0; i < tc.distinct; i++ {
+			s.AddUint64(uint64(i))
+			s.AddUint64(uint64(i))
+		}
+
+		// 4 standard errors
+		maxErr := 4 * 1.04 / math.Sqrt(float64(uint64(1)<<tc.precision))
+		relErr := math.Abs(float64(s.Count())-float64(tc.distinct)) / float64(tc.distinct)
+
+		if relErr > maxErr {
+			t.Errorf("Estimate %d of %d distinct values with precision %d: error %.4f > %.4f",
+				s.Count(), tc.distinct, tc.precision, relErr, maxErr)
+		}
+	}
+}
+
+func TestSketchMerge(t *testing.T) {
+	a, _ := New(12)
+	b, _ := New(12)
+	c, _ := New(12)
+
+	for i := 0; i < 30000; i++ {
+		a.AddUint64(uint64(i))
+	}
+
+	for i := 20000; i < 50000; i++ {
+		b.AddUint64(uint64(i))
+	}
+
+	for i := 0; i < 10; i++ {
+		c.AddUint64(uint64(i))
+	}
+
+	if err := a.Merge(b); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := a.Merge(c); err != nil {
+		t.Error(err)
+		return
+	}
+
+	relErr := math.Abs(float64(a.Count())-50000) / 50000
+
+	if relErr > 4*1.04/64 {
+		t.Errorf("Unexpected estimate of merged sketches: %d", a.Count())
+	}
+
+	d, _ := New(10)
+
+	if err := a.Merge(d); err == nil {
+		t.Error("Merging sketches of different precisions should fail")
+	}
+}
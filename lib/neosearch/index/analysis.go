@@ -0,0 +1,121 @@
+package index
+
+import (
+	"fmt"
+	"strings"
+)
+
+// String analyzers. The analyzer of a string field is given by the
+// "analyzer" option of the field metadata.
+const (
+	// AnalyzerStandard lowercases the value and splits it by spaces. The
+	// entire value is indexed too.
+	AnalyzerStandard string = "standard"
+
+	// AnalyzerNgram indexes every substring of the tokens with length
+	// between the "min_gram" and "max_gram" options.
+	AnalyzerNgram string = "ngram"
+
+	defaultMinGram int = 2
+	defaultMaxGram int = 3
+)
+
+// validateAnalyzer verifies the analyzer options of a string field.
+func validateAnalyzer(metadata Metadata) error {
+	_, _, err := analyze("", metadata)
+	return err
+}
+
+// analyze returns the terms of value to be indexed by the analyzer of
+// the string field and the length of the field used by scoring (the
+// number of tokens).
+func analyze(value string, metadata Metadata) ([]string, uint64, error) {
+	analyzer, ok := metadata["analyzer"].(string)
+
+	if !ok && metadata["analyzer"] != nil {
+		return nil, 0, fmt.Errorf("Invalid analyzer: %+v", metadata["analyzer"])
+	}
+
+	switch analyzer {
+	case "", AnalyzerStandard:
+		terms, length := analyzeStandard(value)
+		return terms, length, nil
+	case AnalyzerNgram:
+		minGram, err := intOption(metadata, "min_gram", defaultMinGram)
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		maxGram, err := intOption(metadata, "max_gram", defaultMaxGram)
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if minGram < 1 || maxGram < minGram {
+			return nil, 0, fmt.Errorf("Invalid ngram range [%d, %d]", minGram, maxGram)
+		}
+
+		grams := analyzeNgram(value, minGram, maxGram)
+		return grams, uint64(len(grams)), nil
+	}
+
+	return nil, 0, fmt.Errorf("Unknown analyzer '%s'", analyzer)
+}
+
+func analyzeStandard(value string) ([]string, uint64) {
+	// default/hardcoded analyser == tokenizer
+	value = strings.Trim(value, " ")
+	value = strings.ToLower(value)
+	tokens := strings.Split(value, " ")
+	length := uint64(len(tokens))
+
+	if len(tokens) == 1 {
+		// if there's one token, then no need for index entire string
+		return tokens, length
+	}
+
+	// Index all string
+	return append(tokens, value), length
+}
+
+func analyzeNgram(value string, minGram, maxGram int) []string {
+	var grams []string
+
+	seen := map[string]bool{}
+
+	for _, token := range strings.Fields(strings.ToLower(value)) {
+		runes := []rune(token)
+
+		for size := minGram; size <= maxGram; size++ {
+			for start := 0; start+size <= len(runes); start++ {
+				gram := string(runes[start : start+size])
+
+				if !seen[gram] {
+					seen[gram] = true
+					grams = append(grams, gram)
+				}
+			}
+		}
+	}
+
+	return grams
+}
+
+// intOption returns the integer option name of metadata. JSON numbers are
+// decoded as float64.
+func intOption(metadata Metadata, name string, defaultValue int) (int, error) {
+	switch v := metadata[name].(type) {
+	case nil:
+		return defaultValue, nil
+	case int:
+		return v, nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	}
+
+	return 0, fmt.Errorf("Invalid option '%s': %+v", name, metadata[name])
+}
@@ -0,0 +1,180 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/store"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
+)
+
+// ErrCompactCanceled is returned by CompactWithOptions when
+// CompactOptions.Cancel is closed
+var ErrCompactCanceled = errors.New("Compaction canceled")
+
+// CompactOptions of CompactWithOptions
+type CompactOptions struct {
+	// Fields are the fields whose storages are compacted. Every storage
+	// of the index is compacted without fields.
+	Fields []string
+
+	// Progress is called after every storage
+	Progress func(progress CompactProgress)
+
+	// Cancel stops the compaction before the next storage when closed
+	Cancel <-chan struct{}
+}
+
+// CompactProgress reports the progress of a compaction
+type CompactProgress struct {
+	// Total is the number of storages to compact
+	Total int `json:"total"`
+
+	// Compacted are the storages already compacted
+	Compacted int `json:"compacted"`
+
+	// Pruned is the number of empty posting lists deleted
+	Pruned uint64 `json:"pruned"`
+}
+
+// Compact compacts the storages of fields, or every storage of the index
+// without fields. See CompactWithOptions.
+func (i *Index) Compact(fields ...string) (CompactProgress, error) {
+	return i.CompactWithOptions(CompactOptions{Fields: fields})
+}
+
+// CompactWithOptions deletes the empty posting lists left by Delete and
+// mergedel, then compacts each storage discarding deleted and overwritten
+// entries. Stores without the store.KVCompacter capability are only
+// pruned. Documents are still added while compacting, they're only
+// blocked while the empty posting lists of a storage are deleted.
+func (i *Index) CompactWithOptions(opts CompactOptions) (CompactProgress, error) {
+	var progress CompactProgress
+
+	storages, err := i.compactStorages(opts.Fields)
+
+	if err != nil {
+		return progress, err
+	}
+
+	progress.Total = len(storages)
+
+	for _, storage := range storages {
+		select {
+		case <-opts.Cancel:
+			return progress, ErrCompactCanceled
+		default:
+		}
+
+		storekv, err := i.engine.GetStore(i.Name, storage)
+
+		if err != nil {
+			return progress, err
+		}
+
+		if strings.HasSuffix(storage, ".idx") || strings.HasSuffix(storage, ".join") {
+			pruned, err := i.pruneEmpty(storekv)
+			progress.Pruned += pruned
+
+			if err != nil {
+				return progress, fmt.Errorf("Pruning storage '%s' failed: %s", storage, err)
+			}
+		}
+
+		if compacter, ok := storekv.(store.KVCompacter); ok {
+			if err = compacter.CompactRange(nil, nil); err != nil {
+				return progress, fmt.Errorf("Compaction of storage '%s' failed: %s", storage, err)
+			}
+		}
+
+		progress.Compacted++
+
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+	}
+
+	return progress, nil
+}
+
+// compactStorages returns the storages of fields, or every storage of the
+// index without fields
+func (i *Index) compactStorages(fields []string) ([]string, error) {
+	var compact []string
+
+	storages, err := i.Storages()
+
+	if err != nil || len(fields) == 0 {
+		return storages, err
+	}
+
+	for _, field := range fields {
+		found := false
+
+		for _, storage := range storages {
+			if name, _, ok := storageField(storage); ok && name == utils.FieldNorm(field) {
+				compact = append(compact, storage)
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("Field '%s' has no storages", field)
+		}
+	}
+
+	return compact, nil
+}
+
+// pruneEmpty deletes the keys of the set storage with empty values and
+// returns the number of keys deleted
+func (i *Index) pruneEmpty(storekv store.KVStore) (uint64, error) {
+	var (
+		empty  [][]byte
+		pruned uint64
+	)
+
+	reader := storekv.Reader()
+	it := reader.GetIterator()
+
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		if len(it.Value()) == 0 {
+			empty = append(empty, append([]byte{}, it.Key()...))
+		}
+	}
+
+	err := it.GetError()
+	it.Close()
+	reader.Close()
+
+	if err != nil || len(empty) == 0 {
+		return 0, err
+	}
+
+	// postings merged after the scan aren't deleted
+	i.writeMutex.Lock()
+	defer i.writeMutex.Unlock()
+
+	writer := storekv.Writer()
+
+	for _, key := range empty {
+		value, err := writer.Get(key)
+
+		if err != nil {
+			return pruned, err
+		}
+
+		if len(value) > 0 {
+			continue
+		}
+
+		if err = writer.Delete(key); err != nil {
+			return pruned, err
+		}
+
+		pruned++
+	}
+
+	return pruned, nil
+}
@@ -0,0 +1,116 @@
+package index
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
+)
+
+// AllField is the catch-all field. When the "all" option of the index
+// settings is enabled, the text of every string field is also indexed in
+// the AllField storage, unless the field sets "include_in_all" to false.
+const AllField string = "_all"
+
+// copyTargets returns the fields given by the "copy_to" option of a
+// field. The option could be a single field name or a list of names.
+func copyTargets(metadata Metadata) ([]string, error) {
+	var targets []string
+
+	switch v := metadata["copy_to"].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		targets = []string{v}
+	case []string:
+		targets = v
+	case []interface{}:
+		for _, target := range v {
+			str, ok := target.(string)
+
+			if !ok {
+				return nil, fmt.Errorf("Invalid copy_to field: %+v", target)
+			}
+
+			targets = append(targets, str)
+		}
+	default:
+		return nil, fmt.Errorf("Invalid copy_to field: %+v", v)
+	}
+
+	for _, target := range targets {
+		if strings.TrimSpace(target) == "" {
+			return nil, fmt.Errorf("Invalid copy_to field: '%s'", target)
+		}
+	}
+
+	return targets, nil
+}
+
+func validateCopyTo(field string, fieldMeta Metadata) error {
+	targets, err := copyTargets(fieldMeta)
+
+	if err != nil {
+		return fmt.Errorf("Invalid mapping for field '%s': %s", field, err)
+	}
+
+	for _, target := range targets {
+		if target == field {
+			return fmt.Errorf("Invalid mapping for field '%s'. Field can't be copied to itself", field)
+		}
+	}
+
+	if include, ok := fieldMeta["include_in_all"]; ok {
+		if _, isBool := include.(bool); !isBool {
+			return fmt.Errorf("Invalid mapping for field '%s'. Option include_in_all must be boolean", field)
+		}
+	}
+
+	return nil
+}
+
+// buildIndexCopyTo appends to commands the commands to index the text
+// value of field in the fields given by "copy_to" and in the AllField.
+// Only string values are copied.
+func (i *Index) buildIndexCopyTo(id uint64, field string, value interface{}, metadata Metadata, commands []engine.Command) ([]engine.Command, error) {
+	text, ok := value.(string)
+
+	if !ok {
+		return commands, nil
+	}
+
+	targets, err := copyTargets(metadata)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, target := range targets {
+		target = utils.FieldNorm(target)
+
+		cmds, err := i.buildIndexValue(id, target, text, i.fieldMapping(target))
+
+		if err != nil {
+			return nil, err
+		}
+
+		commands = append(commands, cmds...)
+	}
+
+	if include, ok := metadata["include_in_all"].(bool); ok && !include {
+		return commands, nil
+	}
+
+	if !i.Settings().All {
+		return commands, nil
+	}
+
+	cmds, err := i.buildIndexString(id, AllField, text, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(commands, cmds...), nil
+}
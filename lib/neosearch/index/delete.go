@@ -0,0 +1,139 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+)
+
+// BuildDelete returns the commands needed to remove the document id from
+// the index. The commands revert the ones built to index the stored
+// document: its id is removed from the posting lists, the keys of the
+// document are deleted and the counters are decremented. Posting lists
+// left empty are kept with an empty value. The postings of fields indexed
+// before they were mapped are removed too, but the postings of fields
+// whose analysis options changed are only removed by Fsck.
+func (i *Index) BuildDelete(id uint64) ([]engine.Command, error) {
+	doc, err := i.storedDocument(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(doc) == 0 {
+		return nil, fmt.Errorf("Document %d not found in index '%s'", id, i.Name)
+	}
+
+	return i.buildRevert(id, doc)
+}
+
+// buildRevert returns the commands reverting the ones built to index doc
+func (i *Index) buildRevert(id uint64, doc []byte) ([]engine.Command, error) {
+	commands, _, err := i.buildCommands(id, doc, Metadata{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	unmapped, err := i.buildUnmapped(id, doc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	storages, err := i.Storages()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// storages never written aren't created by the reverted commands
+	existing := make(map[string]bool, len(storages))
+
+	for _, storage := range storages {
+		existing[storage] = true
+	}
+
+	var (
+		reverted []engine.Command
+		built    = make(map[string]bool, len(commands))
+	)
+
+	for _, cmd := range commands {
+		built[commandKey(cmd)] = true
+	}
+
+	// counters of unmapped fields aren't decremented twice
+	for _, cmd := range unmapped {
+		if cmd.Command != "incr" && !built[commandKey(cmd)] {
+			commands = append(commands, cmd)
+			built[commandKey(cmd)] = true
+		}
+	}
+
+	for _, cmd := range commands {
+		if !existing[cmd.Database] {
+			continue
+		}
+
+		switch cmd.Command {
+		case "set":
+			cmd.Command = "delete"
+			cmd.Value, cmd.ValueType = nil, engine.TypeNil
+		case "mergeset":
+			cmd.Command = "mergedel"
+		case "incr":
+			cmd.Command = "decr"
+		}
+
+		reverted = append(reverted, cmd)
+	}
+
+	return reverted, nil
+}
+
+// buildUnmapped returns the commands that indexed the fields of doc while
+// they weren't mapped
+func (i *Index) buildUnmapped(id uint64, doc []byte) ([]engine.Command, error) {
+	structData := map[string]interface{}{}
+
+	if err := json.Unmarshal(doc, &structData); err != nil {
+		return nil, err
+	}
+
+	return i.buildIndexFields(id, "", structData, nil)
+}
+
+// commandKey identifies the storage, key and value written by cmd
+func commandKey(cmd engine.Command) string {
+	return cmd.Database + "\x00" + cmd.Command + "\x00" + string(cmd.Key) + "\x00" + string(cmd.Value)
+}
+
+// Delete removes the document id from the index and from the materialized
+// joins of the index, and decrements the document count.
+func (i *Index) Delete(id uint64) error {
+	i.writeMutex.RLock()
+	defer i.writeMutex.RUnlock()
+
+	unlock := i.lockID(id)
+	defer unlock()
+
+	commands, err := i.BuildDelete(id)
+
+	if err != nil {
+		return err
+	}
+
+	commands = append(commands, i.buildCount("decr")...)
+
+	for _, cmd := range commands {
+		if _, err := i.engine.Execute(cmd); err != nil {
+			return err
+		}
+	}
+
+	i.setBatchDocument(id, nil)
+
+	return i.unlinkJoins(id)
+}
@@ -0,0 +1,194 @@
+package index
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
+)
+
+// Fields with the option "doc_values" enabled have a forward storage
+// (<field>_<type>.dv) that maps the document id to the field value. Doc
+// values are used to sort search results. Multi-valued fields keep the
+// last value of the list.
+
+// hasDocValues reports if the "doc_values" option of the field is enabled
+func hasDocValues(metadata Metadata) bool {
+	enabled, _ := metadata["doc_values"].(bool)
+	return enabled
+}
+
+func validateDocValues(field string, fieldMeta Metadata) error {
+	option, ok := fieldMeta["doc_values"]
+
+	if !ok {
+		return nil
+	}
+
+	if _, isBool := option.(bool); !isBool {
+		return fmt.Errorf("Invalid mapping for field '%s'. Option doc_values must be boolean", field)
+	}
+
+	fieldType, _ := fieldMeta["type"].(string)
+
+	switch normalizeType(fieldType) {
+	case "slice", "object":
+		return fmt.Errorf("Invalid mapping for field '%s'. Fields of type %s can't have doc values",
+			field, fieldType)
+	}
+
+	return nil
+}
+
+// docValueType returns the type of the doc values storage of the field
+// type. Dates are stored as int (unix nanoseconds), like in the inverted
+// storages.
+func docValueType(fieldType string) string {
+	fieldType = normalizeType(fieldType)
+
+	if fieldType == "date" {
+		return "int"
+	}
+
+	return fieldType
+}
+
+// buildIndexDocValue appends to commands the command to store the value
+// of field in its doc values storage.
+func (i *Index) buildIndexDocValue(id uint64, field string, value interface{}, metadata Metadata, commands []engine.Command) ([]engine.Command, error) {
+	var (
+		data      []byte
+		valueType uint8
+		err       error
+	)
+
+	fieldType, _ := metadata["type"].(string)
+	kind := reflect.TypeOf(value).Kind()
+
+	switch normalizeType(fieldType) {
+	case "uint":
+		var v uint64
+
+		if v, err = utils.Uint64FromInterface(value, kind); err == nil {
+			data, valueType = utils.Uint64ToBytes(v), engine.TypeUint
+		}
+	case "int":
+		var v int64
+
+		if v, err = utils.Int64FromInterface(value, kind); err == nil {
+			data, valueType = utils.Int64ToBytes(v), engine.TypeInt
+		}
+	case "date":
+		str, _ := value.(string)
+		t, err := parseDate(str, metadata)
+
+		if err != nil {
+			return nil, err
+		}
+
+		data, valueType = utils.Int64ToBytes(t.UnixNano()), engine.TypeInt
+	case "float":
+		v, ok := value.(float64)
+
+		if !ok {
+			return nil, fmt.Errorf("Error indexing field '%s'. Value '%+v' isn't float", field, value)
+		}
+
+		data, valueType = utils.Float64ToBytes(v), engine.TypeFloat
+	case "bool":
+		v, ok := value.(bool)
+
+		if !ok {
+			v, err = utils.BoolFromInterface(value, kind)
+		}
+
+		data, valueType = utils.BoolToBytes(v), engine.TypeBool
+	case "string":
+		str, _ := value.(string)
+		data, valueType = []byte(str), engine.TypeString
+	case "keyword":
+		str, _ := value.(string)
+
+		if str, err = normalizeKeyword(str, metadata); err == nil {
+			data, valueType = []byte(str), engine.TypeString
+		}
+	default:
+		return nil, fmt.Errorf("Field '%s' of type %s can't have doc values", field, fieldType)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Error indexing doc value of field '%s': %s", field, err)
+	}
+
+	storageName := field + "_" + docValueType(fieldType) + ".dv"
+
+	return append(commands, engine.Command{
+		Index:     i.Name,
+		Database:  storageName,
+		Command:   "set",
+		Key:       utils.Uint64ToBytes(id),
+		KeyType:   engine.TypeUint,
+		Value:     data,
+		ValueType: valueType,
+	}), nil
+}
+
+// DocValues returns the values of field for each document of docIDs. The
+// values are int64, uint64, float64, bool or string, depending on the
+// field type, or nil for documents without value.
+func (i *Index) DocValues(field string, docIDs []uint64) ([]interface{}, error) {
+	var valueType uint8
+
+	fieldName := utils.FieldNorm(field)
+	fieldMeta := i.fieldMapping(fieldName)
+
+	if !hasDocValues(fieldMeta) {
+		return nil, fmt.Errorf("Field '%s' has no doc values", field)
+	}
+
+	fieldType, _ := fieldMeta["type"].(string)
+	dvType := docValueType(fieldType)
+
+	switch dvType {
+	case "uint":
+		valueType = engine.TypeUint
+	case "int":
+		valueType = engine.TypeInt
+	case "float":
+		valueType = engine.TypeFloat
+	case "bool":
+		valueType = engine.TypeBool
+	default:
+		valueType = engine.TypeString
+	}
+
+	storekv, err := i.engine.GetStore(i.Name, fieldName+"_"+dvType+".dv")
+
+	if err != nil {
+		return nil, err
+	}
+
+	reader := storekv.Reader()
+	defer reader.Close()
+
+	values := make([]interface{}, len(docIDs))
+
+	for idx, id := range docIDs {
+		data, err := reader.Get(utils.Uint64ToBytes(id))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if data == nil {
+			continue
+		}
+
+		if values[idx], err = engine.Decode(data, valueType, false); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,233 @@
+package index
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// DateISO8601 is the date format recorded by dynamic mapping. Dates with
+// this format are parsed by any of the layouts in isoLayouts.
+const DateISO8601 string = "iso8601"
+
+var isoLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// parseISO8601 parses the date value using the ISO-8601 layouts.
+func parseISO8601(value string) (time.Time, error) {
+	for _, layout := range isoLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("Invalid ISO-8601 date: %s", value)
+}
+
+// detectMapping returns the metadata of fields in data that aren't
+// described by metadata. Nested objects and slices are detected
+// recursively.
+func detectMapping(data map[string]interface{}, metadata Metadata) Metadata {
+	detected := Metadata{}
+
+	for key, value := range data {
+		fieldMeta, mapped := toMetadata(metadata[key])
+
+		if !mapped {
+			if valueMeta := detectField(value); valueMeta != nil {
+				detected[key] = valueMeta
+			}
+
+			continue
+		}
+
+		fieldType, _ := fieldMeta["type"].(string)
+		submeta, _ := toMetadata(fieldMeta["metadata"])
+
+		switch normalizeType(fieldType) {
+		case "object":
+			object, ok := value.(map[string]interface{})
+
+			if !ok {
+				continue
+			}
+
+			if subDetected := detectMapping(object, submeta); len(subDetected) > 0 {
+				detected[key] = Metadata{
+					"type":     "object",
+					"metadata": subDetected,
+				}
+			}
+		case "slice":
+			if submeta != nil {
+				continue
+			}
+
+			if itemMeta := detectSliceItem(value); itemMeta != nil {
+				detected[key] = Metadata{
+					"type":     "slice",
+					"metadata": itemMeta,
+				}
+			}
+		}
+	}
+
+	return detected
+}
+
+// detectField returns the metadata of value on first sight of the field.
+// Null values are not detected.
+func detectField(value interface{}) Metadata {
+	switch v := value.(type) {
+	case bool:
+		return Metadata{"type": "bool"}
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v <= math.MaxInt64 {
+			return Metadata{"type": "int"}
+		}
+
+		return Metadata{"type": "float"}
+	case string:
+		if _, err := parseISO8601(v); err == nil {
+			return Metadata{"type": "date", "format": DateISO8601}
+		}
+
+		return Metadata{"type": "string"}
+	case []interface{}:
+		itemMeta := detectSliceItem(v)
+
+		if itemMeta == nil {
+			return nil
+		}
+
+		return Metadata{"type": "slice", "metadata": itemMeta}
+	case map[string]interface{}:
+		return Metadata{
+			"type":     "object",
+			"metadata": detectMapping(v, nil),
+		}
+	}
+
+	return nil
+}
+
+// detectSliceItem detects the type of the slice items by the first
+// non-null item.
+func detectSliceItem(value interface{}) Metadata {
+	items, ok := value.([]interface{})
+
+	if !ok {
+		return nil
+	}
+
+	for _, item := range items {
+		if itemMeta := detectField(item); itemMeta != nil {
+			return itemMeta
+		}
+	}
+
+	return nil
+}
+
+// checkValue verifies if value matches the mapped type of field.
+func checkValue(field, fieldType string, value interface{}) error {
+	var ok bool
+
+	switch normalizeType(fieldType) {
+	case "string", "keyword", "date":
+		_, ok = value.(string)
+	case "int":
+		v, isFloat := value.(float64)
+		ok = isFloat && v == math.Trunc(v)
+	case "uint":
+		v, isFloat := value.(float64)
+		ok = isFloat && v == math.Trunc(v) && v >= 0
+	case "float":
+		_, ok = value.(float64)
+	case "bool":
+		_, ok = value.(bool)
+	case "slice":
+		_, ok = value.([]interface{})
+	case "object":
+		_, ok = value.(map[string]interface{})
+	default:
+		ok = true
+	}
+
+	if !ok {
+		return fmt.Errorf("Field '%s' is mapped as '%s'. Value '%+v' rejected", field, fieldType, value)
+	}
+
+	return nil
+}
+
+// coerceValue converts value to the Go type expected by the mapped type
+// of field.
+func coerceValue(field, fieldType string, value interface{}) (interface{}, error) {
+	var (
+		result interface{}
+		err    error
+	)
+
+	switch normalizeType(fieldType) {
+	case "string", "keyword", "date":
+		switch v := value.(type) {
+		case string:
+			result = v
+		case float64:
+			result = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			result = strconv.FormatBool(v)
+		}
+	case "int", "uint", "float":
+		switch v := value.(type) {
+		case float64:
+			result = v
+		case string:
+			result, err = strconv.ParseFloat(v, 64)
+		case bool:
+			if v {
+				result = float64(1)
+			} else {
+				result = float64(0)
+			}
+		}
+
+		if f, ok := result.(float64); ok && normalizeType(fieldType) != "float" {
+			result = math.Trunc(f)
+
+			if normalizeType(fieldType) == "uint" && f < 0 {
+				result = nil
+			}
+		}
+	case "bool":
+		switch v := value.(type) {
+		case bool:
+			result = v
+		case string:
+			result, err = strconv.ParseBool(v)
+		case float64:
+			result = v != 0
+		}
+	case "slice":
+		if _, ok := value.([]interface{}); ok {
+			result = value
+		} else {
+			result = []interface{}{value}
+		}
+	default:
+		result = value
+	}
+
+	if result == nil || err != nil {
+		return nil, fmt.Errorf("Field '%s' is mapped as '%s'. Failed to coerce value '%+v'",
+			field, fieldType, value)
+	}
+
+	return result, nil
+}
@@ -2,25 +2,91 @@ package index
 
 import (
 	"bytes"
+	"fmt"
+	"strings"
 
 	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
 	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
 )
 
+// termStorage returns the storage of the field and the term value as it
+// was indexed in that storage. Terms of typed fields are parsed from their
+// string representation, eg.: "10" for uint fields.
+func (i *Index) termStorage(field, value []byte) (string, []byte, error) {
+	if string(field) == AllField {
+		// the catch-all field is indexed by the standard analyzer
+		term := strings.ToLower(strings.Trim(string(value), " "))
+		return AllField + "_string.idx", []byte(term), nil
+	}
+
+	fieldName := utils.FieldNorm(string(field))
+	fieldMeta := i.fieldMapping(fieldName)
+
+	fieldType, _ := fieldMeta["type"].(string)
+
+	if normalizeType(fieldType) == "keyword" {
+		term, err := normalizeKeyword(string(value), fieldMeta)
+
+		if err != nil {
+			return "", nil, err
+		}
+
+		return fieldName + "_keyword.idx", []byte(term), nil
+	}
+
+	switch normalizeType(fieldType) {
+	case "uint", "int", "float", "bool", "date":
+		var term interface{} = string(value)
+
+		if normalizeType(fieldType) == "date" {
+			t, err := parseDate(string(value), fieldMeta)
+
+			if err != nil {
+				return "", nil, err
+			}
+
+			term = t.UnixNano()
+		}
+
+		storage, keyType, err := i.invertedStorage(fieldName)
+
+		if err != nil {
+			return "", nil, err
+		}
+
+		key, ok := encodeTerm(term, keyType)
+
+		if !ok {
+			return "", nil, fmt.Errorf("Invalid term '%s' of %s field '%s'", value, fieldType, field)
+		}
+
+		return storage, key, nil
+	}
+
+	return fieldName + "_string.idx", value, nil
+}
+
 func (i *Index) FilterTermID(field, value []byte, limit uint64) ([]uint64, uint64, error) {
+	storage, term, err := i.termStorage(field, value)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
 	cmd := engine.Command{}
 	cmd.Index = i.Name
-	// TODO: implement search for every type
-	cmd.Database = utils.FieldNorm(string(field)) + "_string.idx"
+	cmd.Database = storage
 	cmd.Command = "get"
-	cmd.Key = value
+	cmd.Key = term
 	cmd.KeyType = engine.TypeString
-	data, err := i.engine.Execute(cmd)
+	result, err := i.engine.Execute(cmd)
 
 	if err != nil {
 		return nil, 0, err
 	}
 
+	data := result.Data
+
 	dataLimit := uint64(len(data) / 8)
 	total := dataLimit
 
@@ -69,8 +135,13 @@ func (i *Index) matchPrefix(field []byte, value []byte) ([]uint64, error) {
 		docIDs []uint64
 	)
 
-	// TODO: Implement search for all of field types
-	storekv, err := i.engine.GetStore(i.Name, utils.FieldNorm(string(field))+"_string.idx")
+	storage, value, err := i.termStorage(field, value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	storekv, err := i.engine.GetStore(i.Name, storage)
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,337 @@
+package index
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/store"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
+)
+
+// Kinds of the problems found by Fsck
+const (
+	// FsckDangling are ids of a posting list missing from document.db
+	FsckDangling = "dangling"
+
+	// FsckMissing are ids of documents having the term but missing
+	// from its posting list
+	FsckMissing = "missing"
+
+	// FsckUnsorted is a posting list with ids out of order or repeated
+	FsckUnsorted = "unsorted"
+
+	// FsckCorrupt is a key or posting list that can't be decoded, or a
+	// document that can't be indexed
+	FsckCorrupt = "corrupt"
+)
+
+// FsckProblem is an inconsistency found by Fsck. Key is the decoded term
+// of the posting list, or the document id for documents.
+type FsckProblem struct {
+	Kind    string      `json:"kind"`
+	Storage string      `json:"storage"`
+	Key     interface{} `json:"key"`
+	IDs     []uint64    `json:"ids,omitempty"`
+	Message string      `json:"message,omitempty"`
+}
+
+// FsckReport is the result of Fsck
+type FsckReport struct {
+	Documents uint64        `json:"documents"`
+	Storages  []string      `json:"storages"`
+	Postings  uint64        `json:"postings"`
+	Problems  []FsckProblem `json:"problems"`
+	Repaired  bool          `json:"repaired"`
+}
+
+func (r *FsckReport) add(kind, storage string, key interface{}, ids []uint64, message string) {
+	r.Problems = append(r.Problems, FsckProblem{
+		Kind:    kind,
+		Storage: storage,
+		Key:     key,
+		IDs:     ids,
+		Message: message,
+	})
+}
+
+// Fsck cross-checks every inverted storage (.idx) of the index against
+// document.db. The expected posting lists are built again from every
+// document like BuildAdd does, but the index is only changed when repair
+// is true: then fields detected by dynamic mapping are persisted as in
+// Add. Postings of ids that aren't in document.db, postings missing
+// for indexed documents and posting lists that are unsorted or corrupt are
+// reported and, when repair is true, rewritten in place. Postings of
+// existing documents that aren't built anymore are kept.
+// Documents aren't added or deleted while the index is checked.
+func (i *Index) Fsck(repair bool) (*FsckReport, error) {
+	i.writeMutex.Lock()
+	defer i.writeMutex.Unlock()
+
+	var detected Metadata
+
+	report := &FsckReport{Repaired: repair}
+	docIDs := make(map[uint64]struct{})
+
+	// storage -> key -> sorted ids
+	expected := make(map[string]map[string][]uint64)
+
+	err := i.WalkDocs(func(id uint64, doc []byte) error {
+		docIDs[id] = struct{}{}
+		report.Documents++
+
+		commands, newFields, err := i.buildCommands(id, doc, Metadata{})
+
+		if err == nil && newFields != nil {
+			detected, _, err = mergeMetadata(detected, newFields)
+		}
+
+		if err != nil {
+			report.add(FsckCorrupt, dbName, id, nil, err.Error())
+			return nil
+		}
+
+		for _, cmd := range commands {
+			if cmd.Command != "mergeset" || !strings.HasSuffix(cmd.Database, ".idx") {
+				continue
+			}
+
+			postings, ok := expected[cmd.Database]
+
+			if !ok {
+				postings = make(map[string][]uint64)
+				expected[cmd.Database] = postings
+			}
+
+			ids := postings[string(cmd.Key)]
+
+			if len(ids) == 0 || ids[len(ids)-1] != id {
+				postings[string(cmd.Key)] = append(ids, id)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if repair && detected != nil {
+		if err = i.updateMapping(detected); err != nil {
+			return nil, err
+		}
+	}
+
+	storages, err := i.Storages()
+
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]bool, len(storages))
+
+	for _, storage := range storages {
+		existing[storage] = true
+
+		if _, ok := expected[storage]; !ok && strings.HasSuffix(storage, ".idx") {
+			report.Storages = append(report.Storages, storage)
+		}
+	}
+
+	for storage := range expected {
+		report.Storages = append(report.Storages, storage)
+	}
+
+	sort.Strings(report.Storages)
+
+	for _, storage := range report.Storages {
+		if !existing[storage] && !repair {
+			// checks don't create the storages
+			err = fsckMissing(storage, expected[storage], nil, report)
+		} else {
+			err = i.fsckStorage(storage, expected[storage], docIDs, repair, report)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("Fsck of storage '%s' failed: %s", storage, err)
+		}
+	}
+
+	return report, nil
+}
+
+// fsckStorage checks the posting lists of storage against the expected
+// ids by key, that are removed from expected when found
+func (i *Index) fsckStorage(storage string, expected map[string][]uint64, docIDs map[uint64]struct{}, repair bool, report *FsckReport) error {
+	storekv, err := i.engine.GetStore(i.Name, storage)
+
+	if err != nil {
+		return err
+	}
+
+	reader := storekv.Reader()
+	it := reader.GetIterator()
+
+	defer func() {
+		it.Close()
+		reader.Close()
+	}()
+
+	writer := storekv.Writer()
+	keyType := engine.StorageKeyType(storage)
+
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		key := append([]byte{}, it.Key()...)
+		value := it.Value()
+		want := expected[string(key)]
+
+		delete(expected, string(key))
+		report.Postings++
+
+		term, err := engine.Decode(key, keyType, false)
+
+		if err != nil {
+			report.add(FsckCorrupt, storage, string(key), nil, "invalid key: "+err.Error())
+
+			if repair {
+				if err = writer.Delete(key); err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		if len(value)%8 != 0 {
+			// the list is rebuilt from the documents
+			report.add(FsckCorrupt, storage, term, nil,
+				fmt.Sprintf("invalid posting list of length %d", len(value)))
+
+			if repair {
+				if err = writePostings(writer, key, want); err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		var (
+			dangling, missing []uint64
+			unsorted          bool
+		)
+
+		ids := utils.GetUint64Array(value)
+		found := make(map[uint64]struct{}, len(ids))
+
+		for n, id := range ids {
+			if n > 0 && id <= ids[n-1] {
+				unsorted = true
+			}
+
+			if _, ok := docIDs[id]; !ok {
+				dangling = append(dangling, id)
+			} else {
+				found[id] = struct{}{}
+			}
+		}
+
+		for _, id := range want {
+			if _, ok := found[id]; !ok {
+				missing = append(missing, id)
+			}
+		}
+
+		if unsorted {
+			report.add(FsckUnsorted, storage, term, nil, "")
+		}
+
+		if len(dangling) > 0 {
+			report.add(FsckDangling, storage, term, dangling, "")
+		}
+
+		if len(missing) > 0 {
+			report.add(FsckMissing, storage, term, missing, "")
+		}
+
+		if !repair || (!unsorted && len(dangling) == 0 && len(missing) == 0) {
+			continue
+		}
+
+		fixed := want
+
+		for id := range found {
+			fixed = append(fixed, id)
+		}
+
+		if err = writePostings(writer, key, fixed); err != nil {
+			return err
+		}
+	}
+
+	if err = it.GetError(); err != nil {
+		return err
+	}
+
+	if !repair {
+		writer = nil
+	}
+
+	return fsckMissing(storage, expected, writer, report)
+}
+
+// fsckMissing reports the posting lists of storage not found at all, in
+// storage order, and writes them when writer isn't nil
+func fsckMissing(storage string, expected map[string][]uint64, writer store.KVWriter, report *FsckReport) error {
+	var keys []string
+
+	for key := range expected {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	keyType := engine.StorageKeyType(storage)
+
+	for _, key := range keys {
+		term, err := engine.Decode([]byte(key), keyType, false)
+
+		if err != nil {
+			return err
+		}
+
+		report.add(FsckMissing, storage, term, expected[key], "")
+
+		if writer != nil {
+			if err = writePostings(writer, []byte(key), expected[key]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// writePostings writes the sorted and unique ids as the posting list of
+// key, deleting the key when there're no ids
+func writePostings(writer store.KVWriter, key []byte, ids []uint64) error {
+	if len(ids) == 0 {
+		return writer.Delete(key)
+	}
+
+	sort.Sort(utils.Uint64Slice(ids))
+
+	value := make([]byte, 0, len(ids)*8)
+
+	for n, id := range ids {
+		if n > 0 && id == ids[n-1] {
+			continue
+		}
+
+		value = append(value, utils.Uint64ToBytes(id)...)
+	}
+
+	return writer.Set(key, value)
+}
@@ -9,6 +9,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/NeowayLabs/neosearch/lib/neosearch/config"
@@ -21,6 +22,9 @@ import (
 const (
 	dbName   string = "document.db"
 	indexExt string = "idx"
+
+	// idLocks is the number of locks serializing the writes of documents
+	idLocks = 64
 )
 
 // Index represents an entire index
@@ -35,6 +39,33 @@ type Index struct {
 	enableBatchMode bool
 
 	flushStorages []string
+
+	// batchDocs are the documents written while in batch mode, because
+	// the write batches aren't read by Get
+	batchDocs  map[uint64][]byte
+	batchMutex sync.Mutex
+
+	// idMutexes serialize the writes of the documents by id
+	idMutexes [idLocks]sync.Mutex
+
+	dataDir string
+
+	// mapping stores the metadata of fields persisted in mapping.json
+	mapping      Metadata
+	mappingMutex sync.Mutex
+
+	// settings stores the index options persisted in settings.json
+	settings Settings
+
+	// joins are the materialized joins persisted in joins.json
+	joins []Join
+
+	// opener opens the other indices of the joins
+	opener IndexOpener
+
+	// writeMutex is held for reading by the writes of documents and for
+	// writing by Snapshot
+	writeMutex sync.RWMutex
 }
 
 // ValidateIndexName verifies if name is valid NeoSearch index name
@@ -69,13 +100,25 @@ func New(name string, cfg *config.Config, create bool) (*Index, error) {
 }
 
 func (i *Index) setup(cfg *config.Config, create bool) error {
-	dataDir := cfg.DataDir + "/" + i.Name
+	i.dataDir = cfg.DataDir + "/" + i.Name
 	if create {
-		if err := os.Mkdir(dataDir, 0755); err != nil {
+		if err := os.Mkdir(i.dataDir, 0755); err != nil {
 			return err
 		}
 	}
 
+	if err := i.loadMapping(); err != nil {
+		return err
+	}
+
+	if err := i.loadSettings(); err != nil {
+		return err
+	}
+
+	if err := i.loadJoins(); err != nil {
+		return err
+	}
+
 	i.debug = cfg.Debug
 
 	if cfg.Engine == nil {
@@ -91,7 +134,7 @@ func (i *Index) setup(cfg *config.Config, create bool) error {
 	kvcfg["debug"] = cfg.Debug
 
 	i.engine = engine.New(cfg.Engine)
-	return nil
+	return i.initDocumentCount()
 }
 
 // Batch enables write cache of command before FlushBatch is executed
@@ -118,20 +161,81 @@ func (i *Index) FlushBatch() {
 	}
 
 	i.flushStorages = make([]string, 0)
+
+	i.batchMutex.Lock()
+	i.batchDocs = nil
+	i.batchMutex.Unlock()
+}
+
+// lockID serializes the writes of the documents with the same id, that
+// read the stored document. It returns the unlock function.
+func (i *Index) lockID(id uint64) func() {
+	mutex := &i.idMutexes[id%idLocks]
+	mutex.Lock()
+	return mutex.Unlock
+}
+
+// storedDocument returns the stored document id, nil if it doesn't exist.
+// Documents written by the pending write batch are returned too.
+func (i *Index) storedDocument(id uint64) ([]byte, error) {
+	i.batchMutex.Lock()
+	doc, ok := i.batchDocs[id]
+	i.batchMutex.Unlock()
+
+	if ok {
+		return doc, nil
+	}
+
+	return i.Get(id)
+}
+
+// setBatchDocument records the document id written while the index is in
+// batch mode, nil for deleted documents
+func (i *Index) setBatchDocument(id uint64, doc []byte) {
+	i.batchMutex.Lock()
+	defer i.batchMutex.Unlock()
+
+	if len(i.flushStorages) == 0 {
+		return
+	}
+
+	if i.batchDocs == nil {
+		i.batchDocs = make(map[uint64][]byte)
+	}
+
+	i.batchDocs[id] = doc
 }
 
-// Add executes the sequence of commands necessary to index the document `doc`.
+// Add executes the sequence of commands necessary to index the document
+// `doc`, counts new documents and relates it to the documents of the
+// materialized joins.
 func (i *Index) Add(id uint64, doc []byte, metadata map[string]interface{}) error {
+	i.writeMutex.RLock()
+	defer i.writeMutex.RUnlock()
+
+	unlock := i.lockID(id)
+	defer unlock()
+
 	if metadata == nil {
 		metadata = Metadata{}
 	}
 
-	commands, err := i.BuildAdd(id, doc, metadata)
+	stored, err := i.storedDocument(id)
 
 	if err != nil {
 		return err
 	}
 
+	commands, newFields, err := i.BuildAdd(id, doc, metadata)
+
+	if err != nil {
+		return err
+	}
+
+	if len(stored) == 0 {
+		commands = append(commands, i.buildCount("incr")...)
+	}
+
 	for _, cmd := range commands {
 		_, err := i.engine.Execute(cmd)
 
@@ -140,12 +244,25 @@ func (i *Index) Add(id uint64, doc []byte, metadata map[string]interface{}) erro
 		}
 	}
 
-	return nil
-}
+	i.setBatchDocument(id, doc)
 
-func (i *Index) BuildAdd(id uint64, doc []byte, metadata Metadata) ([]engine.Command, error) {
-	var commands []engine.Command
+	if newFields != nil {
+		if err = i.updateMapping(newFields); err != nil {
+			return err
+		}
+	}
+
+	return i.linkJoins(id, commands)
+}
 
+// BuildAdd returns the commands needed to index the document `doc`.
+// The supplied metadata is merged with the index mapping. When dynamic
+// mapping is enabled in the index settings, the type of unmapped fields
+// is detected too. The scoring statistics of a stored document with the
+// same id are reverted first. The index isn't changed: the new fields of metadata
+// and the detected ones are returned, to be persisted in the mapping
+// with SetMapping after the commands are executed, as Add does.
+func (i *Index) BuildAdd(id uint64, doc []byte, metadata Metadata) ([]engine.Command, Metadata, error) {
 	if i.enableBatchMode {
 		// batchMode says if the BATCH operation is pending on indices
 		// If true, then we need run USING <idx> BATCH; on each index.
@@ -156,10 +273,39 @@ func (i *Index) BuildAdd(id uint64, doc []byte, metadata Metadata) ([]engine.Com
 		}()
 	}
 
+	commands, newFields, err := i.buildCommands(id, doc, metadata)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scoring, err := i.buildRevertScoring(id)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	commands = append(scoring, commands...)
+
+	if i.enableBatchMode {
+		commands = i.batchCommands(commands)
+	}
+
+	return commands, newFields, nil
+}
+
+// buildCommands returns the commands to index doc with the index mapping
+// merged with metadata and, when dynamic mapping is enabled, with the
+// types detected from doc. The metadata to persist in the index mapping is
+// returned when it adds fields. It doesn't change the index, then the
+// commands of stored documents are built again to check or revert them.
+func (i *Index) buildCommands(id uint64, doc []byte, metadata Metadata) ([]engine.Command, Metadata, error) {
+	var newFields Metadata
+
 	docCommands, err := i.buildAddDocument(id, doc)
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	structData := map[string]interface{}{}
@@ -167,43 +313,67 @@ func (i *Index) BuildAdd(id uint64, doc []byte, metadata Metadata) ([]engine.Com
 	err = json.Unmarshal(doc, &structData)
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if len(structData) == 0 {
-		return nil, errors.New("Empty document")
+		return nil, nil, errors.New("Empty document")
 	}
 
-	fieldCommands, err := i.buildIndexFields(id, "", structData, metadata)
+	fieldsMetadata, changed, err := i.applyMapping(metadata)
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	for _, cmd := range docCommands {
-		commands = append(commands, cmd)
+	if changed {
+		newFields = metadata
 	}
 
-	for _, cmd := range fieldCommands {
-		commands = append(commands, cmd)
+	if i.Settings().Dynamic {
+		detected := detectMapping(structData, fieldsMetadata)
+
+		if len(detected) > 0 {
+			if fieldsMetadata, _, err = mergeMetadata(fieldsMetadata, detected); err != nil {
+				return nil, nil, err
+			}
+
+			if newFields, _, err = mergeMetadata(metadata, detected); err != nil {
+				return nil, nil, err
+			}
+		}
 	}
 
-	return commands, nil
-}
+	fieldCommands, err := i.buildIndexFields(id, "", structData, fieldsMetadata)
 
-func (i *Index) buildAddDocument(id uint64, doc []byte) ([]engine.Command, error) {
-	var commands []engine.Command
+	if err != nil {
+		return nil, nil, err
+	}
 
-	commands = make([]engine.Command, 0, 2)
+	return mergeScoring(append(docCommands, fieldCommands...)), newFields, nil
+}
 
-	if i.enableBatchMode {
-		cmd, err := i.buildBatchOn(dbName)
+// batchCommands enables the batch mode of the storages of commands not
+// yet in batch mode, before their first command.
+func (i *Index) batchCommands(commands []engine.Command) []engine.Command {
+	batched := make([]engine.Command, 0, len(commands))
 
-		if err == nil {
-			commands = append(commands, cmd)
+	for _, cmd := range commands {
+		if batch, err := i.buildBatchOn(cmd.Database); err == nil {
+			batched = append(batched, batch)
 		}
+
+		batched = append(batched, cmd)
 	}
 
+	return batched
+}
+
+func (i *Index) buildAddDocument(id uint64, doc []byte) ([]engine.Command, error) {
+	var commands []engine.Command
+
+	commands = make([]engine.Command, 0, 1)
+
 	cmd := engine.Command{}
 	cmd.Database = dbName
 	cmd.Index = i.Name
@@ -229,7 +399,13 @@ func (i *Index) buildGet(id uint64) engine.Command {
 
 // Get retrieves the document by id
 func (i *Index) Get(id uint64) ([]byte, error) {
-	return i.engine.Execute(i.buildGet(id))
+	result, err := i.engine.Execute(i.buildGet(id))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Data, nil
 }
 
 func (i *Index) GetAnalyze(id uint64) (engine.Command, error) {
@@ -239,21 +415,25 @@ func (i *Index) GetAnalyze(id uint64) (engine.Command, error) {
 // GetDocs returns the content of documents specified by docIDs and limited
 // by limit.
 func (i *Index) GetDocs(docIDs []uint64, limit uint) ([]string, error) {
-	var (
-		docLen = uint(len(docIDs))
-	)
+	return i.GetDocsFrom(docIDs, 0, limit)
+}
 
-	if docLen > limit {
-		docLen = limit
+// GetDocsFrom returns the content of at most limit documents of docIDs,
+// skipping the first from documents.
+func (i *Index) GetDocsFrom(docIDs []uint64, from, limit uint) ([]string, error) {
+	if from >= uint(len(docIDs)) {
+		return []string{}, nil
 	}
 
-	docs := make([]string, docLen)
+	docIDs = docIDs[from:]
 
-	for idx, docID := range docIDs {
-		if uint(idx) == docLen {
-			break
-		}
+	if uint(len(docIDs)) > limit {
+		docIDs = docIDs[:limit]
+	}
+
+	docs := make([]string, len(docIDs))
 
+	for idx, docID := range docIDs {
 		if byteDoc, err := i.Get(docID); err == nil {
 			docs[idx] = string(byteDoc)
 		} else {
@@ -320,7 +500,7 @@ func (i *Index) buildIndexFields(id uint64, baseField string, structData map[str
 	sort.Strings(dataKeys)
 
 	for _, key := range dataKeys {
-		metainfo, ok := metadata[key].(Metadata)
+		metainfo, ok := toMetadata(metadata[key])
 
 		if !ok {
 			if i.debug {
@@ -352,7 +532,44 @@ func (i *Index) buildIndexFields(id uint64, baseField string, structData map[str
 	return commands, nil
 }
 
+// applyConflictPolicy checks or coerces value according to the conflict
+// policy of the index settings.
+func (i *Index) applyConflictPolicy(field, fieldType string, value interface{}) (interface{}, error) {
+	switch i.Settings().Conflict {
+	case ConflictReject:
+		return value, checkValue(field, fieldType, value)
+	case ConflictCoerce:
+		return coerceValue(field, fieldType, value)
+	}
+
+	return value, nil
+}
+
+// buildIndexField builds the commands to index the value of field, its
+// sub-fields and the copies of the value to other fields (copy_to/_all).
 func (i *Index) buildIndexField(id uint64, field string, value interface{}, metadata Metadata) ([]engine.Command, error) {
+	if value == nil {
+		// null values aren't indexed
+		return nil, nil
+	}
+
+	commands, err := i.buildIndexValue(id, field, value, metadata)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// sub-fields and copies are indexed from the source value
+	commands, err = i.buildIndexMultiFields(id, field, value, metadata, commands)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return i.buildIndexCopyTo(id, field, value, metadata, commands)
+}
+
+func (i *Index) buildIndexValue(id uint64, field string, value interface{}, metadata Metadata) ([]engine.Command, error) {
 	var (
 		commands  []engine.Command
 		err       error
@@ -360,8 +577,9 @@ func (i *Index) buildIndexField(id uint64, field string, value interface{}, meta
 		fieldType string
 	)
 
-	vtype := reflect.TypeOf(value)
-	fieldType = vtype.String()
+	if value == nil {
+		return nil, nil
+	}
 
 	if metadata != nil {
 		fieldType, ok = metadata["type"].(string)
@@ -369,6 +587,18 @@ func (i *Index) buildIndexField(id uint64, field string, value interface{}, meta
 		if !ok {
 			return nil, fmt.Errorf("Invalid metadata. Field 'type' is required: %+v", metadata)
 		}
+
+		value, err = i.applyConflictPolicy(field, fieldType, value)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	vtype := reflect.TypeOf(value)
+
+	if metadata == nil {
+		fieldType = vtype.String()
 	}
 
 	switch strings.ToLower(fieldType) {
@@ -379,7 +609,15 @@ func (i *Index) buildIndexField(id uint64, field string, value interface{}, meta
 			return nil, fmt.Errorf("Error indexing field '%s'. Value '%+v' isn't string", field, value)
 		}
 
-		commands, err = i.buildIndexString(id, field, vstr)
+		commands, err = i.buildIndexString(id, field, vstr, metadata)
+	case "keyword":
+		vstr, ok := value.(string)
+
+		if !ok {
+			return nil, fmt.Errorf("Error indexing field '%s'. Value '%+v' isn't keyword", field, value)
+		}
+
+		commands, err = i.buildIndexKeyword(id, field, vstr, metadata)
 	case "date":
 		dateStr, ok := value.(string)
 
@@ -445,7 +683,7 @@ func (i *Index) buildIndexField(id uint64, field string, value interface{}, meta
 			return nil, fmt.Errorf("Error indexing field '%s'. Value '%+v' isn't slice", field, value)
 		}
 
-		submetadata, ok := metadata["metadata"].(Metadata)
+		submetadata, ok := toMetadata(metadata["metadata"])
 
 		if !ok {
 			submetadata = nil
@@ -459,7 +697,7 @@ func (i *Index) buildIndexField(id uint64, field string, value interface{}, meta
 			return nil, fmt.Errorf("Error indexing field '%s'. Value '%+v' isn't object", field, value)
 		}
 
-		submetadata, ok := metadata["metadata"].(Metadata)
+		submetadata, ok := toMetadata(metadata["metadata"])
 
 		if !ok {
 			submetadata = nil
@@ -476,22 +714,17 @@ func (i *Index) buildIndexField(id uint64, field string, value interface{}, meta
 		return nil, errors.New(errMsg)
 	}
 
-	return commands, err
+	if err != nil || !hasDocValues(metadata) {
+		return commands, err
+	}
+
+	return i.buildIndexDocValue(id, field, value, metadata, commands)
 }
 
 // TODO: Index don't take care of item order
 func (i *Index) buildIndexSlice(id uint64, field string, values []interface{}, metadata Metadata) ([]engine.Command, error) {
 	var commands []engine.Command
 
-	storageName := field + "_slice.idx"
-
-	if i.enableBatchMode {
-		cmd, err := i.buildBatchOn(storageName)
-		if err == nil {
-			commands = append(commands, cmd)
-		}
-	}
-
 	for _, value := range values {
 		cmds, err := i.buildIndexField(id, field, value, metadata)
 
@@ -507,29 +740,25 @@ func (i *Index) buildIndexSlice(id uint64, field string, values []interface{}, m
 	return commands, nil
 }
 
-func (i *Index) buildIndexString(id uint64, field string, value string) ([]engine.Command, error) {
+func (i *Index) buildIndexString(id uint64, field string, value string, metadata Metadata) ([]engine.Command, error) {
 	var commands []engine.Command
 
-	// default/hardcoded analyser == tokenizer
-	value = strings.Trim(value, " ")
-	value = strings.ToLower(value)
-	tokens := strings.Split(value, " ")
-
-	storageName := field + "_string.idx"
+	tokens, length, err := analyze(value, metadata)
 
-	if i.enableBatchMode {
-		cmd, err := i.buildBatchOn(storageName)
-		if err == nil {
-			commands = append(commands, cmd)
-		}
+	if err != nil {
+		return nil, fmt.Errorf("Error indexing field '%s': %s", field, err)
 	}
 
-	addIndexStringCommand := func(dbase string, key []byte) {
+	storageName := field + "_string.idx"
+
+	// Index each token part
+	// TODO: Optimize array of tokens. Need be *unique* tokens
+	for _, t := range tokens {
 		cmd := engine.Command{}
 		cmd.Index = i.Name
-		cmd.Database = dbase
+		cmd.Database = storageName
 		cmd.Command = "mergeset"
-		cmd.Key = key
+		cmd.Key = []byte(t)
 		cmd.KeyType = engine.TypeString
 		cmd.Value = utils.Uint64ToBytes(id)
 		cmd.ValueType = engine.TypeUint
@@ -537,40 +766,32 @@ func (i *Index) buildIndexString(id uint64, field string, value string) ([]engin
 		commands = append(commands, cmd)
 	}
 
-	// Index each token part
-	// TODO: Optimize array of tokens. Need be *unique* tokens
-	for _, t := range tokens {
-		addIndexStringCommand(storageName, []byte(t))
-	}
+	return i.buildIndexScoring(id, storageName, tokens, length, commands)
+}
 
-	if len(tokens) == 1 {
-		// if there's one token, then no need for index entire string
-		return commands, nil
+func (i *Index) buildIndexDate(id uint64, field string, value string, metadata Metadata) ([]engine.Command, error) {
+	t, err := parseDate(value, metadata)
+
+	if err != nil {
+		return nil, err
 	}
 
-	// Index all string
-	addIndexStringCommand(storageName, []byte(value))
-	return commands, nil
+	return i.buildIndexInt64(id, field, t.UnixNano())
 }
 
-func (i *Index) buildIndexDate(id uint64, field string, value string, metadata Metadata) ([]engine.Command, error) {
-	var (
-		t time.Time
-	)
-
+// parseDate parses value using the "format" option of the date field
+func parseDate(value string, metadata Metadata) (time.Time, error) {
 	format, hasFmt := metadata["format"].(string)
 
 	if !hasFmt {
 		format = time.ANSIC
 	}
 
-	t, err := time.Parse(format, value)
-
-	if err != nil {
-		return nil, err
+	if format == DateISO8601 {
+		return parseISO8601(value)
 	}
 
-	return i.buildIndexInt64(id, field, t.UnixNano())
+	return time.Parse(format, value)
 }
 
 func (i *Index) buildIndexCommands(field string, cmdKey []byte, cmdVal []byte, keyType uint8) ([]engine.Command, error) {
@@ -598,13 +819,6 @@ func (i *Index) buildIndexCommands(field string, cmdKey []byte, cmdVal []byte, k
 
 	storageName := field + "_" + typeStr + ".idx"
 
-	if i.enableBatchMode {
-		cmd, err := i.buildBatchOn(storageName)
-		if err == nil {
-			commands = append(commands, cmd)
-		}
-	}
-
 	cmd := engine.Command{}
 	cmd.Index = i.Name
 	cmd.Database = storageName
@@ -0,0 +1,112 @@
+package index
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestIndexCompact(t *testing.T) {
+	var (
+		indexName = "document-sample-compact"
+		indexDir  = DataDirTmp + "/" + indexName
+		progress  CompactProgress
+		reports   []CompactProgress
+		cancel    = make(chan struct{})
+		terms     []interface{}
+		postings  [][]uint64
+		storages  []string
+		err       error
+		index     *Index
+	)
+
+	index, err = createIndex(indexName, t)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = index.SetMapping(Metadata{
+		"state":     Metadata{"type": "keyword"},
+		"employees": Metadata{"type": "int"},
+	})
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	for id, doc := range []string{
+		`{"state": "SC", "employees": 100}`,
+		`{"state": "SP", "employees": 10}`,
+		`{"state": "RS", "employees": 10}`,
+	} {
+		if err = index.Add(uint64(id), []byte(doc), nil); err != nil {
+			t.Error(err)
+			goto cleanup
+		}
+	}
+
+	// the posting lists of RS and SC are left empty
+	for _, id := range []uint64{0, 2} {
+		if err = index.Delete(id); err != nil {
+			t.Error(err)
+			goto cleanup
+		}
+	}
+
+	if progress, err = index.Compact("state"); err != nil ||
+		progress.Total != 1 || progress.Compacted != 1 || progress.Pruned != 2 {
+		t.Errorf("Unexpected compaction of state: %+v, %v", progress, err)
+		goto cleanup
+	}
+
+	err = index.WalkPostings("state", func(term interface{}, docIDs []uint64) error {
+		terms = append(terms, term)
+		postings = append(postings, docIDs)
+		return nil
+	})
+
+	if err != nil || !reflect.DeepEqual(terms, []interface{}{"SP"}) ||
+		!reflect.DeepEqual(postings, [][]uint64{{1}}) {
+		t.Errorf("Unexpected postings: %v, %v, %v", terms, postings, err)
+		goto cleanup
+	}
+
+	if storages, err = index.Storages(); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	progress, err = index.CompactWithOptions(CompactOptions{
+		Progress: func(progress CompactProgress) {
+			reports = append(reports, progress)
+		},
+	})
+
+	if err != nil || progress.Total != len(storages) || progress.Compacted != len(storages) ||
+		len(reports) != len(storages) || progress.Pruned == 0 {
+		t.Errorf("Unexpected compaction of the index: %+v, %v, %v", progress, reports, err)
+		goto cleanup
+	}
+
+	if doc, err := index.Get(1); err != nil || string(doc) != `{"state": "SP", "employees": 10}` {
+		t.Errorf("Unexpected document after compaction: %s, %v", doc, err)
+		goto cleanup
+	}
+
+	close(cancel)
+
+	if progress, err = index.CompactWithOptions(CompactOptions{Cancel: cancel}); err != ErrCompactCanceled || progress.Compacted != 0 {
+		t.Errorf("Compaction should be canceled: %+v, %v", progress, err)
+	}
+
+	if _, err = index.Compact("unmapped"); err == nil {
+		t.Error("Compaction of fields without storages should fail")
+	}
+
+cleanup:
+	index.Close()
+	os.RemoveAll(indexDir)
+}
@@ -0,0 +1,95 @@
+package index
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestIndexCopyToAndAll(t *testing.T) {
+	var (
+		indexName = "document-sample-copyto"
+		indexDir  = DataDirTmp + "/" + indexName
+		err       error
+		index     *Index
+		docIDs    []uint64
+	)
+
+	index, err = createIndex(indexName, t)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = index.SetSettings(Settings{All: true})
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	err = index.SetMapping(Metadata{
+		"first_name": Metadata{
+			"type":    "string",
+			"copy_to": "full_name",
+		},
+		"last_name": Metadata{
+			"type":    "string",
+			"copy_to": []interface{}{"full_name"},
+		},
+		"secret": Metadata{
+			"type":           "string",
+			"include_in_all": false,
+		},
+	})
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	for id, doc := range []string{
+		`{"first_name": "John", "last_name": "Smith", "secret": "hidden"}`,
+		`{"first_name": "Smith", "last_name": "Doe", "address": {"city": "Paris"}, "tags": ["red"]}`,
+	} {
+		if err = index.Add(uint64(id), []byte(doc), nil); err != nil {
+			t.Error(err)
+			goto cleanup
+		}
+	}
+
+	for _, tc := range []struct {
+		field, value string
+		expected     []uint64
+	}{
+		{"full_name", "smith", []uint64{0, 1}},
+		{"full_name", "john", []uint64{0}},
+		{AllField, "Smith", []uint64{0, 1}},
+		{AllField, "paris", []uint64{1}},
+		{AllField, "red", []uint64{1}},
+		{AllField, "hidden", []uint64{}},
+	} {
+		docIDs, _, err = index.FilterTermID([]byte(tc.field), []byte(tc.value), 0)
+
+		if err != nil || !reflect.DeepEqual(docIDs, tc.expected) {
+			t.Errorf("Unexpected result for %s:%s: %v, %v", tc.field, tc.value, docIDs, err)
+			goto cleanup
+		}
+	}
+
+	err = index.SetMapping(Metadata{
+		"name": Metadata{
+			"type":    "string",
+			"copy_to": "name",
+		},
+	})
+
+	if err == nil {
+		t.Error("Field copied to itself should fail")
+	}
+
+cleanup:
+	index.Close()
+	os.RemoveAll(indexDir)
+}
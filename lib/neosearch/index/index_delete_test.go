@@ -0,0 +1,80 @@
+package index
+
+import (
+	"os"
+	"testing"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+)
+
+func TestIndexDeleteAfterMappingChange(t *testing.T) {
+	var (
+		indexName = "document-sample-delete-mapping"
+		indexDir  = DataDirTmp + "/" + indexName
+		result    *engine.Result
+		storages  []string
+		err       error
+		index     *Index
+	)
+
+	index, err = createIndex(indexName, t)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// name and city aren't mapped, name is indexed in name_string.idx
+	if err = index.Add(1, []byte(`{"name": "Neoway", "city": "Florianopolis"}`), nil); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if err = index.SetMapping(Metadata{"name": Metadata{"type": "keyword"}}); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if err = index.SetSettings(Settings{Dynamic: true}); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if err = index.Delete(1); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	result, err = index.engine.Execute(engine.Command{
+		Index:    indexName,
+		Database: "name_string.idx",
+		Command:  "get",
+		Key:      []byte("neoway"),
+		KeyType:  engine.TypeString,
+	})
+
+	if err != nil || len(result.Data) != 0 {
+		t.Errorf("Postings of the unmapped field should be deleted: %v, %v", result, err)
+		goto cleanup
+	}
+
+	if city := index.fieldMapping("city"); city != nil {
+		t.Errorf("Delete shouldn't change the mapping: %v", city)
+		goto cleanup
+	}
+
+	if storages, err = index.Storages(); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	for _, storage := range storages {
+		if storage == "name_keyword.idx" {
+			t.Errorf("Delete shouldn't create storages: %v", storages)
+		}
+	}
+
+cleanup:
+	index.Close()
+	os.RemoveAll(indexDir)
+}
@@ -0,0 +1,126 @@
+package index
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
+)
+
+func TestIndexDocValues(t *testing.T) {
+	var (
+		indexName = "document-sample-docvalues"
+		indexDir  = DataDirTmp + "/" + indexName
+		commands  []engine.Command
+		docJSON   = []byte(`{"revenue": 10.5, "founded": "2010-01-02", "code": "AB-1", "name": "Neoway"}`)
+		values    []interface{}
+		founded   time.Time
+		err       error
+		index     *Index
+	)
+
+	index, err = createIndex(indexName, t)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = index.SetMapping(Metadata{
+		"revenue": Metadata{"type": "float", "doc_values": true},
+		"founded": Metadata{"type": "date", "format": DateISO8601, "doc_values": true},
+		"code":    Metadata{"type": "keyword", "normalizer": "lowercase", "doc_values": true},
+		"name":    Metadata{"type": "string"},
+	})
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	commands, _, err = index.BuildAdd(1, docJSON, nil)
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	founded, _ = time.Parse("2006-01-02", "2010-01-02")
+
+	if !compareCommands(t, []engine.Command{commands[2], commands[4], commands[7]}, []engine.Command{
+		{
+			Index:     indexName,
+			Database:  "code_keyword.dv",
+			Command:   "set",
+			Key:       utils.Uint64ToBytes(1),
+			KeyType:   engine.TypeUint,
+			Value:     []byte("ab-1"),
+			ValueType: engine.TypeString,
+		},
+		{
+			Index:     indexName,
+			Database:  "founded_int.dv",
+			Command:   "set",
+			Key:       utils.Uint64ToBytes(1),
+			KeyType:   engine.TypeUint,
+			Value:     utils.Int64ToBytes(founded.UnixNano()),
+			ValueType: engine.TypeInt,
+		},
+		{
+			Index:     indexName,
+			Database:  "revenue_float.dv",
+			Command:   "set",
+			Key:       utils.Uint64ToBytes(1),
+			KeyType:   engine.TypeUint,
+			Value:     utils.Float64ToBytes(10.5),
+			ValueType: engine.TypeFloat,
+		},
+	}) {
+		goto cleanup
+	}
+
+	for id, doc := range []string{
+		`{"revenue": 10.5, "code": "AB-1"}`,
+		`{"revenue": 3, "code": "CD-2"}`,
+		`{"code": "EF-3"}`,
+	} {
+		if err = index.Add(uint64(id), []byte(doc), nil); err != nil {
+			t.Error(err)
+			goto cleanup
+		}
+	}
+
+	values, err = index.DocValues("revenue", []uint64{1, 0, 2})
+
+	if err != nil || !reflect.DeepEqual(values, []interface{}{float64(3), 10.5, nil}) {
+		t.Errorf("Unexpected doc values: %v, %v", values, err)
+		goto cleanup
+	}
+
+	values, err = index.DocValues("code", []uint64{2})
+
+	if err != nil || !reflect.DeepEqual(values, []interface{}{"ef-3"}) {
+		t.Errorf("Unexpected doc values: %v, %v", values, err)
+		goto cleanup
+	}
+
+	if _, err = index.DocValues("name", []uint64{1}); err == nil {
+		t.Error("Fields without doc values should fail")
+		goto cleanup
+	}
+
+	err = index.SetMapping(Metadata{
+		"tags": Metadata{"type": "slice", "doc_values": true},
+	})
+
+	if err == nil {
+		t.Error("Doc values of slices should fail")
+	}
+
+cleanup:
+	index.Close()
+	os.RemoveAll(indexDir)
+}
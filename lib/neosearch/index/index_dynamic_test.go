@@ -0,0 +1,189 @@
+package index
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
+)
+
+func TestIndexDynamicMapping(t *testing.T) {
+	var (
+		indexName                  = "document-sample-dynamic"
+		indexDir                   = DataDirTmp + "/" + indexName
+		commands, expectedCommands []engine.Command
+		docJSON                    = []byte(`{"id": 1, "active": true, "created": "2015-06-01", "name": "neoway", "sku": "AB-123", "tags": ["a"], "extra": null}`)
+		err                        error
+		index                      *Index
+		mapping                    Metadata
+		date                       time.Time
+	)
+
+	index, err = createIndex(indexName, t)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = index.SetSettings(Settings{Dynamic: true, Conflict: ConflictReject})
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	commands, mapping, err = index.BuildAdd(1, docJSON, nil)
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	date, _ = time.Parse("2006-01-02", "2015-06-01")
+
+	expectedCommands = []engine.Command{
+		{
+			Index:     indexName,
+			Database:  "document.db",
+			Key:       utils.Uint64ToBytes(1),
+			KeyType:   engine.TypeUint,
+			Value:     docJSON,
+			ValueType: engine.TypeString,
+			Command:   "set",
+		},
+		{
+			Index:     indexName,
+			Database:  "active_bool.idx",
+			Key:       utils.BoolToBytes(true),
+			KeyType:   engine.TypeBool,
+			Value:     utils.Uint64ToBytes(1),
+			ValueType: engine.TypeUint,
+			Command:   "mergeset",
+		},
+		{
+			Index:     indexName,
+			Database:  "created_int.idx",
+			Key:       utils.Int64ToBytes(date.UnixNano()),
+			KeyType:   engine.TypeInt,
+			Value:     utils.Uint64ToBytes(1),
+			ValueType: engine.TypeUint,
+			Command:   "mergeset",
+		},
+		{
+			Index:     indexName,
+			Database:  "id_int.idx",
+			Key:       utils.Int64ToBytes(1),
+			KeyType:   engine.TypeInt,
+			Value:     utils.Uint64ToBytes(1),
+			ValueType: engine.TypeUint,
+			Command:   "mergeset",
+		},
+		{
+			Index:     indexName,
+			Database:  "name_string.idx",
+			Key:       []byte("neoway"),
+			KeyType:   engine.TypeString,
+			Value:     utils.Uint64ToBytes(1),
+			ValueType: engine.TypeUint,
+			Command:   "mergeset",
+		},
+		{
+			Index:     indexName,
+			Database:  "sku_string.idx",
+			Key:       []byte("ab-123"),
+			KeyType:   engine.TypeString,
+			Value:     utils.Uint64ToBytes(1),
+			ValueType: engine.TypeUint,
+			Command:   "mergeset",
+		},
+		{
+			Index:     indexName,
+			Database:  "tags_string.idx",
+			Key:       []byte("a"),
+			KeyType:   engine.TypeString,
+			Value:     utils.Uint64ToBytes(1),
+			ValueType: engine.TypeUint,
+			Command:   "mergeset",
+		},
+	}
+
+	if !compareCommands(t, commands, expectedCommands) {
+		goto cleanup
+	}
+
+	if len(index.Mapping()) != 0 {
+		t.Errorf("BuildAdd shouldn't persist the detected fields: %+v", index.Mapping())
+		goto cleanup
+	}
+
+	if meta, ok := mapping["created"].(Metadata); !ok || meta["type"] != "date" || meta["format"] != DateISO8601 {
+		t.Errorf("Date not detected: %+v", mapping)
+		goto cleanup
+	}
+
+	if meta, ok := mapping["sku"].(Metadata); !ok || meta["type"] != "string" {
+		t.Errorf("Strings with digits should be detected as string: %+v", mapping)
+		goto cleanup
+	}
+
+	if meta, ok := mapping["tags"].(Metadata); !ok || meta["type"] != "slice" {
+		t.Errorf("Slice not detected: %+v", mapping)
+		goto cleanup
+	}
+
+	if _, ok := mapping["extra"]; ok {
+		t.Errorf("Null values must not be mapped: %+v", mapping)
+		goto cleanup
+	}
+
+	if err = index.Add(1, docJSON, nil); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if meta, ok := index.Mapping()["id"].(Metadata); !ok || meta["type"] != "int" {
+		t.Errorf("Detected fields not persisted by Add: %+v", index.Mapping())
+		goto cleanup
+	}
+
+	// id is mapped as int now
+	_, _, err = index.BuildAdd(2, []byte(`{"id": "2"}`), nil)
+
+	if err == nil {
+		t.Error("Conflicting value of field 'id' should be rejected")
+		goto cleanup
+	}
+
+	err = index.SetSettings(Settings{Dynamic: true, Conflict: ConflictCoerce})
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	commands, _, err = index.BuildAdd(2, []byte(`{"id": "2"}`), nil)
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if len(commands) != 2 || commands[1].Database != "id_int.idx" ||
+		utils.BytesToInt64(commands[1].Key) != 2 {
+		t.Errorf("Value of field 'id' not coerced: %+v", commands)
+		goto cleanup
+	}
+
+	err = index.SetSettings(Settings{Conflict: "ignore"})
+
+	if err == nil {
+		t.Error("Invalid conflict policy should fail")
+	}
+
+cleanup:
+	index.Close()
+	os.RemoveAll(indexDir)
+}
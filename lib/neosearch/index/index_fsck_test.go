@@ -0,0 +1,219 @@
+package index
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
+)
+
+func TestIndexFsck(t *testing.T) {
+	var (
+		indexName   = "document-sample-fsck"
+		indexDir    = DataDirTmp + "/" + indexName
+		stateDB     string
+		employeesDB string
+		problems    []string
+		report      *FsckReport
+		terms       []interface{}
+		postings    [][]uint64
+		err         error
+		index       *Index
+	)
+
+	index, err = createIndex(indexName, t)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = index.SetMapping(Metadata{
+		"state":     Metadata{"type": "keyword"},
+		"employees": Metadata{"type": "int"},
+	})
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	for id, doc := range []string{
+		`{"state": "SC", "employees": 100}`,
+		`{"state": "SP", "employees": 10}`,
+		`{"state": "SC", "employees": 10}`,
+	} {
+		if err = index.Add(uint64(id), []byte(doc), nil); err != nil {
+			t.Error(err)
+			goto cleanup
+		}
+	}
+
+	if report, err = index.Fsck(false); err != nil || len(report.Problems) != 0 || report.Documents != 3 {
+		t.Errorf("Unexpected report of a consistent index: %+v, %v", report, err)
+		goto cleanup
+	}
+
+	stateDB, _, _ = index.invertedStorage("state")
+	employeesDB, _, _ = index.invertedStorage("employees")
+
+	// unsorted with a dangling id, dangling term, missing and corrupt
+	// posting lists
+	err = setPostings(index, stateDB, []byte("SC"), []byte{}, 2, 0, 7)
+
+	if err == nil {
+		err = setPostings(index, stateDB, []byte("XX"), []byte{}, 9)
+	}
+
+	if err == nil {
+		err = setPostings(index, employeesDB, utils.Int64ToBytes(10), nil)
+	}
+
+	if err == nil {
+		err = setPostings(index, employeesDB, utils.Int64ToBytes(100), []byte{0, 0, 0, 0, 1})
+	}
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if report, err = index.Fsck(false); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	for _, problem := range report.Problems {
+		problems = append(problems, fmt.Sprintf("%s %s %v %v", problem.Kind, problem.Storage, problem.Key, problem.IDs))
+	}
+
+	if !reflect.DeepEqual(problems, []string{
+		"corrupt " + employeesDB + " 100 []",
+		"missing " + employeesDB + " 10 [1 2]",
+		"unsorted " + stateDB + " SC []",
+		"dangling " + stateDB + " SC [7]",
+		"dangling " + stateDB + " XX [9]",
+	}) {
+		t.Errorf("Unexpected problems: %q", problems)
+		goto cleanup
+	}
+
+	if report, err = index.Fsck(true); err != nil || !report.Repaired || len(report.Problems) != 5 {
+		t.Errorf("Repair failed: %+v, %v", report, err)
+		goto cleanup
+	}
+
+	if report, err = index.Fsck(false); err != nil || len(report.Problems) != 0 {
+		t.Errorf("Unexpected report of a repaired index: %+v, %v", report, err)
+		goto cleanup
+	}
+
+	err = index.WalkPostings("state", func(term interface{}, docIDs []uint64) error {
+		terms = append(terms, term)
+		postings = append(postings, docIDs)
+		return nil
+	})
+
+	if err != nil || !reflect.DeepEqual(terms, []interface{}{"SC", "SP"}) ||
+		!reflect.DeepEqual(postings, [][]uint64{{0, 2}, {1}}) {
+		t.Errorf("Unexpected postings: %v, %v, %v", terms, postings, err)
+		goto cleanup
+	}
+
+	terms, postings = nil, nil
+
+	err = index.WalkPostings("employees", func(term interface{}, docIDs []uint64) error {
+		terms = append(terms, term)
+		postings = append(postings, docIDs)
+		return nil
+	})
+
+	if err != nil || !reflect.DeepEqual(terms, []interface{}{int64(10), int64(100)}) ||
+		!reflect.DeepEqual(postings, [][]uint64{{1, 2}, {0}}) {
+		t.Errorf("Unexpected postings: %v, %v, %v", terms, postings, err)
+	}
+
+cleanup:
+	index.Close()
+	os.RemoveAll(indexDir)
+}
+
+func TestIndexFsckDynamicMapping(t *testing.T) {
+	var (
+		indexName = "document-sample-fsck-dynamic"
+		indexDir  = DataDirTmp + "/" + indexName
+		storages  []string
+		report    *FsckReport
+		err       error
+		index     *Index
+	)
+
+	index, err = createIndex(indexName, t)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// year isn't mapped, it's indexed as float
+	if err = index.Add(1, []byte(`{"year": 2015}`), nil); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if err = index.SetSettings(Settings{Dynamic: true}); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if report, err = index.Fsck(false); err != nil || len(report.Problems) != 1 ||
+		report.Problems[0].Kind != FsckMissing || report.Problems[0].Storage != "year_int.idx" {
+		t.Errorf("Unexpected report: %+v, %v", report, err)
+		goto cleanup
+	}
+
+	if storages, err = index.Storages(); err != nil || !reflect.DeepEqual(storages, []string{"document.db", countStorage, "year_float.idx"}) {
+		t.Errorf("Check shouldn't create storages: %v, %v", storages, err)
+		goto cleanup
+	}
+
+	if year := index.fieldMapping("year"); year != nil {
+		t.Errorf("Check shouldn't change the mapping: %v", year)
+		goto cleanup
+	}
+
+	if report, err = index.Fsck(true); err != nil || len(report.Problems) != 1 {
+		t.Errorf("Unexpected report of the repair: %+v, %v", report, err)
+		goto cleanup
+	}
+
+	if year := index.FieldType("year"); year != "int" {
+		t.Errorf("Repair should persist the detected mapping: %v", year)
+	}
+
+cleanup:
+	index.Close()
+	os.RemoveAll(indexDir)
+}
+
+// setPostings overwrites the posting list of key with value followed by
+// ids, or deletes it when value is nil
+func setPostings(index *Index, storage string, key, value []byte, ids ...uint64) error {
+	storekv, err := index.engine.GetStore(index.Name, storage)
+
+	if err != nil {
+		return err
+	}
+
+	if value == nil {
+		return storekv.Writer().Delete(key)
+	}
+
+	for _, id := range ids {
+		value = append(value, utils.Uint64ToBytes(id)...)
+	}
+
+	return storekv.Writer().Set(key, value)
+}
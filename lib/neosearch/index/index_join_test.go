@@ -0,0 +1,145 @@
+package index
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestIndexJoin(t *testing.T) {
+	var (
+		companies, partners *Index
+		related             map[uint64][]uint64
+		docIDs              []uint64
+		err                 error
+	)
+
+	opener := func(name string) (*Index, error) {
+		switch name {
+		case "join-companies":
+			return companies, nil
+		case "join-partners":
+			return partners, nil
+		}
+
+		return nil, fmt.Errorf("Index '%s' not found", name)
+	}
+
+	if companies, err = createIndex("join-companies", t); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if partners, err = createIndex("join-partners", t); err != nil {
+		t.Error(err)
+		companies.Close()
+		os.RemoveAll(DataDirTmp + "/join-companies")
+		return
+	}
+
+	for _, ind := range []*Index{companies, partners} {
+		ind.SetIndexOpener(opener)
+
+		err = ind.SetMapping(Metadata{
+			"id":         Metadata{"type": "uint"},
+			"company_id": Metadata{"type": "uint"},
+			"name":       Metadata{"type": "string"},
+		})
+
+		if err != nil {
+			t.Error(err)
+			goto cleanup
+		}
+	}
+
+	for id, doc := range []string{
+		`{"id": 1, "name": "Neoway"}`,
+		`{"id": 2, "name": "Google"}`,
+	} {
+		if err = companies.Add(uint64(id), []byte(doc), nil); err != nil {
+			t.Error(err)
+			goto cleanup
+		}
+	}
+
+	if err = partners.Add(0, []byte(`{"company_id": 1, "name": "Alice"}`), nil); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if err = CreateJoin("partners", companies, "id", partners, "company_id"); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if err = CreateJoin("partners", companies, "id", partners, "company_id"); err == nil {
+		t.Error("Duplicated joins should fail")
+		goto cleanup
+	}
+
+	if _, ok := partners.FindJoin("company_id", "join-companies", "id"); !ok {
+		t.Errorf("Join not found in index 'join-partners': %v", partners.Joins())
+		goto cleanup
+	}
+
+	// documents added after the join are related on both sides
+	for id, doc := range []string{
+		`{"company_id": 1, "name": "Bob"}`,
+		`{"company_id": 2, "name": "Carol"}`,
+	} {
+		if err = partners.Add(uint64(id+1), []byte(doc), nil); err != nil {
+			t.Error(err)
+			goto cleanup
+		}
+	}
+
+	related, err = companies.JoinedIDs("partners", []uint64{0, 1})
+
+	if err != nil || !reflect.DeepEqual(related, map[uint64][]uint64{0: {0, 1}, 1: {2}}) {
+		t.Errorf("Unexpected joined ids: %v, %v", related, err)
+		goto cleanup
+	}
+
+	// updates move the document to its new relations
+	if err = partners.Add(1, []byte(`{"company_id": 2, "name": "Bob"}`), nil); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	related, err = companies.JoinedIDs("partners", []uint64{0, 1})
+
+	if err != nil || !reflect.DeepEqual(related, map[uint64][]uint64{0: {0}, 1: {1, 2}}) {
+		t.Errorf("Unexpected joined ids after update: %v, %v", related, err)
+		goto cleanup
+	}
+
+	if err = companies.Delete(1); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	related, err = partners.JoinedIDs("partners", []uint64{0, 1, 2})
+
+	if err != nil || !reflect.DeepEqual(related, map[uint64][]uint64{0: {0}}) {
+		t.Errorf("Unexpected joined ids after delete: %v, %v", related, err)
+		goto cleanup
+	}
+
+	docIDs, err = companies.TermDocIDs("id", uint64(2))
+
+	if err != nil || len(docIDs) != 0 {
+		t.Errorf("Deleted document found in postings: %v, %v", docIDs, err)
+		goto cleanup
+	}
+
+	if err = companies.Delete(1); err == nil {
+		t.Error("Deleting a missing document should fail")
+	}
+
+cleanup:
+	companies.Close()
+	partners.Close()
+	os.RemoveAll(DataDirTmp + "/join-companies")
+	os.RemoveAll(DataDirTmp + "/join-partners")
+}
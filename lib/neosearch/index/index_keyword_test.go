@@ -0,0 +1,143 @@
+package index
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
+)
+
+func TestIndexKeyword(t *testing.T) {
+	var (
+		indexName                  = "document-sample-keyword"
+		indexDir                   = DataDirTmp + "/" + indexName
+		commands, expectedCommands []engine.Command
+		docJSON                    = []byte(`{"sku": "AB-123 x", "city": " São Paulo "}`)
+		err                        error
+		index                      *Index
+		docIDs                     []uint64
+		docs                       []string
+	)
+
+	index, err = createIndex(indexName, t)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = index.SetMapping(Metadata{
+		"sku": Metadata{
+			"type": "keyword",
+		},
+		"city": Metadata{
+			"type":       "keyword",
+			"normalizer": []interface{}{"trim", "lowercase", "asciifold"},
+		},
+	})
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	commands, _, err = index.BuildAdd(1, docJSON, nil)
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	expectedCommands = []engine.Command{
+		{
+			Index:     indexName,
+			Database:  "document.db",
+			Key:       utils.Uint64ToBytes(1),
+			KeyType:   engine.TypeUint,
+			Value:     docJSON,
+			ValueType: engine.TypeString,
+			Command:   "set",
+		},
+		{
+			Index:     indexName,
+			Database:  "city_keyword.idx",
+			Key:       []byte("sao paulo"),
+			KeyType:   engine.TypeString,
+			Value:     utils.Uint64ToBytes(1),
+			ValueType: engine.TypeUint,
+			Command:   "mergeset",
+		},
+		{
+			Index:     indexName,
+			Database:  "sku_keyword.idx",
+			Key:       []byte("AB-123 x"),
+			KeyType:   engine.TypeString,
+			Value:     utils.Uint64ToBytes(1),
+			ValueType: engine.TypeUint,
+			Command:   "mergeset",
+		},
+	}
+
+	if !compareCommands(t, commands, expectedCommands) {
+		goto cleanup
+	}
+
+	if err = index.Add(1, docJSON, nil); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	docIDs, _, err = index.FilterTermID([]byte("sku"), []byte("AB-123 x"), 0)
+
+	if err != nil || !reflect.DeepEqual(docIDs, []uint64{1}) {
+		t.Errorf("Keyword term not found: %v, %v", docIDs, err)
+		goto cleanup
+	}
+
+	// keywords are case-sensitive without the lowercase normalizer
+	docIDs, _, err = index.FilterTermID([]byte("sku"), []byte("ab-123 x"), 0)
+
+	if err != nil || len(docIDs) != 0 {
+		t.Errorf("Keyword must match exactly: %v, %v", docIDs, err)
+		goto cleanup
+	}
+
+	// normalizers are applied to the query value
+	docIDs, _, err = index.FilterTermID([]byte("city"), []byte("SÃO PAULO"), 0)
+
+	if err != nil || !reflect.DeepEqual(docIDs, []uint64{1}) {
+		t.Errorf("Normalized keyword term not found: %v, %v", docIDs, err)
+		goto cleanup
+	}
+
+	docs, err = index.MatchPrefix([]byte("sku"), []byte("AB-1"))
+
+	if err != nil || len(docs) != 1 {
+		t.Errorf("Keyword prefix not found: %v, %v", docs, err)
+		goto cleanup
+	}
+
+	docs, err = index.MatchPrefix([]byte("sku"), []byte("ab"))
+
+	if err != nil || len(docs) != 0 {
+		t.Errorf("Keyword prefix must match exactly: %v, %v", docs, err)
+		goto cleanup
+	}
+
+	err = index.SetMapping(Metadata{
+		"code": Metadata{
+			"type":       "keyword",
+			"normalizer": "uppercase",
+		},
+	})
+
+	if err == nil {
+		t.Error("Unknown normalizer should fail")
+	}
+
+cleanup:
+	index.Close()
+	os.RemoveAll(indexDir)
+}
@@ -0,0 +1,163 @@
+package index
+
+import (
+	"os"
+	"testing"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/config"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
+)
+
+func TestIndexMapping(t *testing.T) {
+	var (
+		indexName                  = "document-sample-mapping"
+		indexDir                   = DataDirTmp + "/" + indexName
+		commands, expectedCommands []engine.Command
+		docJSON                    = []byte(`{"id": 1, "name": "neoway"}`)
+		err                        error
+		index                      *Index
+		mapping, newFields         Metadata
+		cfg                        = config.NewConfig()
+	)
+
+	cfg.Option(config.DataDir(DataDirTmp))
+
+	index, err = createIndex(indexName, t)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = index.SetMapping(Metadata{
+		"id": map[string]interface{}{
+			"type": "uint",
+		},
+	})
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	// reopen the index to verify if mapping was persisted
+	index.Close()
+
+	index, err = New(indexName, cfg, false)
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	mapping = index.Mapping()
+
+	if idMeta, ok := mapping["id"].(Metadata); !ok || idMeta["type"] != "uint" {
+		t.Errorf("Mapping not persisted: %+v", mapping)
+		goto cleanup
+	}
+
+	// metadata of field name should be merged with the index mapping
+	commands, newFields, err = index.BuildAdd(1, docJSON, Metadata{
+		"name": Metadata{
+			"type": "string",
+		},
+	})
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	expectedCommands = []engine.Command{
+		{
+			Index:     indexName,
+			Database:  "document.db",
+			Key:       utils.Uint64ToBytes(1),
+			KeyType:   engine.TypeUint,
+			Value:     docJSON,
+			ValueType: engine.TypeString,
+			Command:   "set",
+		},
+		{
+			Index:     indexName,
+			Database:  "id_uint.idx",
+			Key:       utils.Uint64ToBytes(1),
+			KeyType:   engine.TypeUint,
+			Value:     utils.Uint64ToBytes(1),
+			ValueType: engine.TypeUint,
+			Command:   "mergeset",
+		},
+		{
+			Index:     indexName,
+			Database:  "name_string.idx",
+			Key:       []byte("neoway"),
+			KeyType:   engine.TypeString,
+			Value:     utils.Uint64ToBytes(1),
+			ValueType: engine.TypeUint,
+			Command:   "mergeset",
+		},
+	}
+
+	if !compareCommands(t, commands, expectedCommands) {
+		goto cleanup
+	}
+
+	if _, ok := newFields["name"]; !ok {
+		t.Errorf("New field not returned: %+v", newFields)
+		goto cleanup
+	}
+
+	if _, ok := index.Mapping()["name"]; ok {
+		t.Errorf("BuildAdd shouldn't persist new fields: %+v", index.Mapping())
+		goto cleanup
+	}
+
+	if err = index.Add(1, docJSON, newFields); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if _, ok := index.Mapping()["name"]; !ok {
+		t.Errorf("New field not added to mapping: %+v", index.Mapping())
+		goto cleanup
+	}
+
+	// type changes must be rejected
+	_, _, err = index.BuildAdd(2, docJSON, Metadata{
+		"id": Metadata{
+			"type": "string",
+		},
+	})
+
+	if err == nil {
+		t.Error("Conflicting type of field 'id' should fail")
+		goto cleanup
+	}
+
+	err = index.SetMapping(Metadata{
+		"name": Metadata{
+			"type": "int",
+		},
+	})
+
+	if err == nil {
+		t.Error("Conflicting type of field 'name' should fail")
+		goto cleanup
+	}
+
+	err = index.SetMapping(Metadata{
+		"title": Metadata{
+			"type": "unknown",
+		},
+	})
+
+	if err == nil {
+		t.Error("Unknown type should fail")
+	}
+
+cleanup:
+	index.Close()
+	os.RemoveAll(indexDir)
+}
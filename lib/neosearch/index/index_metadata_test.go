@@ -46,7 +46,7 @@ func TestSimpleIndexWithMetadata(t *testing.T) {
 		goto cleanup
 	}
 
-	commands, err = index.BuildAdd(1, docJSON, metadata)
+	commands, _, err = index.BuildAdd(1, docJSON, metadata)
 
 	if err != nil {
 		t.Error(err.Error())
@@ -194,7 +194,7 @@ func TestSimpleIndexWithMetadata(t *testing.T) {
 		},
 	}
 
-	commands, err = index.BuildAdd(2, docJSON, metadata)
+	commands, _, err = index.BuildAdd(2, docJSON, metadata)
 
 	if err != nil {
 		t.Error(err)
@@ -253,7 +253,7 @@ func TestDateIndex(t *testing.T) {
 		goto cleanup
 	}
 
-	commands, err = index.BuildAdd(1, docJSON, metadata)
+	commands, _, err = index.BuildAdd(1, docJSON, metadata)
 
 	if err != nil {
 		t.Error(err.Error())
@@ -0,0 +1,151 @@
+package index
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+)
+
+func TestIndexMultiFields(t *testing.T) {
+	var (
+		indexName = "document-sample-multifield"
+		indexDir  = DataDirTmp + "/" + indexName
+		commands  []engine.Command
+		docJSON   = []byte(`{"name": "Neoway Inc"}`)
+		err       error
+		index     *Index
+		docIDs    []uint64
+		terms     []string
+	)
+
+	index, err = createIndex(indexName, t)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = index.SetMapping(Metadata{
+		"name": Metadata{
+			"type": "string",
+			"fields": Metadata{
+				"raw": Metadata{
+					"type": "keyword",
+				},
+				"ngram": Metadata{
+					"type":     "string",
+					"analyzer": "ngram",
+					"min_gram": 3,
+					"max_gram": 3,
+				},
+			},
+		},
+	})
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	commands, _, err = index.BuildAdd(1, docJSON, nil)
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	for _, cmd := range commands[1:] {
+		terms = append(terms, cmd.Database+":"+string(cmd.Key))
+	}
+
+	if !reflect.DeepEqual(terms, []string{
+		"name_string.idx:neoway",
+		"name_string.idx:inc",
+		"name_string.idx:neoway inc",
+		"name.ngram_string.idx:neo",
+		"name.ngram_string.idx:eow",
+		"name.ngram_string.idx:owa",
+		"name.ngram_string.idx:way",
+		"name.ngram_string.idx:inc",
+		"name.raw_keyword.idx:Neoway Inc",
+	}) {
+		t.Errorf("Unexpected sub-field terms: %v", terms)
+		goto cleanup
+	}
+
+	if err = index.Add(1, docJSON, nil); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	for field, value := range map[string]string{
+		"name":       "neoway",
+		"name.raw":   "Neoway Inc",
+		"name.ngram": "owa",
+	} {
+		docIDs, _, err = index.FilterTermID([]byte(field), []byte(value), 0)
+
+		if err != nil || !reflect.DeepEqual(docIDs, []uint64{1}) {
+			t.Errorf("Term '%s' of field '%s' not found: %v, %v", value, field, docIDs, err)
+			goto cleanup
+		}
+	}
+
+	// sub-fields are merged with the existing ones
+	err = index.SetMapping(Metadata{
+		"name": Metadata{
+			"type": "string",
+			"fields": Metadata{
+				"lower": Metadata{
+					"type":       "keyword",
+					"normalizer": "lowercase",
+				},
+			},
+		},
+	})
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if len(index.fieldMapping("name")["fields"].(Metadata)) != 3 {
+		t.Errorf("Sub-fields not merged: %+v", index.Mapping())
+		goto cleanup
+	}
+
+	err = index.SetMapping(Metadata{
+		"tags": Metadata{
+			"type": "slice",
+			"fields": Metadata{
+				"raw": Metadata{
+					"type": "keyword",
+				},
+			},
+		},
+	})
+
+	if err == nil {
+		t.Error("Sub-fields of slices should fail")
+		goto cleanup
+	}
+
+	err = index.SetMapping(Metadata{
+		"title": Metadata{
+			"type":     "string",
+			"analyzer": "ngram",
+			"min_gram": 3,
+			"max_gram": 2,
+		},
+	})
+
+	if err == nil {
+		t.Error("Invalid ngram range should fail")
+	}
+
+cleanup:
+	index.Close()
+	os.RemoveAll(indexDir)
+}
@@ -0,0 +1,151 @@
+package index
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestIndexWalkPostings(t *testing.T) {
+	var (
+		indexName = "document-sample-postings"
+		indexDir  = DataDirTmp + "/" + indexName
+		terms     []interface{}
+		postings  [][]uint64
+		err       error
+		index     *Index
+	)
+
+	index, err = createIndex(indexName, t)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = index.SetMapping(Metadata{
+		"state":     Metadata{"type": "keyword"},
+		"employees": Metadata{"type": "int"},
+	})
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	for id, doc := range []string{
+		`{"state": "SC", "employees": 100}`,
+		`{"state": "SP", "employees": 10}`,
+		`{"state": "SC", "employees": 10}`,
+	} {
+		if err = index.Add(uint64(id), []byte(doc), nil); err != nil {
+			t.Error(err)
+			goto cleanup
+		}
+	}
+
+	err = index.WalkPostings("state", func(term interface{}, docIDs []uint64) error {
+		terms = append(terms, term)
+		postings = append(postings, docIDs)
+		return nil
+	})
+
+	if err != nil || !reflect.DeepEqual(terms, []interface{}{"SC", "SP"}) ||
+		!reflect.DeepEqual(postings, [][]uint64{{0, 2}, {1}}) {
+		t.Errorf("Unexpected postings: %v, %v, %v", terms, postings, err)
+		goto cleanup
+	}
+
+	terms, postings = nil, nil
+
+	err = index.WalkPostings("employees", func(term interface{}, docIDs []uint64) error {
+		terms = append(terms, term)
+		postings = append(postings, docIDs)
+		return nil
+	})
+
+	if err != nil || !reflect.DeepEqual(terms, []interface{}{int64(10), int64(100)}) ||
+		!reflect.DeepEqual(postings, [][]uint64{{1, 2}, {0}}) {
+		t.Errorf("Unexpected postings: %v, %v, %v", terms, postings, err)
+		goto cleanup
+	}
+
+	if err = index.WalkPostings("unmapped", nil); err == nil {
+		t.Error("Walking unmapped fields should fail")
+	}
+
+cleanup:
+	index.Close()
+	os.RemoveAll(indexDir)
+}
+
+func TestIndexFilterTypedTerms(t *testing.T) {
+	var (
+		indexName = "document-sample-typed-terms"
+		indexDir  = DataDirTmp + "/" + indexName
+		docIDs    []uint64
+		err       error
+		index     *Index
+	)
+
+	index, err = createIndex(indexName, t)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = index.SetMapping(Metadata{
+		"company_id": Metadata{"type": "uint"},
+		"active":     Metadata{"type": "bool"},
+		"founded":    Metadata{"type": "date", "format": DateISO8601},
+	})
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	for id, doc := range []string{
+		`{"company_id": 10, "active": true, "founded": "2010-01-01"}`,
+		`{"company_id": 20, "active": false, "founded": "2011-01-01"}`,
+		`{"company_id": 10, "active": false, "founded": "2010-01-01"}`,
+	} {
+		if err = index.Add(uint64(id), []byte(doc), nil); err != nil {
+			t.Error(err)
+			goto cleanup
+		}
+	}
+
+	for _, tc := range []struct {
+		field, value string
+		expected     []uint64
+	}{
+		{"company_id", "10", []uint64{0, 2}},
+		{"active", "false", []uint64{1, 2}},
+		{"founded", "2011-01-01", []uint64{1}},
+		{"company_id", "30", []uint64{}},
+	} {
+		docIDs, _, err = index.FilterTermID([]byte(tc.field), []byte(tc.value), 0)
+
+		if err != nil || !reflect.DeepEqual(docIDs, tc.expected) {
+			t.Errorf("Unexpected postings of %s:%s: %v, %v", tc.field, tc.value, docIDs, err)
+			goto cleanup
+		}
+	}
+
+	docIDs, err = index.TermDocIDs("company_id", int64(20))
+
+	if err != nil || !reflect.DeepEqual(docIDs, []uint64{1}) {
+		t.Errorf("Unexpected postings: %v, %v", docIDs, err)
+		goto cleanup
+	}
+
+	if _, _, err = index.FilterTermID([]byte("company_id"), []byte("abc"), 0); err == nil {
+		t.Error("Invalid uint terms should fail")
+	}
+
+cleanup:
+	index.Close()
+	os.RemoveAll(indexDir)
+}
@@ -0,0 +1,325 @@
+package index
+
+import (
+	"math"
+	"os"
+	"testing"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/config"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
+)
+
+func TestIndexScoring(t *testing.T) {
+	var (
+		indexName = "document-sample-scoring"
+		indexDir  = DataDirTmp + "/" + indexName
+		commands  []engine.Command
+		docIDs    []uint64
+		scores    []float64
+		err       error
+		index     *Index
+	)
+
+	index, err = createIndex(indexName, t)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = index.SetSettings(Settings{Similarity: SimilarityBM25})
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	commands, _, err = index.BuildAdd(1, []byte(`{"name": "neo neo way"}`), nil)
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if !compareCommands(t, commands[5:], []engine.Command{
+		{
+			Index:     indexName,
+			Database:  "name_string.bm25",
+			Command:   "set",
+			Key:       termFreqKey("neo", 1),
+			KeyType:   engine.TypeString,
+			Value:     utils.Uint64ToBytes(2),
+			ValueType: engine.TypeUint,
+		},
+		{
+			Index:     indexName,
+			Database:  "name_string.bm25",
+			Command:   "set",
+			Key:       termFreqKey("way", 1),
+			KeyType:   engine.TypeString,
+			Value:     utils.Uint64ToBytes(1),
+			ValueType: engine.TypeUint,
+		},
+		{
+			Index:     indexName,
+			Database:  "name_string.bm25",
+			Command:   "set",
+			Key:       termFreqKey("neo neo way", 1),
+			KeyType:   engine.TypeString,
+			Value:     utils.Uint64ToBytes(1),
+			ValueType: engine.TypeUint,
+		},
+		{
+			Index:     indexName,
+			Database:  "name_string.bm25",
+			Command:   "set",
+			Key:       normKey(1),
+			KeyType:   engine.TypeString,
+			Value:     utils.Uint64ToBytes(3),
+			ValueType: engine.TypeUint,
+		},
+		{
+			Index:     indexName,
+			Database:  "name_string.bm25",
+			Command:   "incr",
+			Key:       statDocs,
+			KeyType:   engine.TypeString,
+			Value:     utils.Uint64ToBytes(1),
+			ValueType: engine.TypeUint,
+		},
+		{
+			Index:     indexName,
+			Database:  "name_string.bm25",
+			Command:   "incr",
+			Key:       statLength,
+			KeyType:   engine.TypeString,
+			Value:     utils.Uint64ToBytes(3),
+			ValueType: engine.TypeUint,
+		},
+	}) {
+		goto cleanup
+	}
+
+	for id, doc := range []string{
+		`{"name": "neo"}`,
+		`{"name": "neo neo"}`,
+		`{"name": "neo business solution"}`,
+		`{"name": "other"}`,
+	} {
+		if err = index.Add(uint64(id), []byte(doc), nil); err != nil {
+			t.Error(err)
+			goto cleanup
+		}
+	}
+
+	docIDs, _, err = index.FilterTermID([]byte("name"), []byte("neo"), 0)
+
+	if err != nil || len(docIDs) != 3 {
+		t.Errorf("Unexpected postings: %v, %v", docIDs, err)
+		goto cleanup
+	}
+
+	scores, err = index.ScoreTerm([]byte("name"), []byte("neo"), docIDs)
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	// higher frequency ranks first, longer fields rank last
+	if !(scores[1] > scores[0] && scores[0] > scores[2] && scores[2] > 0) {
+		t.Errorf("Unexpected scores: %v", scores)
+	}
+
+cleanup:
+	index.Close()
+	os.RemoveAll(indexDir)
+}
+
+func TestIndexScoringIDF(t *testing.T) {
+	var (
+		indexName = "document-sample-scoring-idf"
+		indexDir  = DataDirTmp + "/" + indexName
+		cfg       = config.NewConfig()
+		docs      uint64
+		docIDs    []uint64
+		scores    []float64
+		err       error
+		index     *Index
+	)
+
+	index, err = createIndex(indexName, t)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	cfg.Option(config.DataDir(DataDirTmp))
+
+	// without BM25 the score is the idf of the term in the N documents
+	idf := func(n, df float64) float64 {
+		return math.Log(1 + (n-df+0.5)/(df+0.5))
+	}
+
+	for id, doc := range []string{
+		`{"name": "neo"}`,
+		`{"name": "neo way"}`,
+		`{"name": "other"}`,
+		`{"name": "another"}`,
+	} {
+		if err = index.Add(uint64(id), []byte(doc), nil); err != nil {
+			t.Error(err)
+			goto cleanup
+		}
+	}
+
+	docIDs, _, err = index.FilterTermID([]byte("name"), []byte("neo"), 0)
+
+	if err == nil {
+		scores, err = index.ScoreTerm([]byte("name"), []byte("neo"), docIDs)
+	}
+
+	if err != nil || len(scores) != 2 || math.Abs(scores[0]-idf(4, 2)) > 1e-9 || scores[0] != scores[1] {
+		t.Errorf("Unexpected scores: %v, %v", scores, err)
+		goto cleanup
+	}
+
+	// the documents are counted by Add and Delete, stored documents once
+	for id, doc := range []string{`{"name": "more"}`, `{"name": "again"}`} {
+		if err = index.Add(uint64(id+3), []byte(doc), nil); err != nil {
+			t.Error(err)
+			goto cleanup
+		}
+	}
+
+	scores, err = index.ScoreTerm([]byte("name"), []byte("neo"), docIDs)
+
+	if err != nil || math.Abs(scores[0]-idf(5, 2)) > 1e-9 {
+		t.Errorf("Unexpected scores after add: %v, %v", scores, err)
+		goto cleanup
+	}
+
+	if err = index.Delete(4); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if docs, err = index.documentCount(); err != nil || docs != 4 {
+		t.Errorf("Unexpected document count after delete: %d, %v", docs, err)
+		goto cleanup
+	}
+
+	// indices written before the documents were counted are counted when
+	// opened
+	index.Close()
+	os.RemoveAll(indexDir + "/" + countStorage)
+
+	if index, err = New(indexName, cfg, false); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if docs, err = index.documentCount(); err != nil || docs != 4 {
+		t.Errorf("Unexpected document count of reopened index: %d, %v", docs, err)
+	}
+
+cleanup:
+	index.Close()
+	os.RemoveAll(indexDir)
+}
+
+func TestIndexScoringStatistics(t *testing.T) {
+	var (
+		indexName = "document-sample-scoring-stats"
+		indexDir  = DataDirTmp + "/" + indexName
+		err       error
+		index     *Index
+	)
+
+	// stat returns the uint stored in key of the scoring storage of name
+	stat := func(key []byte) (uint64, bool) {
+		result, err := index.engine.Execute(engine.Command{
+			Index:    indexName,
+			Database: "name_string.bm25",
+			Command:  "get",
+			Key:      key,
+			KeyType:  engine.TypeString,
+		})
+
+		if err != nil || len(result.Data) != 8 {
+			return 0, false
+		}
+
+		return utils.BytesToUint64(result.Data), true
+	}
+
+	index, err = createIndex(indexName, t)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = index.SetSettings(Settings{Similarity: SimilarityBM25})
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	// the values of an array are scored as one field
+	if err = index.Add(1, []byte(`{"name": ["neo way", "neo"]}`), nil); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	for key, expected := range map[string]uint64{
+		string(statDocs):              1,
+		string(statLength):            3,
+		string(normKey(1)):            3,
+		string(termFreqKey("neo", 1)): 2,
+	} {
+		if value, _ := stat([]byte(key)); value != expected {
+			t.Errorf("Unexpected statistic %q: %d", key, value)
+			goto cleanup
+		}
+	}
+
+	// the statistics of the stored document are replaced
+	if err = index.Add(1, []byte(`{"name": "way"}`), nil); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	for key, expected := range map[string]uint64{
+		string(statDocs):              1,
+		string(statLength):            1,
+		string(normKey(1)):            1,
+		string(termFreqKey("way", 1)): 1,
+	} {
+		if value, _ := stat([]byte(key)); value != expected {
+			t.Errorf("Unexpected statistic %q after add: %d", key, value)
+			goto cleanup
+		}
+	}
+
+	if _, ok := stat(termFreqKey("neo", 1)); ok {
+		t.Error("Frequency of the replaced term should be deleted")
+		goto cleanup
+	}
+
+	if err = index.Delete(1); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if docs, _ := stat(statDocs); docs != 0 {
+		t.Errorf("Unexpected statistic after delete: %d", docs)
+	}
+
+cleanup:
+	index.Close()
+	os.RemoveAll(indexDir)
+}
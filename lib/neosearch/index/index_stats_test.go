@@ -0,0 +1,152 @@
+package index
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
+)
+
+func TestIndexStats(t *testing.T) {
+	var (
+		indexName = "document-sample-stats"
+		indexDir  = DataDirTmp + "/" + indexName
+		stats     *Stats
+		fields    map[string]FieldStats
+		storages  map[string]StorageStats
+		err       error
+		index     *Index
+	)
+
+	index, err = createIndex(indexName, t)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = index.SetMapping(Metadata{
+		"state":     Metadata{"type": "keyword"},
+		"employees": Metadata{"type": "int"},
+	})
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	for id, doc := range []string{
+		`{"state": "SC", "employees": 100}`,
+		`{"state": "SP", "employees": 10}`,
+		`{"state": "SC", "employees": 10}`,
+		`{"state": "SC", "employees": 5}`,
+	} {
+		if err = index.Add(uint64(id), []byte(doc), nil); err != nil {
+			t.Error(err)
+			goto cleanup
+		}
+	}
+
+	if stats, err = index.Stats(true); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if stats.Index != indexName || stats.Documents != 4 || stats.Size <= 0 || !stats.Exact {
+		t.Errorf("Unexpected stats: %+v", stats)
+		goto cleanup
+	}
+
+	storages = make(map[string]StorageStats)
+
+	for _, storage := range stats.Storages {
+		storages[storage.Name] = storage
+	}
+
+	if storages[dbName].Keys != 4 || storages[dbName].Size <= 0 ||
+		storages[dbName].KeysSize != 32 || storages[dbName].ValuesSize == 0 {
+		t.Errorf("Unexpected stats of %s: %+v", dbName, storages[dbName])
+		goto cleanup
+	}
+
+	fields = make(map[string]FieldStats)
+
+	for _, field := range stats.Fields {
+		fields[field.Field] = field
+	}
+
+	if !reflect.DeepEqual(fields["state"], FieldStats{
+		Field:    "state",
+		Type:     "keyword",
+		Storage:  "state_keyword.idx",
+		Terms:    2,
+		Postings: 4,
+	}) || fields["employees"].Terms != 3 || fields["employees"].Postings != 4 {
+		t.Errorf("Unexpected field stats: %+v", stats.Fields)
+		goto cleanup
+	}
+
+	if len(stats.TopPostings) < 2 ||
+		!reflect.DeepEqual(stats.TopPostings[0], PostingStats{Field: "state", Term: "SC", Docs: 3}) ||
+		stats.TopPostings[1].Docs != 2 {
+		t.Errorf("Unexpected top postings: %+v", stats.TopPostings)
+		goto cleanup
+	}
+
+	if stats, err = index.Stats(false); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if stats.Documents != 4 || stats.Exact || len(stats.Storages) != len(storages) ||
+		len(stats.Fields) != len(fields) || len(stats.TopPostings) != 0 {
+		t.Errorf("Unexpected approximate stats: %+v", stats)
+		goto cleanup
+	}
+
+	for _, storage := range stats.Storages {
+		if storage.Size <= 0 || storage.Keys != 0 {
+			t.Errorf("Unexpected approximate stats of %s: %+v", storage.Name, storage)
+		}
+	}
+
+	if !reflect.DeepEqual(stats.Fields[0], FieldStats{
+		Field:   stats.Fields[0].Field,
+		Type:    stats.Fields[0].Type,
+		Storage: stats.Fields[0].Storage,
+	}) {
+		t.Errorf("Approximate field stats shouldn't count terms: %+v", stats.Fields)
+		goto cleanup
+	}
+
+	// the documents of approximate stats are the kept count
+	_, err = index.engine.Execute(engine.Command{
+		Index:     indexName,
+		Database:  countStorage,
+		Command:   "incr",
+		Key:       statDocs,
+		KeyType:   engine.TypeString,
+		Value:     utils.Uint64ToBytes(10),
+		ValueType: engine.TypeUint,
+	})
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if stats, err = index.Stats(false); err != nil || stats.Documents != 14 {
+		t.Errorf("Approximate stats should read the document count: %+v, %v", stats, err)
+		goto cleanup
+	}
+
+	if stats, err = index.Stats(true); err != nil || stats.Documents != 4 {
+		t.Errorf("Exact stats should count the documents: %+v, %v", stats, err)
+	}
+
+cleanup:
+	index.Close()
+	os.RemoveAll(indexDir)
+}
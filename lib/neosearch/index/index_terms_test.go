@@ -0,0 +1,96 @@
+package index
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestIndexTerms(t *testing.T) {
+	var (
+		indexName = "document-sample-terms"
+		indexDir  = DataDirTmp + "/" + indexName
+		terms     []TermFreq
+		err       error
+		index     *Index
+	)
+
+	index, err = createIndex(indexName, t)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = index.SetMapping(Metadata{
+		"city":      Metadata{"type": "keyword"},
+		"employees": Metadata{"type": "int"},
+	})
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	for id, doc := range []string{
+		`{"city": "Florianopolis", "employees": 100}`,
+		`{"city": "Sao Paulo", "employees": -10}`,
+		`{"city": "Florianopolis", "employees": 10}`,
+		`{"city": "Sao Jose", "employees": 10}`,
+		`{"city": "Blumenau", "employees": 5}`,
+	} {
+		if err = index.Add(uint64(id), []byte(doc), nil); err != nil {
+			t.Error(err)
+			goto cleanup
+		}
+	}
+
+	for _, test := range []struct {
+		field    string
+		opts     TermsOptions
+		expected string
+	}{
+		{"city", TermsOptions{}, "[{Blumenau 1} {Florianopolis 2} {Sao Jose 1} {Sao Paulo 1}]"},
+		{"city", TermsOptions{Prefix: "Sao"}, "[{Sao Jose 1} {Sao Paulo 1}]"},
+		{"city", TermsOptions{From: "C", To: "Sao Jose"}, "[{Florianopolis 2} {Sao Jose 1}]"},
+		{"city", TermsOptions{Regexp: "o$"}, "[{Sao Paulo 1}]"},
+		{"city", TermsOptions{Size: 2}, "[{Blumenau 1} {Florianopolis 2}]"},
+		{"city", TermsOptions{Size: 2, After: "Florianopolis"}, "[{Sao Jose 1} {Sao Paulo 1}]"},
+		{"city", TermsOptions{Prefix: "Sao", After: "Sao Paulo"}, "[]"},
+		{"employees", TermsOptions{}, "[{5 1} {10 2} {100 1} {-10 1}]"},
+		{"employees", TermsOptions{From: int64(-10), To: "10"}, "[{5 1} {10 2} {-10 1}]"},
+		{"employees", TermsOptions{Size: 1, After: int64(5)}, "[{10 2}]"},
+	} {
+		if terms, err = index.Terms(test.field, test.opts); err != nil {
+			t.Errorf("Terms of %s %+v failed: %s", test.field, test.opts, err)
+			continue
+		}
+
+		if result := fmt.Sprintf("%v", terms); result != test.expected {
+			t.Errorf("Unexpected terms of %s %+v: %s", test.field, test.opts, result)
+		}
+	}
+
+	for _, opts := range []TermsOptions{
+		{Prefix: "1"},
+		{From: "many"},
+		{After: 1.5},
+	} {
+		if terms, err = index.Terms("employees", opts); err == nil {
+			t.Errorf("Terms %+v should fail: %v", opts, terms)
+		}
+	}
+
+	if terms, err = index.Terms("city", TermsOptions{Regexp: "("}); err == nil {
+		t.Errorf("Invalid regexp should fail: %v", terms)
+	}
+
+	if terms, err = index.Terms("unmapped", TermsOptions{}); err == nil || !reflect.DeepEqual(terms, []TermFreq(nil)) {
+		t.Errorf("Terms of unmapped fields should fail: %v", terms)
+	}
+
+cleanup:
+	index.Close()
+	os.RemoveAll(indexDir)
+}
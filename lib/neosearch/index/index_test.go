@@ -92,7 +92,7 @@ func TestBuildAddDocument(t *testing.T) {
 		return
 	}
 
-	commands, err = index.BuildAdd(1, docJSON, nil)
+	commands, _, err = index.BuildAdd(1, docJSON, nil)
 
 	if err != nil {
 		t.Error(err.Error())
@@ -231,7 +231,7 @@ func TestBuildAddDocument(t *testing.T) {
 		},
 	}
 
-	commands, err = index.BuildAdd(2, docJSON, nil)
+	commands, _, err = index.BuildAdd(2, docJSON, nil)
 
 	if err != nil {
 		t.Error(err)
@@ -265,7 +265,7 @@ func TestBuildAddDocumentWithBatchMode(t *testing.T) {
 	}
 
 	index.Batch()
-	commands, err = index.BuildAdd(1, docJSON, nil)
+	commands, _, err = index.BuildAdd(1, docJSON, nil)
 
 	if err != nil {
 		t.Error(err.Error())
@@ -447,7 +447,7 @@ func TestBuildAddDocumentWithBatchMode(t *testing.T) {
 	}
 
 	index.Batch()
-	commands, err = index.BuildAdd(2, docJSON, nil)
+	commands, _, err = index.BuildAdd(2, docJSON, nil)
 
 	if err != nil {
 		t.Error(err)
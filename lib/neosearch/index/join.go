@@ -0,0 +1,354 @@
+package index
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
+)
+
+const joinsFile = "joins.json"
+
+// Join is a materialized join between a field of the index and a field of
+// another index. Both indices keep the join in joins.json, from their own
+// side, and a <name>.join storage mapping their document ids to the ids of
+// the related documents of the other index. Documents are related when a
+// term of Field is a term of ForeignField, like in $join clauses.
+type Join struct {
+	Name         string `json:"name"`
+	Field        string `json:"field"`
+	Index        string `json:"index"`
+	ForeignField string `json:"foreign_field"`
+}
+
+func (j Join) storage() string {
+	return j.Name + ".join"
+}
+
+// IndexOpener opens an index by name. Indices with joins use it to update
+// the other side of the join.
+type IndexOpener func(name string) (*Index, error)
+
+// SetIndexOpener sets the function used to open the other indices of the
+// joins, eg.: NeoSearch.OpenIndex
+func (i *Index) SetIndexOpener(opener IndexOpener) {
+	i.opener = opener
+}
+
+// Joins returns the materialized joins of the index
+func (i *Index) Joins() []Join {
+	i.mappingMutex.Lock()
+	defer i.mappingMutex.Unlock()
+
+	return append([]Join{}, i.joins...)
+}
+
+// FindJoin returns the materialized join between field and the field
+// foreignField of the index foreignIndex.
+func (i *Index) FindJoin(field, foreignIndex, foreignField string) (Join, bool) {
+	for _, join := range i.Joins() {
+		if join.Field == field && join.Index == foreignIndex && join.ForeignField == foreignField {
+			return join, true
+		}
+	}
+
+	return Join{}, false
+}
+
+// CreateJoin declares the materialized join name between fieldA of index
+// a and fieldB of index b and builds its storages from the posting lists
+// of both fields. From then on, the join is updated by Add and Delete on
+// either index.
+func CreateJoin(name string, a *Index, fieldA string, b *Index, fieldB string) error {
+	if !ValidateIndexName(name) {
+		return fmt.Errorf("Invalid join name '%s'", name)
+	}
+
+	if a.Name == b.Name {
+		return errors.New("Joins of an index with itself aren't supported")
+	}
+
+	for _, ind := range []*Index{a, b} {
+		for _, join := range ind.Joins() {
+			if join.Name == name {
+				return fmt.Errorf("Join '%s' already exists in index '%s'", name, ind.Name)
+			}
+		}
+	}
+
+	// validates the fields
+	if _, _, err := a.invertedStorage(fieldA); err != nil {
+		return err
+	}
+
+	if _, _, err := b.invertedStorage(fieldB); err != nil {
+		return err
+	}
+
+	joinA := Join{Name: name, Field: fieldA, Index: b.Name, ForeignField: fieldB}
+	joinB := Join{Name: name, Field: fieldB, Index: a.Name, ForeignField: fieldA}
+
+	err := a.WalkPostings(fieldA, func(term interface{}, docIDs []uint64) error {
+		related, err := b.TermDocIDs(fieldB, term)
+
+		if err != nil {
+			return err
+		}
+
+		for _, id := range docIDs {
+			if err = link(a, joinA, id, b, joinB, related); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err = a.addJoin(joinA); err != nil {
+		return err
+	}
+
+	return b.addJoin(joinB)
+}
+
+// link relates the document id of ind to the documents related of the
+// foreign index in both join storages.
+func link(ind *Index, join Join, id uint64, foreign *Index, foreignJoin Join, related []uint64) error {
+	for _, relatedID := range related {
+		_, err := ind.engine.Execute(engine.Command{
+			Index:     ind.Name,
+			Database:  join.storage(),
+			Command:   "mergeset",
+			Key:       utils.Uint64ToBytes(id),
+			KeyType:   engine.TypeUint,
+			Value:     utils.Uint64ToBytes(relatedID),
+			ValueType: engine.TypeUint,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		_, err = foreign.engine.Execute(engine.Command{
+			Index:     foreign.Name,
+			Database:  foreignJoin.storage(),
+			Command:   "mergeset",
+			Key:       utils.Uint64ToBytes(relatedID),
+			KeyType:   engine.TypeUint,
+			Value:     utils.Uint64ToBytes(id),
+			ValueType: engine.TypeUint,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// JoinedIDs returns the ids of the documents of the other index related
+// to each document of docIDs by the materialized join name.
+func (i *Index) JoinedIDs(name string, docIDs []uint64) (map[uint64][]uint64, error) {
+	storekv, err := i.engine.GetStore(i.Name, Join{Name: name}.storage())
+
+	if err != nil {
+		return nil, err
+	}
+
+	reader := storekv.Reader()
+	defer reader.Close()
+
+	related := make(map[uint64][]uint64)
+
+	for _, id := range docIDs {
+		data, err := reader.Get(utils.Uint64ToBytes(id))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(data) > 0 {
+			related[id] = utils.GetUint64Array(data)
+		}
+	}
+
+	return related, nil
+}
+
+// foreignJoin opens the other index of join and returns its side of the
+// join
+func (i *Index) foreignJoin(join Join) (*Index, Join, error) {
+	if i.opener == nil {
+		return nil, Join{}, fmt.Errorf("Index '%s' can't open the indices of its joins", i.Name)
+	}
+
+	foreign, err := i.opener(join.Index)
+
+	if err != nil {
+		return nil, Join{}, err
+	}
+
+	foreignJoin, ok := foreign.FindJoin(join.ForeignField, i.Name, join.Field)
+
+	if !ok {
+		return nil, Join{}, fmt.Errorf("Join '%s' not found in index '%s'", join.Name, join.Index)
+	}
+
+	return foreign, foreignJoin, nil
+}
+
+// linkJoins relates the document id, indexed by commands, to the
+// documents of the other indices of the joins. Previous relations of the
+// document are removed first.
+func (i *Index) linkJoins(id uint64, commands []engine.Command) error {
+	for _, join := range i.Joins() {
+		if err := i.unlinkJoin(join, id); err != nil {
+			return err
+		}
+
+		storage, keyType, err := i.invertedStorage(join.Field)
+
+		if err != nil {
+			return err
+		}
+
+		foreign, foreignJoin, err := i.foreignJoin(join)
+
+		if err != nil {
+			return err
+		}
+
+		var related []uint64
+
+		// the terms of the field are the keys of its posting commands
+		for _, cmd := range commands {
+			if cmd.Command != "mergeset" || cmd.Database != storage {
+				continue
+			}
+
+			term, err := engine.Decode(cmd.Key, keyType, false)
+
+			if err != nil {
+				return err
+			}
+
+			ids, err := foreign.TermDocIDs(foreignJoin.Field, term)
+
+			if err != nil {
+				return err
+			}
+
+			for _, relatedID := range ids {
+				related = utils.UniqueUint64Add(related, relatedID)
+			}
+		}
+
+		if err = link(i, join, id, foreign, foreignJoin, related); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unlinkJoins removes the relations of the document id from every join
+func (i *Index) unlinkJoins(id uint64) error {
+	for _, join := range i.Joins() {
+		if err := i.unlinkJoin(join, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (i *Index) unlinkJoin(join Join, id uint64) error {
+	related, err := i.JoinedIDs(join.Name, []uint64{id})
+
+	if err != nil || len(related[id]) == 0 {
+		return err
+	}
+
+	foreign, foreignJoin, err := i.foreignJoin(join)
+
+	if err != nil {
+		return err
+	}
+
+	for _, relatedID := range related[id] {
+		_, err = foreign.engine.Execute(engine.Command{
+			Index:     foreign.Name,
+			Database:  foreignJoin.storage(),
+			Command:   "mergedel",
+			Key:       utils.Uint64ToBytes(relatedID),
+			KeyType:   engine.TypeUint,
+			Value:     utils.Uint64ToBytes(id),
+			ValueType: engine.TypeUint,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = i.engine.Execute(engine.Command{
+		Index:    i.Name,
+		Database: join.storage(),
+		Command:  "delete",
+		Key:      utils.Uint64ToBytes(id),
+		KeyType:  engine.TypeUint,
+	})
+
+	return err
+}
+
+func (i *Index) addJoin(join Join) error {
+	i.mappingMutex.Lock()
+	defer i.mappingMutex.Unlock()
+
+	joins := append(append([]Join{}, i.joins...), join)
+	content, err := json.Marshal(joins)
+
+	if err != nil {
+		return err
+	}
+
+	tmpFile := i.dataDir + "/" + joinsFile + ".tmp"
+
+	if err = ioutil.WriteFile(tmpFile, content, 0644); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmpFile, i.dataDir+"/"+joinsFile); err != nil {
+		return err
+	}
+
+	i.joins = joins
+	return nil
+}
+
+func (i *Index) loadJoins() error {
+	content, err := ioutil.ReadFile(i.dataDir + "/" + joinsFile)
+
+	if os.IsNotExist(err) {
+		i.joins = nil
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err = json.Unmarshal(content, &i.joins); err != nil {
+		return fmt.Errorf("Invalid joins file of index '%s': %s", i.Name, err)
+	}
+
+	return nil
+}
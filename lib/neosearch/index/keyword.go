@@ -0,0 +1,134 @@
+package index
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
+)
+
+// Keyword normalizers. The normalizers of a keyword field are applied
+// in the order of the "normalizer" option, on indexing and on queries.
+const (
+	NormalizerLowercase string = "lowercase"
+	NormalizerTrim      string = "trim"
+	NormalizerASCIIFold string = "asciifold"
+)
+
+var asciiFolding = map[rune]string{
+	'À': "A", 'Á': "A", 'Â': "A", 'Ã': "A", 'Ä': "A", 'Å': "A", 'Æ': "AE",
+	'Ç': "C", 'È': "E", 'É': "E", 'Ê': "E", 'Ë': "E", 'Ì': "I", 'Í': "I",
+	'Î': "I", 'Ï': "I", 'Ð': "D", 'Ñ': "N", 'Ò': "O", 'Ó': "O", 'Ô': "O",
+	'Õ': "O", 'Ö': "O", 'Ø': "O", 'Ù': "U", 'Ú': "U", 'Û': "U", 'Ü': "U",
+	'Ý': "Y", 'Þ': "TH", 'ß': "ss", 'à': "a", 'á': "a", 'â': "a", 'ã': "a",
+	'ä': "a", 'å': "a", 'æ': "ae", 'ç': "c", 'è': "e", 'é': "e", 'ê': "e",
+	'ë': "e", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ð': "d", 'ñ': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ù': "u",
+	'ú': "u", 'û': "u", 'ü': "u", 'ý': "y", 'þ': "th", 'ÿ': "y",
+}
+
+// asciiFold replaces the latin accented characters of value by their
+// ASCII equivalent.
+func asciiFold(value string) string {
+	folded := make([]rune, 0, len(value))
+
+	for _, r := range value {
+		if r > unicode.MaxASCII {
+			if ascii, ok := asciiFolding[r]; ok {
+				folded = append(folded, []rune(ascii)...)
+				continue
+			}
+		}
+
+		folded = append(folded, r)
+	}
+
+	return string(folded)
+}
+
+// keywordNormalizers returns the list of normalizers of a keyword field.
+// The "normalizer" option could be a single name or a list of names.
+func keywordNormalizers(metadata Metadata) ([]string, error) {
+	var names []string
+
+	switch v := metadata["normalizer"].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		names = []string{v}
+	case []string:
+		names = v
+	case []interface{}:
+		for _, name := range v {
+			str, ok := name.(string)
+
+			if !ok {
+				return nil, fmt.Errorf("Invalid normalizer: %+v", name)
+			}
+
+			names = append(names, str)
+		}
+	default:
+		return nil, fmt.Errorf("Invalid normalizer: %+v", v)
+	}
+
+	for _, name := range names {
+		switch name {
+		case NormalizerLowercase, NormalizerTrim, NormalizerASCIIFold:
+		default:
+			return nil, fmt.Errorf("Unknown normalizer '%s'", name)
+		}
+	}
+
+	return names, nil
+}
+
+// normalizeKeyword applies the normalizers of a keyword field to value.
+func normalizeKeyword(value string, metadata Metadata) (string, error) {
+	normalizers, err := keywordNormalizers(metadata)
+
+	if err != nil {
+		return "", err
+	}
+
+	for _, name := range normalizers {
+		switch name {
+		case NormalizerLowercase:
+			value = strings.ToLower(value)
+		case NormalizerTrim:
+			value = strings.TrimSpace(value)
+		case NormalizerASCIIFold:
+			value = asciiFold(value)
+		}
+	}
+
+	return value, nil
+}
+
+// buildIndexKeyword indexes the value as a single term. Differently from
+// strings, keywords aren't tokenized nor lowercased.
+func (i *Index) buildIndexKeyword(id uint64, field string, value string, metadata Metadata) ([]engine.Command, error) {
+	var commands []engine.Command
+
+	value, err := normalizeKeyword(value, metadata)
+
+	if err != nil {
+		return nil, fmt.Errorf("Error indexing field '%s': %s", field, err)
+	}
+
+	storageName := field + "_keyword.idx"
+
+	cmd := engine.Command{}
+	cmd.Index = i.Name
+	cmd.Database = storageName
+	cmd.Command = "mergeset"
+	cmd.Key = []byte(value)
+	cmd.KeyType = engine.TypeString
+	cmd.Value = utils.Uint64ToBytes(id)
+	cmd.ValueType = engine.TypeUint
+
+	commands = append(commands, cmd)
+	return i.buildIndexScoring(id, storageName, []string{value}, 1, commands)
+}
@@ -0,0 +1,382 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/extemporalgenome/slug"
+)
+
+const mappingFile string = "mapping.json"
+
+// Mapping returns the field metadata stored in the index directory.
+func (i *Index) Mapping() Metadata {
+	i.mappingMutex.Lock()
+	defer i.mappingMutex.Unlock()
+
+	return copyMetadata(i.mapping)
+}
+
+// SetMapping merges mapping into the index mapping and write it to disk.
+// Fields already mapped with a different type are rejected.
+func (i *Index) SetMapping(mapping Metadata) error {
+	if err := ValidateMapping(mapping); err != nil {
+		return err
+	}
+
+	i.mappingMutex.Lock()
+	defer i.mappingMutex.Unlock()
+
+	merged, changed, err := mergeMetadata(i.mapping, mapping)
+
+	if err != nil {
+		return err
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return i.saveMapping(merged)
+}
+
+// ValidateMapping verifies if every field of mapping has a known type.
+func ValidateMapping(mapping Metadata) error {
+	for field, value := range mapping {
+		fieldMeta, ok := toMetadata(value)
+
+		if !ok {
+			return fmt.Errorf("Invalid mapping for field '%s': %+v", field, value)
+		}
+
+		if err := validateField(field, fieldMeta); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateField(field string, fieldMeta Metadata) error {
+	fieldType, ok := fieldMeta["type"].(string)
+
+	if !ok {
+		return fmt.Errorf("Invalid mapping. Field 'type' is required: %+v", fieldMeta)
+	}
+
+	submeta, hasSub := toMetadata(fieldMeta["metadata"])
+
+	switch normalizeType(fieldType) {
+	case "":
+		return fmt.Errorf("Invalid mapping for field '%s'. Unknown type %s", field, fieldType)
+	case "string":
+		if err := validateAnalyzer(fieldMeta); err != nil {
+			return fmt.Errorf("Invalid mapping for field '%s': %s", field, err)
+		}
+	case "keyword":
+		if _, err := keywordNormalizers(fieldMeta); err != nil {
+			return fmt.Errorf("Invalid mapping for field '%s': %s", field, err)
+		}
+	case "slice":
+		// the metadata of slices describes the type of its items
+		if hasSub {
+			if err := validateField(field, submeta); err != nil {
+				return err
+			}
+		}
+	case "object":
+		if hasSub {
+			if err := ValidateMapping(submeta); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := validateCopyTo(field, fieldMeta); err != nil {
+		return err
+	}
+
+	if err := validateDocValues(field, fieldMeta); err != nil {
+		return err
+	}
+
+	return validateMultiFields(field, fieldMeta)
+}
+
+// applyMapping returns the index mapping merged with the metadata supplied
+// on document indexing. The second return value says if metadata adds new
+// fields to the index mapping.
+func (i *Index) applyMapping(metadata Metadata) (Metadata, bool, error) {
+	i.mappingMutex.Lock()
+	defer i.mappingMutex.Unlock()
+
+	if err := ValidateMapping(metadata); err != nil {
+		return nil, false, err
+	}
+
+	return mergeMetadata(i.mapping, metadata)
+}
+
+// updateMapping persists the merged mapping returned by applyMapping.
+func (i *Index) updateMapping(metadata Metadata) error {
+	i.mappingMutex.Lock()
+	defer i.mappingMutex.Unlock()
+
+	merged, changed, err := mergeMetadata(i.mapping, metadata)
+
+	if err != nil || !changed {
+		return err
+	}
+
+	return i.saveMapping(merged)
+}
+
+// fieldMapping returns the mapping of the field given by its dotted
+// name, eg.: "address.city". Nil is returned for unmapped fields.
+func (i *Index) fieldMapping(field string) Metadata {
+	i.mappingMutex.Lock()
+	defer i.mappingMutex.Unlock()
+
+	var fieldMeta, subFields Metadata
+
+	metadata := i.mapping
+
+	for _, part := range strings.Split(field, ".") {
+		fieldMeta = nil
+
+		// sub-fields (multi-fields) and object fields share the
+		// dotted notation
+		for _, fields := range []Metadata{subFields, metadata} {
+			for key, value := range fields {
+				if key == part || slug.SlugAscii(key) == part {
+					fieldMeta, _ = toMetadata(value)
+					break
+				}
+			}
+
+			if fieldMeta != nil {
+				break
+			}
+		}
+
+		if fieldMeta == nil {
+			return nil
+		}
+
+		metadata, _ = toMetadata(fieldMeta["metadata"])
+		fieldType, _ := fieldMeta["type"].(string)
+
+		// the metadata of slices describes its items
+		if normalizeType(fieldType) == "slice" && metadata != nil {
+			fieldMeta = metadata
+			metadata, _ = toMetadata(fieldMeta["metadata"])
+		}
+
+		subFields, _ = toMetadata(fieldMeta["fields"])
+	}
+
+	return fieldMeta
+}
+
+func (i *Index) loadMapping() error {
+	content, err := ioutil.ReadFile(i.dataDir + "/" + mappingFile)
+
+	if os.IsNotExist(err) {
+		i.mapping = Metadata{}
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	mapping := Metadata{}
+
+	if err = json.Unmarshal(content, &mapping); err != nil {
+		return fmt.Errorf("Invalid mapping file of index '%s': %s", i.Name, err)
+	}
+
+	i.mapping = copyMetadata(mapping)
+	return nil
+}
+
+// saveMapping writes the mapping to a temporary file and then rename it
+// to avoid partial writes.
+func (i *Index) saveMapping(mapping Metadata) error {
+	content, err := json.Marshal(mapping)
+
+	if err != nil {
+		return err
+	}
+
+	tmpFile := i.dataDir + "/" + mappingFile + ".tmp"
+
+	if err = ioutil.WriteFile(tmpFile, content, 0644); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmpFile, i.dataDir+"/"+mappingFile); err != nil {
+		return err
+	}
+
+	i.mapping = mapping
+	return nil
+}
+
+// mergeMetadata merges other into base returning a new Metadata. Fields
+// in other override the options of base (like date format), but the
+// field types must be the same.
+func mergeMetadata(base, other Metadata) (Metadata, bool, error) {
+	var changed bool
+
+	merged := copyMetadata(base)
+
+	for field, value := range other {
+		otherMeta, ok := toMetadata(value)
+
+		if !ok {
+			return nil, false, fmt.Errorf("Invalid metadata for field '%s': %+v", field, value)
+		}
+
+		baseMeta, exists := toMetadata(merged[field])
+
+		if !exists {
+			merged[field] = copyMetadata(otherMeta)
+			changed = true
+			continue
+		}
+
+		fieldMeta, fieldChanged, err := mergeField(field, baseMeta, otherMeta)
+
+		if err != nil {
+			return nil, false, err
+		}
+
+		merged[field] = fieldMeta
+		changed = changed || fieldChanged
+	}
+
+	return merged, changed, nil
+}
+
+func mergeField(field string, baseMeta, otherMeta Metadata) (Metadata, bool, error) {
+	var (
+		changed bool
+		err     error
+	)
+
+	baseType, _ := baseMeta["type"].(string)
+	otherType, _ := otherMeta["type"].(string)
+
+	if normalizeType(baseType) != normalizeType(otherType) {
+		return nil, false, fmt.Errorf("Field '%s' is mapped as '%s' and can't be changed to '%s'",
+			field, baseType, otherType)
+	}
+
+	fieldMeta := copyMetadata(baseMeta)
+
+	for name, option := range otherMeta {
+		if name != "metadata" && name != "type" && name != "fields" {
+			fieldMeta[name] = option
+		}
+	}
+
+	// sub-fields are merged like object fields
+	if otherFields, ok := toMetadata(otherMeta["fields"]); ok {
+		baseFields, _ := toMetadata(baseMeta["fields"])
+		fields, fieldsChanged, err := mergeMetadata(baseFields, otherFields)
+
+		if err != nil {
+			return nil, false, fmt.Errorf("Field '%s': %s", field, err)
+		}
+
+		fieldMeta["fields"] = fields
+		changed = fieldsChanged
+	}
+
+	baseSub, hasBaseSub := toMetadata(baseMeta["metadata"])
+	otherSub, hasSub := toMetadata(otherMeta["metadata"])
+
+	if !hasSub {
+		return fieldMeta, changed, nil
+	}
+
+	var (
+		sub        Metadata
+		subChanged bool
+	)
+
+	if normalizeType(baseType) == "slice" {
+		if !hasBaseSub {
+			sub, subChanged = copyMetadata(otherSub), true
+		} else {
+			sub, subChanged, err = mergeField(field, baseSub, otherSub)
+		}
+	} else {
+		sub, subChanged, err = mergeMetadata(baseSub, otherSub)
+	}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("Field '%s': %s", field, err)
+	}
+
+	fieldMeta["metadata"] = sub
+	return fieldMeta, changed || subChanged, nil
+}
+
+// normalizeType returns the canonical name of a field type or an empty
+// string if the type is unknown.
+func normalizeType(fieldType string) string {
+	switch strings.ToLower(fieldType) {
+	case "string":
+		return "string"
+	case "keyword":
+		return "keyword"
+	case "date":
+		return "date"
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		return "uint"
+	case "int", "int8", "int16", "int32", "int64":
+		return "int"
+	case "bool", "boolean":
+		return "bool"
+	case "float", "float32", "float64":
+		return "float"
+	case "slice", "list", "[]interface {}":
+		return "slice"
+	case "object", "map", "map[string]interface {}":
+		return "object"
+	}
+
+	return ""
+}
+
+// toMetadata converts the value to Metadata. Metadata decoded from JSON
+// have map[string]interface{} values instead of Metadata.
+func toMetadata(value interface{}) (Metadata, bool) {
+	switch v := value.(type) {
+	case Metadata:
+		return v, true
+	case map[string]interface{}:
+		return Metadata(v), true
+	}
+
+	return nil, false
+}
+
+// copyMetadata returns a deep copy of metadata with every nested object
+// converted to Metadata.
+func copyMetadata(metadata Metadata) Metadata {
+	result := Metadata{}
+
+	for key, value := range metadata {
+		if submeta, ok := toMetadata(value); ok {
+			result[key] = copyMetadata(submeta)
+		} else {
+			result[key] = value
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,87 @@
+package index
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+	"github.com/extemporalgenome/slug"
+)
+
+// validateMultiFields verifies the "fields" option of a field. Each
+// sub-field is a scalar field indexed from the same source value and
+// queried by the dotted name <field>.<sub-field>.
+func validateMultiFields(field string, fieldMeta Metadata) error {
+	if fieldMeta["fields"] == nil {
+		return nil
+	}
+
+	fields, ok := toMetadata(fieldMeta["fields"])
+
+	if !ok {
+		return fmt.Errorf("Invalid sub-fields of field '%s': %+v", field, fieldMeta["fields"])
+	}
+
+	fieldType, _ := fieldMeta["type"].(string)
+
+	switch normalizeType(fieldType) {
+	case "slice", "object":
+		return fmt.Errorf("Invalid mapping for field '%s'. Fields of type %s can't have sub-fields",
+			field, fieldType)
+	}
+
+	for name, value := range fields {
+		subField := field + "." + name
+		subMeta, ok := toMetadata(value)
+
+		if !ok {
+			return fmt.Errorf("Invalid mapping for field '%s': %+v", subField, value)
+		}
+
+		subType, _ := subMeta["type"].(string)
+
+		switch normalizeType(subType) {
+		case "slice", "object":
+			return fmt.Errorf("Invalid mapping for field '%s'. Sub-fields can't be of type %s",
+				subField, subType)
+		}
+
+		if err := validateField(subField, subMeta); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// buildIndexMultiFields appends to commands the commands to index the
+// sub-fields declared in the "fields" option of metadata.
+func (i *Index) buildIndexMultiFields(id uint64, field string, value interface{}, metadata Metadata, commands []engine.Command) ([]engine.Command, error) {
+	fields, ok := toMetadata(metadata["fields"])
+
+	if !ok {
+		return commands, nil
+	}
+
+	var names []string
+
+	for name := range fields {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		subMeta, _ := toMetadata(fields[name])
+
+		cmds, err := i.buildIndexValue(id, field+"."+slug.SlugAscii(name), value, subMeta)
+
+		if err != nil {
+			return nil, err
+		}
+
+		commands = append(commands, cmds...)
+	}
+
+	return commands, nil
+}
@@ -0,0 +1,276 @@
+package index
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
+)
+
+// FieldType returns the normalized mapped type of field, or an empty
+// string if the field isn't mapped.
+func (i *Index) FieldType(field string) string {
+	if field == AllField {
+		return "string"
+	}
+
+	fieldType, _ := i.fieldMapping(utils.FieldNorm(field))["type"].(string)
+	return normalizeType(fieldType)
+}
+
+// invertedStorage returns the inverted storage (.idx) of field and the
+// engine type of its keys. Dates are indexed in the int storage.
+func (i *Index) invertedStorage(field string) (string, uint8, error) {
+	if field == AllField {
+		return AllField + "_string.idx", engine.TypeString, nil
+	}
+
+	var keyType uint8
+
+	fieldType := i.FieldType(field)
+
+	switch fieldType {
+	case "string", "keyword":
+		keyType = engine.TypeString
+	case "uint":
+		keyType = engine.TypeUint
+	case "int":
+		keyType = engine.TypeInt
+	case "date":
+		fieldType, keyType = "int", engine.TypeInt
+	case "float":
+		keyType = engine.TypeFloat
+	case "bool":
+		keyType = engine.TypeBool
+	case "":
+		return "", 0, fmt.Errorf("Field '%s' isn't mapped", field)
+	default:
+		return "", 0, fmt.Errorf("Field '%s' of type %s has no terms", field, fieldType)
+	}
+
+	return utils.FieldNorm(field) + "_" + fieldType + ".idx", keyType, nil
+}
+
+// WalkPostings calls fn for each term of the inverted storage of field, in
+// storage order, with the sorted ids of the documents having the term.
+// Terms are string, int64 (also dates, in unix nanoseconds), uint64,
+// float64 or bool, depending on the field type. Walking stops at the first
+// error returned by fn.
+func (i *Index) WalkPostings(field string, fn func(term interface{}, docIDs []uint64) error) error {
+	storage, keyType, err := i.invertedStorage(field)
+
+	if err != nil {
+		return err
+	}
+
+	storekv, err := i.engine.GetStore(i.Name, storage)
+
+	if err != nil {
+		return err
+	}
+
+	reader := storekv.Reader()
+	it := reader.GetIterator()
+
+	defer func() {
+		it.Close()
+		reader.Close()
+	}()
+
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		value := it.Value()
+
+		if len(value) == 0 {
+			continue
+		}
+
+		term, err := engine.Decode(it.Key(), keyType, false)
+
+		if err != nil {
+			return err
+		}
+
+		if err = fn(term, utils.GetUint64Array(value)); err != nil {
+			return err
+		}
+	}
+
+	return it.GetError()
+}
+
+// TermDocIDs returns the sorted ids of the documents having term in
+// field. Unlike FilterTermID the term isn't analyzed, but it's converted to
+// the type of the field, eg.: the int64 term 10 matches the string "10"
+// and the uint 10. Terms that can't be converted match no documents.
+func (i *Index) TermDocIDs(field string, term interface{}) ([]uint64, error) {
+	storage, keyType, err := i.invertedStorage(field)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := encodeTerm(term, keyType)
+
+	if !ok {
+		return nil, nil
+	}
+
+	if keyType == engine.TypeString {
+		// keyword terms are normalized
+		if storage, key, err = i.termStorage([]byte(field), key); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := i.engine.Execute(engine.Command{
+		Index:    i.Name,
+		Database: storage,
+		Command:  "get",
+		Key:      key,
+		KeyType:  keyType,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.GetUint64Array(result.Data), nil
+}
+
+// encodeTerm encodes term as a key of the given engine type
+func encodeTerm(term interface{}, keyType uint8) ([]byte, bool) {
+	switch keyType {
+	case engine.TypeString:
+		switch v := term.(type) {
+		case string:
+			return []byte(v), true
+		case int64:
+			return []byte(strconv.FormatInt(v, 10)), true
+		case uint64:
+			return []byte(strconv.FormatUint(v, 10)), true
+		case float64:
+			return []byte(strconv.FormatFloat(v, 'f', -1, 64)), true
+		case bool:
+			return []byte(strconv.FormatBool(v)), true
+		}
+	case engine.TypeInt:
+		switch v := term.(type) {
+		case int64:
+			return utils.Int64ToBytes(v), true
+		case uint64:
+			if v <= math.MaxInt64 {
+				return utils.Int64ToBytes(int64(v)), true
+			}
+		case float64:
+			if v == math.Trunc(v) && v >= math.MinInt64 && v <= math.MaxInt64 {
+				return utils.Int64ToBytes(int64(v)), true
+			}
+		case string:
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return utils.Int64ToBytes(n), true
+			}
+		}
+	case engine.TypeUint:
+		switch v := term.(type) {
+		case uint64:
+			return utils.Uint64ToBytes(v), true
+		case int64:
+			if v >= 0 {
+				return utils.Uint64ToBytes(uint64(v)), true
+			}
+		case float64:
+			if v == math.Trunc(v) && v >= 0 && v <= math.MaxUint64 {
+				return utils.Uint64ToBytes(uint64(v)), true
+			}
+		case string:
+			if n, err := strconv.ParseUint(v, 10, 64); err == nil {
+				return utils.Uint64ToBytes(n), true
+			}
+		}
+	case engine.TypeFloat:
+		switch v := term.(type) {
+		case float64:
+			return utils.Float64ToBytes(v), true
+		case int64:
+			return utils.Float64ToBytes(float64(v)), true
+		case uint64:
+			return utils.Float64ToBytes(float64(v)), true
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return utils.Float64ToBytes(f), true
+			}
+		}
+	case engine.TypeBool:
+		switch v := term.(type) {
+		case bool:
+			return utils.BoolToBytes(v), true
+		case string:
+			if b, err := strconv.ParseBool(v); err == nil {
+				return utils.BoolToBytes(b), true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// DocIDs returns the sorted ids of every document of the index
+func (i *Index) DocIDs() ([]uint64, error) {
+	var docIDs []uint64
+
+	storekv, err := i.engine.GetStore(i.Name, dbName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	reader := storekv.Reader()
+	it := reader.GetIterator()
+
+	defer func() {
+		it.Close()
+		reader.Close()
+	}()
+
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		if len(it.Key()) == 8 {
+			docIDs = append(docIDs, utils.BytesToUint64(it.Key()))
+		}
+	}
+
+	return docIDs, it.GetError()
+}
+
+// WalkDocs calls fn with each document of the index, in id order. Walking
+// stops at the first error returned by fn.
+func (i *Index) WalkDocs(fn func(id uint64, doc []byte) error) error {
+	storekv, err := i.engine.GetStore(i.Name, dbName)
+
+	if err != nil {
+		return err
+	}
+
+	reader := storekv.Reader()
+	it := reader.GetIterator()
+
+	defer func() {
+		it.Close()
+		reader.Close()
+	}()
+
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		if len(it.Key()) != 8 {
+			continue
+		}
+
+		doc := append([]byte{}, it.Value()...)
+
+		if err = fn(utils.BytesToUint64(it.Key()), doc); err != nil {
+			return err
+		}
+	}
+
+	return it.GetError()
+}
@@ -0,0 +1,338 @@
+package index
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
+)
+
+// BM25 parameters
+const (
+	bm25K1 float64 = 1.2
+	bm25B  float64 = 0.75
+)
+
+// Keys of the scoring storages (<field>_<type>.bm25). Each text storage
+// has a scoring storage with the frequency of terms by document, the
+// length of the field by document and the corpus statistics of the field.
+var (
+	statDocs   = []byte("sdocs")
+	statLength = []byte("slength")
+)
+
+// countStorage is the storage of the number of documents of the index,
+// the statDocs counter written by Add and Delete. It's the number of
+// documents of the inverse document frequencies.
+const countStorage = "stats.bm25"
+
+func scoringStorage(storage string) string {
+	return strings.TrimSuffix(storage, "."+indexExt) + ".bm25"
+}
+
+// termFreqKey returns the key of the frequency of term in document id
+func termFreqKey(term string, id uint64) []byte {
+	key := make([]byte, 0, len(term)+10)
+	key = append(key, 't')
+	key = append(key, term...)
+	key = append(key, 0)
+	return append(key, utils.Uint64ToBytes(id)...)
+}
+
+// normKey returns the key of the field length of document id
+func normKey(id uint64) []byte {
+	return append([]byte{'n'}, utils.Uint64ToBytes(id)...)
+}
+
+// buildIndexScoring appends to commands the commands to store the term
+// frequencies, the field length and the corpus statistics of a value of
+// the text storage. The commands of the values of a document are merged
+// by mergeScoring. Nothing is stored if the index similarity isn't BM25.
+func (i *Index) buildIndexScoring(id uint64, storage string, terms []string, length uint64, commands []engine.Command) ([]engine.Command, error) {
+	if i.Settings().Similarity != SimilarityBM25 {
+		return commands, nil
+	}
+
+	storageName := scoringStorage(storage)
+
+	var uniqueTerms []string
+
+	freqs := map[string]uint64{}
+
+	for _, term := range terms {
+		if freqs[term] == 0 {
+			uniqueTerms = append(uniqueTerms, term)
+		}
+
+		freqs[term]++
+	}
+
+	addCommand := func(command string, key []byte, keyType uint8, value uint64) {
+		commands = append(commands, engine.Command{
+			Index:     i.Name,
+			Database:  storageName,
+			Command:   command,
+			Key:       key,
+			KeyType:   keyType,
+			Value:     utils.Uint64ToBytes(value),
+			ValueType: engine.TypeUint,
+		})
+	}
+
+	for _, term := range uniqueTerms {
+		addCommand("set", termFreqKey(term, id), engine.TypeString, freqs[term])
+	}
+
+	addCommand("set", normKey(id), engine.TypeString, length)
+	addCommand("incr", statDocs, engine.TypeString, 1)
+	addCommand("incr", statLength, engine.TypeString, length)
+
+	return commands, nil
+}
+
+// mergeScoring merges the scoring commands built for each value of the
+// fields of a document: the term frequencies and the lengths of the values
+// of a field are summed, and the document is counted once by field.
+func mergeScoring(commands []engine.Command) []engine.Command {
+	merged := make([]engine.Command, 0, len(commands))
+	positions := make(map[string]int)
+
+	for _, cmd := range commands {
+		if !strings.HasSuffix(cmd.Database, ".bm25") {
+			merged = append(merged, cmd)
+			continue
+		}
+
+		key := cmd.Database + "\x00" + cmd.Command + "\x00" + string(cmd.Key)
+		pos, ok := positions[key]
+
+		if !ok {
+			positions[key] = len(merged)
+			merged = append(merged, cmd)
+			continue
+		}
+
+		if !bytes.Equal(cmd.Key, statDocs) {
+			sum := utils.BytesToUint64(merged[pos].Value) + utils.BytesToUint64(cmd.Value)
+			merged[pos].Value = utils.Uint64ToBytes(sum)
+		}
+	}
+
+	return merged
+}
+
+// buildRevertScoring returns the commands reverting the scoring statistics
+// of the stored document id, written again when the document is added
+// again. Nothing is reverted if the index similarity isn't BM25.
+func (i *Index) buildRevertScoring(id uint64) ([]engine.Command, error) {
+	var scoring []engine.Command
+
+	if i.Settings().Similarity != SimilarityBM25 {
+		return nil, nil
+	}
+
+	doc, err := i.storedDocument(id)
+
+	if err != nil || len(doc) == 0 {
+		return nil, err
+	}
+
+	reverted, err := i.buildRevert(id, doc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cmd := range reverted {
+		if strings.HasSuffix(cmd.Database, ".bm25") {
+			scoring = append(scoring, cmd)
+		}
+	}
+
+	return scoring, nil
+}
+
+// ScoreTerm returns the BM25 score of the term value of field for each
+// document of docIDs. The docIDs must be the entire posting list of the
+// term, because its length is the document frequency. BM25 is enabled by
+// the SimilarityBM25 setting, indices without it have no term frequencies
+// and field lengths and are scored only by the BM25 inverse document
+// frequency of the term.
+func (i *Index) ScoreTerm(field, value []byte, docIDs []uint64) ([]float64, error) {
+	storage, term, err := i.termStorage(field, value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make([]float64, len(docIDs))
+	df := float64(len(docIDs))
+
+	if i.Settings().Similarity != SimilarityBM25 {
+		docs, err := i.documentCount()
+
+		if err != nil {
+			return nil, err
+		}
+
+		n := math.Max(float64(docs), df)
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+		for idx := range scores {
+			scores[idx] = idf
+		}
+
+		return scores, nil
+	}
+
+	storekv, err := i.engine.GetStore(i.Name, scoringStorage(storage))
+
+	if err != nil {
+		return nil, err
+	}
+
+	reader := storekv.Reader()
+	defer reader.Close()
+
+	getUint := func(key []byte) (uint64, bool, error) {
+		data, err := reader.Get(key)
+
+		if err != nil || len(data) != 8 {
+			return 0, false, err
+		}
+
+		return utils.BytesToUint64(data), true, nil
+	}
+
+	docs, _, err := getUint(statDocs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	totalLength, _, err := getUint(statLength)
+
+	if err != nil {
+		return nil, err
+	}
+
+	n := math.Max(float64(docs), df)
+	idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+	avgLength := float64(1)
+
+	if docs > 0 && totalLength > 0 {
+		avgLength = float64(totalLength) / float64(docs)
+	}
+
+	for idx, id := range docIDs {
+		tf, hasTf, err := getUint(termFreqKey(string(term), id))
+
+		if err != nil {
+			return nil, err
+		}
+
+		length, hasNorm, err := getUint(normKey(id))
+
+		if err != nil {
+			return nil, err
+		}
+
+		freq, docLength := float64(1), avgLength
+
+		if hasTf {
+			freq = float64(tf)
+		}
+
+		if hasNorm {
+			docLength = float64(length)
+		}
+
+		scores[idx] = idf * (freq * (bm25K1 + 1)) /
+			(freq + bm25K1*(1-bm25B+bm25B*docLength/avgLength))
+	}
+
+	return scores, nil
+}
+
+// buildCount returns the incr or decr command counting a document, in
+// batch mode when the index is
+func (i *Index) buildCount(command string) []engine.Command {
+	commands := []engine.Command{{
+		Index:     i.Name,
+		Database:  countStorage,
+		Command:   command,
+		Key:       statDocs,
+		KeyType:   engine.TypeString,
+		Value:     utils.Uint64ToBytes(1),
+		ValueType: engine.TypeUint,
+	}}
+
+	if len(i.flushStorages) > 0 {
+		commands = i.batchCommands(commands)
+	}
+
+	return commands
+}
+
+// documentCount returns the number of documents of the index counted by
+// Add and Delete
+func (i *Index) documentCount() (uint64, error) {
+	if !i.storageExists(countStorage) {
+		return 0, nil
+	}
+
+	result, err := i.engine.Execute(engine.Command{
+		Index:    i.Name,
+		Database: countStorage,
+		Command:  "get",
+		Key:      statDocs,
+		KeyType:  engine.TypeString,
+	})
+
+	if err != nil || len(result.Data) != 8 {
+		return 0, err
+	}
+
+	return utils.BytesToUint64(result.Data), nil
+}
+
+// initDocumentCount counts the documents of the indices written before
+// they were counted by Add and Delete, eg.: restored from old backups.
+func (i *Index) initDocumentCount() error {
+	if !i.storageExists(dbName) || i.storageExists(countStorage) {
+		return nil
+	}
+
+	result, err := i.engine.Execute(engine.Command{
+		Index:    i.Name,
+		Database: dbName,
+		Command:  "count",
+	})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = i.engine.Execute(engine.Command{
+		Index:     i.Name,
+		Database:  countStorage,
+		Command:   "set",
+		Key:       statDocs,
+		KeyType:   engine.TypeString,
+		Value:     utils.Uint64ToBytes(result.Count),
+		ValueType: engine.TypeUint,
+	})
+
+	return err
+}
+
+// storageExists reports if the storage was created in the index directory
+func (i *Index) storageExists(storage string) bool {
+	_, err := os.Stat(filepath.Join(i.dataDir, storage))
+	return err == nil
+}
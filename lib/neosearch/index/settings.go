@@ -0,0 +1,118 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+const (
+	settingsFile string = "settings.json"
+
+	// ConflictReject rejects documents with values that doesn't match
+	// the mapped type of the field.
+	ConflictReject string = "reject"
+
+	// ConflictCoerce converts the values to the mapped type of the field
+	// when possible.
+	ConflictCoerce string = "coerce"
+
+	// SimilarityBM25 stores term frequencies, field lengths and corpus
+	// statistics of text fields to rank search results with BM25.
+	SimilarityBM25 string = "bm25"
+)
+
+// Settings stores the index options persisted in the index directory.
+type Settings struct {
+	// Dynamic enables the detection of the type of unmapped fields.
+	// Detected types are recorded in the index mapping.
+	Dynamic bool `json:"dynamic"`
+
+	// All enables the catch-all field. See AllField.
+	All bool `json:"all"`
+
+	// Conflict is the policy for values that doesn't match the mapped
+	// type of the field: ConflictReject or ConflictCoerce. An empty
+	// policy keeps the conversions done by each field type.
+	Conflict string `json:"conflict,omitempty"`
+
+	// Similarity is the scoring model of text fields. BM25 is opt-in:
+	// indices without SimilarityBM25 don't store term frequencies and
+	// field lengths, and are scored only by the inverse document
+	// frequency of the terms.
+	Similarity string `json:"similarity,omitempty"`
+}
+
+// Validate verifies if settings have valid options.
+func (s Settings) Validate() error {
+	switch s.Conflict {
+	case "", ConflictReject, ConflictCoerce:
+	default:
+		return fmt.Errorf("Invalid conflict policy '%s'. Expected '%s' or '%s'",
+			s.Conflict, ConflictReject, ConflictCoerce)
+	}
+
+	if s.Similarity != "" && s.Similarity != SimilarityBM25 {
+		return fmt.Errorf("Invalid similarity '%s'. Expected '%s'", s.Similarity, SimilarityBM25)
+	}
+
+	return nil
+}
+
+// Settings returns the index settings
+func (i *Index) Settings() Settings {
+	i.mappingMutex.Lock()
+	defer i.mappingMutex.Unlock()
+
+	return i.settings
+}
+
+// SetSettings validates and write the index settings to disk.
+func (i *Index) SetSettings(settings Settings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(settings)
+
+	if err != nil {
+		return err
+	}
+
+	i.mappingMutex.Lock()
+	defer i.mappingMutex.Unlock()
+
+	tmpFile := i.dataDir + "/" + settingsFile + ".tmp"
+
+	if err = ioutil.WriteFile(tmpFile, content, 0644); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmpFile, i.dataDir+"/"+settingsFile); err != nil {
+		return err
+	}
+
+	i.settings = settings
+	return nil
+}
+
+func (i *Index) loadSettings() error {
+	content, err := ioutil.ReadFile(i.dataDir + "/" + settingsFile)
+
+	if os.IsNotExist(err) {
+		i.settings = Settings{}
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	settings := Settings{}
+
+	if err = json.Unmarshal(content, &settings); err != nil {
+		return fmt.Errorf("Invalid settings file of index '%s': %s", i.Name, err)
+	}
+
+	i.settings = settings
+	return settings.Validate()
+}
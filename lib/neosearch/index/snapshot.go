@@ -0,0 +1,120 @@
+package index
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/store"
+)
+
+// ConfigFiles are the files of the index directory that aren't storages
+var ConfigFiles = []string{mappingFile, settingsFile, joinsFile}
+
+// Snapshot is a read-only view of every storage of the index taken at the
+// same point, between writes of documents. Close releases the snapshot.
+type Snapshot struct {
+	// Storages are the names of the storages in directory order
+	Storages []string
+
+	// Files are the contents of the existing ConfigFiles by name
+	Files map[string][]byte
+
+	readers map[string]store.KVReader
+}
+
+// Reader returns the reader of the storage
+func (s *Snapshot) Reader(storage string) store.KVReader {
+	return s.readers[storage]
+}
+
+// Close releases the readers of the snapshot
+func (s *Snapshot) Close() {
+	for _, reader := range s.readers {
+		reader.Close()
+	}
+
+	s.readers = nil
+}
+
+// Storages returns the names of the storages of the index, the
+// directories of the index directory in name order.
+func (i *Index) Storages() ([]string, error) {
+	var storages []string
+
+	files, err := ioutil.ReadDir(i.dataDir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if file.IsDir() && store.ValidateDatabaseName(file.Name()) {
+			storages = append(storages, file.Name())
+		}
+	}
+
+	return storages, nil
+}
+
+// storageField returns the normalized field and the type of the field
+// storage <field>_<type>.<ext>, eg.: name_string.idx
+func storageField(storage string) (string, string, bool) {
+	name := strings.TrimSuffix(storage, filepath.Ext(storage))
+	sep := strings.LastIndex(name, "_")
+
+	if sep <= 0 {
+		return "", "", false
+	}
+
+	return name[:sep], name[sep+1:], true
+}
+
+// Snapshot opens a reader of every storage of the index while documents
+// aren't being added or deleted, then the snapshot never has a document
+// partially indexed.
+func (i *Index) Snapshot() (*Snapshot, error) {
+	i.writeMutex.Lock()
+	defer i.writeMutex.Unlock()
+
+	storages, err := i.Storages()
+
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{
+		Storages: storages,
+		Files:    make(map[string][]byte),
+		readers:  make(map[string]store.KVReader),
+	}
+
+	i.mappingMutex.Lock()
+	defer i.mappingMutex.Unlock()
+
+	for _, name := range ConfigFiles {
+		content, err := ioutil.ReadFile(i.dataDir + "/" + name)
+
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		snapshot.Files[name] = content
+	}
+
+	for _, storage := range storages {
+		storekv, err := i.engine.GetStore(i.Name, storage)
+
+		if err != nil {
+			snapshot.Close()
+			return nil, err
+		}
+
+		snapshot.readers[storage] = storekv.Reader()
+	}
+
+	return snapshot, nil
+}
@@ -0,0 +1,275 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/store"
+)
+
+// statsTopPostings is the number of largest posting lists of Stats
+const statsTopPostings = 10
+
+// Stats describes the content and size of an index. Keys, terms and
+// postings are counted only by exact stats.
+type Stats struct {
+	Index string `json:"index"`
+
+	// Documents is the document count kept by Add and Delete, or the
+	// documents of document.db counted by exact stats
+	Documents uint64 `json:"documents"`
+	Exact     bool   `json:"exact"`
+
+	// Size is the size on disk of the index directory, in bytes
+	Size int64 `json:"size"`
+
+	Storages []StorageStats `json:"storages"`
+	Fields   []FieldStats   `json:"fields"`
+
+	// TopPostings are the largest posting lists of the index fields
+	TopPostings []PostingStats `json:"top_postings,omitempty"`
+}
+
+// StorageStats describes a storage of the index. Size is the size of the
+// storage files on disk, compressed and including deleted entries not yet
+// compacted, and ApproximateSize is the size of its tables estimated by
+// the store. KeysSize and ValuesSize are the sums of the lengths of keys
+// and values.
+type StorageStats struct {
+	Name            string `json:"name"`
+	Size            int64  `json:"size"`
+	ApproximateSize uint64 `json:"approximate_size,omitempty"`
+	Keys            uint64 `json:"keys,omitempty"`
+	KeysSize        uint64 `json:"keys_size,omitempty"`
+	ValuesSize      uint64 `json:"values_size,omitempty"`
+}
+
+// FieldStats describes an inverted storage (<field>_<type>.idx) of the
+// index. Postings is the number of ids of all terms.
+type FieldStats struct {
+	Field    string `json:"field"`
+	Type     string `json:"type"`
+	Storage  string `json:"storage"`
+	Terms    uint64 `json:"terms,omitempty"`
+	Postings uint64 `json:"postings,omitempty"`
+}
+
+// PostingStats is the number of documents of a term
+type PostingStats struct {
+	Field string      `json:"field"`
+	Term  interface{} `json:"term"`
+	Docs  uint64      `json:"docs"`
+}
+
+// Stats returns the statistics of the index. By default the storages
+// aren't read: the documents are the count kept by Add and Delete and the
+// sizes are estimated by the stores with the store.KVSizer capability.
+// Exact stats count the documents, keys, terms and postings from a
+// snapshot of every storage, then they're as expensive as a scan of the
+// index.
+func (i *Index) Stats(exact bool) (*Stats, error) {
+	if exact {
+		return i.exactStats()
+	}
+
+	storages, err := i.Storages()
+
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{Index: i.Name}
+
+	if stats.Documents, err = i.documentCount(); err != nil {
+		return nil, err
+	}
+
+	if stats.Size, err = dirSize(i.dataDir); err != nil {
+		return nil, err
+	}
+
+	for _, storage := range storages {
+		storageStats, err := i.storageStats(storage)
+
+		if err != nil {
+			return nil, err
+		}
+
+		stats.Storages = append(stats.Storages, storageStats)
+
+		if field := newFieldStats(storage); field != nil {
+			stats.Fields = append(stats.Fields, *field)
+		}
+	}
+
+	return stats, nil
+}
+
+// exactStats returns the statistics of the index counted from a snapshot
+// of every storage
+func (i *Index) exactStats() (*Stats, error) {
+	snapshot, err := i.Snapshot()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer snapshot.Close()
+
+	stats := &Stats{Index: i.Name, Exact: true}
+
+	if stats.Size, err = dirSize(i.dataDir); err != nil {
+		return nil, err
+	}
+
+	for _, storage := range snapshot.Storages {
+		storageStats, err := i.storageStats(storage)
+
+		if err != nil {
+			return nil, err
+		}
+
+		field := newFieldStats(storage)
+		it := snapshot.Reader(storage).GetIterator()
+
+		for it.SeekToFirst(); it.Valid(); it.Next() {
+			key, value := it.Key(), it.Value()
+
+			storageStats.Keys++
+			storageStats.KeysSize += uint64(len(key))
+			storageStats.ValuesSize += uint64(len(value))
+
+			if storage == dbName && len(key) == 8 {
+				stats.Documents++
+			}
+
+			if field == nil || len(value) == 0 {
+				continue
+			}
+
+			docs := uint64(len(value) / 8)
+
+			field.Terms++
+			field.Postings += docs
+
+			if len(stats.TopPostings) == statsTopPostings &&
+				docs <= stats.TopPostings[statsTopPostings-1].Docs {
+				continue
+			}
+
+			term, err := engine.Decode(key, engine.StorageKeyType(storage), false)
+
+			if err != nil {
+				// corrupt keys are reported by Fsck
+				continue
+			}
+
+			stats.TopPostings = addTopPosting(stats.TopPostings, PostingStats{
+				Field: field.Field,
+				Term:  term,
+				Docs:  docs,
+			})
+		}
+
+		err = it.GetError()
+		it.Close()
+
+		if err != nil {
+			return nil, err
+		}
+
+		stats.Storages = append(stats.Storages, storageStats)
+
+		if field != nil {
+			stats.Fields = append(stats.Fields, *field)
+		}
+	}
+
+	return stats, nil
+}
+
+// storageStats returns the sizes of storage, estimated by stores with the
+// store.KVSizer capability
+func (i *Index) storageStats(storage string) (StorageStats, error) {
+	var err error
+
+	stats := StorageStats{Name: storage}
+
+	if stats.Size, err = dirSize(filepath.Join(i.dataDir, storage)); err != nil {
+		return stats, err
+	}
+
+	storekv, err := i.engine.GetStore(i.Name, storage)
+
+	if err != nil {
+		return stats, err
+	}
+
+	if sizer, ok := storekv.(store.KVSizer); ok {
+		if stats.ApproximateSize, err = sizer.ApproximateSize(nil, nil); err != nil {
+			return stats, err
+		}
+	}
+
+	return stats, nil
+}
+
+// newFieldStats returns the stats of the inverted storage of a field, nil
+// for the other storages
+func newFieldStats(storage string) *FieldStats {
+	name, fieldType, ok := storageField(storage)
+
+	if !ok || !strings.HasSuffix(storage, ".idx") {
+		return nil
+	}
+
+	return &FieldStats{
+		Field:   name,
+		Type:    fieldType,
+		Storage: storage,
+	}
+}
+
+// addTopPosting inserts posting in top, sorted by number of documents,
+// keeping at most statsTopPostings postings
+func addTopPosting(top []PostingStats, posting PostingStats) []PostingStats {
+	pos := len(top)
+
+	for pos > 0 && top[pos-1].Docs < posting.Docs {
+		pos--
+	}
+
+	top = append(top, PostingStats{})
+	copy(top[pos+1:], top[pos:])
+	top[pos] = posting
+
+	if len(top) > statsTopPostings {
+		top = top[:statsTopPostings]
+	}
+
+	return top
+}
+
+// dirSize returns the sum of the sizes of the files of dir
+func dirSize(dir string) (int64, error) {
+	var size int64
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			// removed by a compaction
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return size, err
+}
@@ -0,0 +1,227 @@
+package index
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/engine"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
+)
+
+// DefaultTermsSize is the number of terms returned by Terms when the
+// size isn't given
+const DefaultTermsSize = 100
+
+// TermsOptions filters and pages the terms returned by Terms. The filters
+// match the indexed terms, then they aren't analyzed.
+type TermsOptions struct {
+	// Prefix and Regexp filter the terms of string and keyword fields
+	Prefix string
+	Regexp string
+
+	// From and To are the inclusive bounds of the terms, converted to
+	// the type of the field like the terms of TermDocIDs. Bounds of date
+	// fields are dates in the format of the field or unix nanoseconds.
+	From interface{}
+	To   interface{}
+
+	// After is the last term of the previous page
+	After interface{}
+
+	// Size is the maximum number of terms returned
+	Size int
+}
+
+// TermFreq is a term and the number of documents having it
+type TermFreq struct {
+	Term interface{} `json:"term"`
+	Docs uint64      `json:"docs"`
+}
+
+// Terms returns the terms of field matching opts with their document
+// frequencies, in storage order like WalkPostings. Strings are in byte
+// order and the storage of int, date and float fields only orders the
+// non-negative terms, then ranges of numbers are filtered by a scan of
+// the field terms.
+func (i *Index) Terms(field string, opts TermsOptions) ([]TermFreq, error) {
+	var (
+		terms    []TermFreq
+		re       *regexp.Regexp
+		from, to interface{}
+		after    []byte
+	)
+
+	storage, keyType, err := i.invertedStorage(field)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if (opts.Prefix != "" || opts.Regexp != "") && keyType != engine.TypeString {
+		return nil, fmt.Errorf("Field '%s' of type %s has no string terms", field, i.FieldType(field))
+	}
+
+	if opts.Regexp != "" {
+		if re, err = regexp.Compile(opts.Regexp); err != nil {
+			return nil, err
+		}
+	}
+
+	if from, err = i.termBound(field, opts.From, keyType); err != nil {
+		return nil, err
+	}
+
+	if to, err = i.termBound(field, opts.To, keyType); err != nil {
+		return nil, err
+	}
+
+	if opts.After != nil {
+		var ok bool
+
+		if after, ok = encodeTerm(opts.After, keyType); !ok {
+			return nil, fmt.Errorf("Invalid term '%v' of field '%s'", opts.After, field)
+		}
+	}
+
+	size := opts.Size
+
+	if size <= 0 {
+		size = DefaultTermsSize
+	}
+
+	// strings are sought from the greatest lower bound
+	seek := []byte(opts.Prefix)
+
+	if from, ok := from.(string); ok && from > opts.Prefix {
+		seek = []byte(from)
+	}
+
+	if bytes.Compare(after, seek) > 0 {
+		seek = after
+	}
+
+	storekv, err := i.engine.GetStore(i.Name, storage)
+
+	if err != nil {
+		return nil, err
+	}
+
+	reader := storekv.Reader()
+	it := reader.GetIterator()
+
+	defer func() {
+		it.Close()
+		reader.Close()
+	}()
+
+	if len(seek) > 0 {
+		it.Seek(seek)
+	} else {
+		it.SeekToFirst()
+	}
+
+	for ; it.Valid() && len(terms) < size; it.Next() {
+		key, value := it.Key(), it.Value()
+
+		if len(value) == 0 || bytes.Equal(key, after) {
+			continue
+		}
+
+		if !bytes.HasPrefix(key, []byte(opts.Prefix)) {
+			break
+		}
+
+		term, err := engine.Decode(key, keyType, false)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if to != nil && compareTerms(term, to) > 0 {
+			if keyType == engine.TypeString {
+				break
+			}
+
+			continue
+		}
+
+		if (from != nil && compareTerms(term, from) < 0) ||
+			(re != nil && !re.MatchString(term.(string))) {
+			continue
+		}
+
+		terms = append(terms, TermFreq{
+			Term: term,
+			Docs: uint64(len(value) / 8),
+		})
+	}
+
+	return terms, it.GetError()
+}
+
+// termBound converts the bound of a range of terms of field to the type
+// of its keys
+func (i *Index) termBound(field string, bound interface{}, keyType uint8) (interface{}, error) {
+	if bound == nil {
+		return nil, nil
+	}
+
+	if value, ok := bound.(string); ok && i.FieldType(field) == "date" {
+		if t, err := parseDate(value, i.fieldMapping(utils.FieldNorm(field))); err == nil {
+			bound = t.UnixNano()
+		}
+	}
+
+	key, ok := encodeTerm(bound, keyType)
+
+	if !ok {
+		return nil, fmt.Errorf("Invalid term '%v' of field '%s'", bound, field)
+	}
+
+	return engine.Decode(key, keyType, false)
+}
+
+// compareTerms compares two decoded terms of the same field
+func compareTerms(a, b interface{}) int {
+	switch va := a.(type) {
+	case float64:
+		vb, _ := b.(float64)
+
+		if va < vb {
+			return -1
+		} else if va > vb {
+			return 1
+		}
+	case int64:
+		vb, _ := b.(int64)
+
+		if va < vb {
+			return -1
+		} else if va > vb {
+			return 1
+		}
+	case uint64:
+		vb, _ := b.(uint64)
+
+		if va < vb {
+			return -1
+		} else if va > vb {
+			return 1
+		}
+	case string:
+		vb, _ := b.(string)
+		return strings.Compare(va, vb)
+	case bool:
+		vb, _ := b.(bool)
+
+		if !va && vb {
+			return -1
+		} else if va && !vb {
+			return 1
+		}
+	}
+
+	return 0
+}
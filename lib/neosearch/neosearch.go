@@ -68,6 +68,13 @@
 // NeoSearch supports the features below:
 //
 //   - Create/Delete index
+//   - Index aliases
+//   - Reindex
+//   - Backup and restore
+//   - Consistency check and repair
+//   - Index statistics
+//   - Term dictionary browsing
+//   - Storage compaction
 //   - Index JSON documents (No schema)
 //   - Bulk writes
 //   - Analysers
@@ -75,6 +82,9 @@
 //   - Search
 //     - MatchPrefix
 //     - FilterTerm
+//     - Joins between indices ($join clauses and materialized joins)
+//     - Multi-index search with index patterns (eg.: companies-*)
+//     - Multi-hop traversals across indices
 //
 // This project is in active development stage, it is not recommended for
 // production environments.
@@ -85,6 +95,7 @@ import (
 	"expvar"
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/NeowayLabs/neosearch/lib/neosearch/cache"
 	"github.com/NeowayLabs/neosearch/lib/neosearch/config"
@@ -101,6 +112,10 @@ var (
 type NeoSearch struct {
 	indices cache.Cache
 	config  *config.Config
+
+	// aliases are the indices of each alias, loaded on first use
+	aliases      map[string][]string
+	aliasesMutex sync.Mutex
 }
 
 // New creates the NeoSearch high-level interface.
@@ -141,32 +156,51 @@ func (neo *NeoSearch) GetIndices() cache.Cache {
 
 // CreateIndex creates and setup a new index
 func (neo *NeoSearch) CreateIndex(name string) (*index.Index, error) {
+	if indices, err := neo.resolveAlias(name); err != nil {
+		return nil, err
+	} else if indices != nil {
+		return nil, fmt.Errorf("Index name '%s' is an alias", name)
+	}
+
 	indx, err := index.New(name, neo.config, true)
 	if err != nil {
 		return nil, err
 	}
 
+	indx.SetIndexOpener(neo.OpenIndex)
+
 	neo.indices.Add(name, indx)
 	cachedIndices.Set(int64(neo.indices.Len()))
 	return indx, nil
 }
 
-// DeleteIndex does exactly what the name says.
+// DeleteIndex does exactly what the name says. The index is removed from
+// its aliases.
 func (neo *NeoSearch) DeleteIndex(name string) error {
+	if indices, err := neo.resolveAlias(name); err != nil {
+		return err
+	} else if indices != nil {
+		return fmt.Errorf("'%s' is an alias. Aliases are removed with UpdateAliases", name)
+	}
+
 	// closes the index on remove
 	neo.indices.Remove(name)
 	idxLen := neo.indices.Len()
 	cachedIndices.Set(int64(idxLen))
 
-	if exists, err := neo.IndexExists(name); exists == true && err == nil {
-		err := os.RemoveAll(neo.config.DataDir + "/" + name)
-		return err
+	if exists, err := neo.indexDirExists(name); exists == true && err == nil {
+		if err = os.RemoveAll(neo.config.DataDir + "/" + name); err != nil {
+			return err
+		}
+
+		return neo.removeIndexAliases(name)
 	}
 
 	return errors.New("Index '" + name + "' not found.")
 }
 
-// OpenIndex open a existing index for read/write operations.
+// OpenIndex open a existing index for read/write operations. Aliases are
+// opened as their index, then aliases of several indices fail.
 func (neo *NeoSearch) OpenIndex(name string) (*index.Index, error) {
 	var (
 		ok         bool
@@ -175,6 +209,14 @@ func (neo *NeoSearch) OpenIndex(name string) (*index.Index, error) {
 		err        error
 	)
 
+	if indices, err := neo.resolveAlias(name); err != nil {
+		return nil, err
+	} else if len(indices) > 1 {
+		return nil, fmt.Errorf("Alias '%s' points to several indices: %v", name, indices)
+	} else if len(indices) == 1 {
+		name = indices[0]
+	}
+
 	cacheIndex, ok = neo.indices.Get(name)
 
 	if ok && cacheIndex != nil {
@@ -187,7 +229,7 @@ func (neo *NeoSearch) OpenIndex(name string) (*index.Index, error) {
 		return indx, nil
 	}
 
-	ok, err = neo.IndexExists(name)
+	ok, err = neo.indexDirExists(name)
 
 	if err == nil && !ok {
 		return nil, fmt.Errorf("Index '%s' not found in directory '%s'.", name, neo.config.DataDir)
@@ -200,13 +242,44 @@ func (neo *NeoSearch) OpenIndex(name string) (*index.Index, error) {
 		return nil, err
 	}
 
+	indx.SetIndexOpener(neo.OpenIndex)
+
 	neo.indices.Add(name, indx)
 	cachedIndices.Set(int64(neo.indices.Len()))
 	return indx, nil
 }
 
-// IndexExists verifies if the directory of the index given by name exists
+// CreateJoin declares a materialized join between the field of index and
+// the foreignField of foreignIndex. The join is kept updated when
+// documents are added to or deleted from either index, and it's used by
+// $join clauses between these fields.
+func (neo *NeoSearch) CreateJoin(name, indexName, field, foreignIndex, foreignField string) error {
+	a, err := neo.OpenIndex(indexName)
+
+	if err != nil {
+		return err
+	}
+
+	b, err := neo.OpenIndex(foreignIndex)
+
+	if err != nil {
+		return err
+	}
+
+	return index.CreateJoin(name, a, field, b, foreignField)
+}
+
+// IndexExists verifies if the directory of the index given by name
+// exists, or if name is an alias.
 func (neo *NeoSearch) IndexExists(name string) (bool, error) {
+	if indices, err := neo.resolveAlias(name); err != nil || indices != nil {
+		return indices != nil, err
+	}
+
+	return neo.indexDirExists(name)
+}
+
+func (neo *NeoSearch) indexDirExists(name string) (bool, error) {
 	indexPath := neo.config.DataDir + "/" + name
 	_, err := os.Stat(indexPath)
 
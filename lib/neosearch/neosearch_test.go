@@ -1,15 +1,18 @@
 package neosearch
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/NeowayLabs/neosearch/lib/neosearch/config"
 	"github.com/NeowayLabs/neosearch/lib/neosearch/index"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/search"
 )
 
 var DataDirTmp string
@@ -165,6 +168,430 @@ func TestDeleteIndex(t *testing.T) {
 	}
 }
 
+func TestResolveIndices(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.Option(config.DataDir(DataDirTmp))
+
+	neo := New(cfg)
+
+	defer func() {
+		for _, name := range []string{"resolve-a", "resolve-b", "other-resolve"} {
+			neo.DeleteIndex(name)
+		}
+
+		neo.Close()
+	}()
+
+	for _, name := range []string{"resolve-a", "resolve-b", "other-resolve"} {
+		if _, err := neo.CreateIndex(name); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	for pattern, expected := range map[string][]string{
+		"resolve-a":                 {"resolve-a"},
+		"resolve-*":                 {"resolve-a", "resolve-b"},
+		"resolve-b, other-resolve":  {"other-resolve", "resolve-b"},
+		"resolve-a,resolve-*,nope*": {"resolve-a", "resolve-b"},
+	} {
+		names, err := neo.ResolveIndices(pattern)
+
+		if err != nil || !reflect.DeepEqual(names, expected) {
+			t.Errorf("Unexpected indices of '%s': %v, %v", pattern, names, err)
+		}
+	}
+
+	for _, pattern := range []string{"missing", "resolve-a,missing", "nope*", "", "resolve-[", "../resolve-a"} {
+		if names, err := neo.ResolveIndices(pattern); err == nil {
+			t.Errorf("Pattern '%s' should fail: %v", pattern, names)
+		}
+	}
+}
+
+func TestAliases(t *testing.T) {
+	var (
+		aliases map[string][]string
+		indx    *index.Index
+		err     error
+	)
+
+	cfg := config.NewConfig()
+	cfg.Option(config.DataDir(DataDirTmp))
+
+	neo := New(cfg)
+
+	for _, name := range []string{"alias-v1", "alias-v2"} {
+		if _, err = neo.CreateIndex(name); err != nil {
+			t.Error(err)
+			goto cleanup
+		}
+	}
+
+	err = neo.UpdateAliases([]AliasAction{
+		{Action: AliasAdd, Index: "alias-v1", Alias: "companies"},
+		{Action: AliasAdd, Index: "alias-v1", Alias: "all-companies"},
+		{Action: AliasAdd, Index: "alias-v2", Alias: "all-companies"},
+	})
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if indx, err = neo.OpenIndex("companies"); err != nil || indx.Name != "alias-v1" {
+		t.Errorf("Unexpected index of alias: %v, %v", indx, err)
+		goto cleanup
+	}
+
+	if _, err = neo.OpenIndex("all-companies"); err == nil {
+		t.Error("Aliases of several indices can't be opened")
+		goto cleanup
+	}
+
+	// the swap is atomic: the invalid action discards the first one
+	err = neo.UpdateAliases([]AliasAction{
+		{Action: AliasRemove, Index: "alias-v1", Alias: "companies"},
+		{Action: AliasAdd, Index: "missing", Alias: "companies"},
+	})
+
+	if err == nil {
+		t.Error("Aliases of missing indices should fail")
+		goto cleanup
+	}
+
+	err = neo.UpdateAliases([]AliasAction{
+		{Action: AliasRemove, Index: "alias-v1", Alias: "companies"},
+		{Action: AliasAdd, Index: "alias-v2", Alias: "companies"},
+	})
+
+	if err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	// aliases are persisted
+	neo.Close()
+	neo = New(cfg)
+
+	if indx, err = neo.OpenIndex("companies"); err != nil || indx.Name != "alias-v2" {
+		t.Errorf("Unexpected index of alias: %v, %v", indx, err)
+		goto cleanup
+	}
+
+	if _, err = neo.CreateIndex("companies"); err == nil {
+		t.Error("Indices can't have the name of an alias")
+		goto cleanup
+	}
+
+	if err = neo.DeleteIndex("alias-v2"); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	aliases, err = neo.Aliases()
+
+	if err != nil || !reflect.DeepEqual(aliases, map[string][]string{"all-companies": {"alias-v1"}}) {
+		t.Errorf("Unexpected aliases: %v, %v", aliases, err)
+	}
+
+cleanup:
+	neo.DeleteIndex("alias-v1")
+	neo.DeleteIndex("alias-v2")
+	neo.Close()
+}
+
+func TestReindex(t *testing.T) {
+	var (
+		source   *index.Index
+		dest     *index.Index
+		progress ReindexProgress
+		reports  []ReindexProgress
+		doc      []byte
+		docIDs   []uint64
+		cancel   = make(chan struct{})
+		err      error
+	)
+
+	cfg := config.NewConfig()
+	cfg.Option(config.DataDir(DataDirTmp))
+
+	neo := New(cfg)
+
+	if source, err = neo.CreateIndex("reindex-src"); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	for id, doc := range []string{
+		`{"kind": "company", "name": "Neoway", "code": "AB-1"}`,
+		`{"kind": "person", "name": "Alice", "code": "CD-2"}`,
+		`{"kind": "company", "name": "Google", "code": "EF-3"}`,
+		`{"kind": "company", "name": "Facebook", "code": "GH-4", "tmp": true}`,
+	} {
+		if err = source.Add(uint64(id), []byte(doc), nil); err != nil {
+			t.Error(err)
+			goto cleanup
+		}
+	}
+
+	progress, err = neo.Reindex("reindex-src", "reindex-dst", ReindexOptions{
+		Query:     search.DSL{"$and": []interface{}{map[string]interface{}{"kind": "company"}}},
+		Mapping:   index.Metadata{"code": index.Metadata{"type": "keyword", "normalizer": "lowercase"}},
+		Transform: FieldTransform{Remove: []string{"tmp"}, Rename: map[string]string{"name": "title"}}.Apply,
+		BatchSize: 2,
+		Progress: func(progress ReindexProgress) {
+			reports = append(reports, progress)
+		},
+	})
+
+	if err != nil || progress != (ReindexProgress{Total: 4, Processed: 4, Indexed: 3, Skipped: 1}) {
+		t.Errorf("Unexpected reindex progress: %+v, %v", progress, err)
+		goto cleanup
+	}
+
+	if len(reports) != 2 || reports[0].Processed != 2 {
+		t.Errorf("Unexpected progress reports: %+v", reports)
+		goto cleanup
+	}
+
+	if dest, err = neo.OpenIndex("reindex-dst"); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if doc, err = dest.Get(3); err != nil || string(doc) != `{"code":"GH-4","kind":"company","title":"Facebook"}` {
+		t.Errorf("Unexpected reindexed document: %s, %v", doc, err)
+		goto cleanup
+	}
+
+	// the new mapping is used by the destination
+	if docIDs, err = dest.TermDocIDs("code", "gh-4"); err != nil || !reflect.DeepEqual(docIDs, []uint64{3}) {
+		t.Errorf("Unexpected documents of the keyword: %v, %v", docIDs, err)
+		goto cleanup
+	}
+
+	if _, err = neo.Reindex("reindex-src", "reindex-src", ReindexOptions{}); err == nil {
+		t.Error("Reindex into the source index should fail")
+		goto cleanup
+	}
+
+	close(cancel)
+
+	progress, err = neo.Reindex("reindex-src", "reindex-canceled", ReindexOptions{BatchSize: 1, Cancel: cancel})
+
+	if err != ErrReindexCanceled || progress.Indexed != 1 {
+		t.Errorf("Unexpected canceled reindex: %+v, %v", progress, err)
+	}
+
+cleanup:
+	for _, name := range []string{"reindex-src", "reindex-dst", "reindex-canceled"} {
+		neo.DeleteIndex(name)
+	}
+
+	neo.Close()
+}
+
+func TestBackup(t *testing.T) {
+	var (
+		source   *index.Index
+		dest     *index.Index
+		manifest *BackupManifest
+		backup   bytes.Buffer
+		doc      []byte
+		docIDs   []uint64
+		allIDs   []uint64
+		files    []os.FileInfo
+		done     = make(chan struct{})
+		err      error
+	)
+
+	cfg := config.NewConfig()
+	cfg.Option(config.DataDir(DataDirTmp))
+
+	neo := New(cfg)
+
+	if source, err = neo.CreateIndex("backup-src"); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if err = source.SetSettings(index.Settings{Similarity: index.SimilarityBM25}); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if err = source.Add(1, []byte(`{"kind": "company", "name": "Neoway"}`), nil); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	// documents added during the backup are entirely in the backup or
+	// not in the backup at all
+	go func() {
+		for id := uint64(2); id < 200; id++ {
+			source.Add(id, []byte(`{"kind": "company", "name": "Company"}`), nil)
+		}
+
+		close(done)
+	}()
+
+	manifest, err = neo.Backup("backup-src", &backup)
+	<-done
+
+	if err != nil || manifest.Index != "backup-src" || len(manifest.Storages) == 0 || manifest.Storages[0].Name != "document.db" {
+		t.Errorf("Unexpected backup manifest: %+v, %v", manifest, err)
+		goto cleanup
+	}
+
+	if manifest, err = neo.Restore("backup-dst", bytes.NewReader(backup.Bytes())); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if dest, err = neo.OpenIndex("backup-dst"); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if doc, err = dest.Get(1); err != nil || string(doc) != `{"kind": "company", "name": "Neoway"}` {
+		t.Errorf("Unexpected restored document: %s, %v", doc, err)
+		goto cleanup
+	}
+
+	docIDs, _, err = dest.FilterTermID([]byte("kind"), []byte("company"), 0)
+
+	if err == nil {
+		allIDs, err = dest.DocIDs()
+	}
+
+	if err != nil || !reflect.DeepEqual(docIDs, allIDs) || uint64(len(allIDs)) != manifest.Storages[0].Keys {
+		t.Errorf("Inconsistent restored index: %v != %v, %v", docIDs, allIDs, err)
+		goto cleanup
+	}
+
+	if dest.Settings().Similarity != index.SimilarityBM25 {
+		t.Errorf("Unexpected restored settings: %+v", dest.Settings())
+		goto cleanup
+	}
+
+	if _, err = neo.Restore("backup-dst", bytes.NewReader(backup.Bytes())); err == nil {
+		t.Error("Restore of existing index should fail")
+		goto cleanup
+	}
+
+	// corrupt a document of the backup
+	doc = backup.Bytes()
+	doc[bytes.Index(doc, []byte("Neoway"))] = 'X'
+
+	if _, err = neo.Restore("backup-bad", bytes.NewReader(doc)); err == nil {
+		t.Error("Restore of corrupted backup should fail")
+		goto cleanup
+	}
+
+	if files, err = ioutil.ReadDir(DataDirTmp); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	for _, file := range files {
+		if strings.HasPrefix(file.Name(), "_restore-") || file.Name() == "backup-bad" {
+			t.Errorf("Failed restores should be removed: %s", file.Name())
+		}
+	}
+
+cleanup:
+	for _, name := range []string{"backup-src", "backup-dst"} {
+		neo.DeleteIndex(name)
+	}
+
+	neo.Close()
+}
+
+func TestBackupJoins(t *testing.T) {
+	var (
+		companies *index.Index
+		partners  *index.Index
+		dest      *index.Index
+		backup    bytes.Buffer
+		err       error
+	)
+
+	cfg := config.NewConfig()
+	cfg.Option(config.DataDir(DataDirTmp))
+
+	neo := New(cfg)
+
+	if companies, err = neo.CreateIndex("backup-companies"); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if partners, err = neo.CreateIndex("backup-partners"); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	for _, ind := range []*index.Index{companies, partners} {
+		err = ind.SetMapping(index.Metadata{
+			"id":         index.Metadata{"type": "uint"},
+			"company_id": index.Metadata{"type": "uint"},
+		})
+
+		if err != nil {
+			t.Error(err)
+			goto cleanup
+		}
+	}
+
+	if err = companies.Add(1, []byte(`{"id": 1, "name": "Neoway"}`), nil); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if err = neo.CreateJoin("partners", "backup-companies", "id", "backup-partners", "company_id"); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if _, err = neo.Backup("backup-companies", &backup); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if _, err = neo.Restore("backup-companies-copy", bytes.NewReader(backup.Bytes())); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	if dest, err = neo.OpenIndex("backup-companies-copy"); err != nil {
+		t.Error(err)
+		goto cleanup
+	}
+
+	// the partners index doesn't join the restored index
+	if joins := dest.Joins(); len(joins) != 0 {
+		t.Errorf("Joins of the index restored under another name should be dropped: %+v", joins)
+		goto cleanup
+	}
+
+	if err = dest.Add(2, []byte(`{"id": 2, "name": "Google"}`), nil); err != nil {
+		t.Errorf("Add to the restored index failed: %s", err)
+		goto cleanup
+	}
+
+	if _, err = os.Stat(DataDirTmp + "/backup-companies-copy/partners.join"); !os.IsNotExist(err) {
+		t.Errorf("Join storages of the restored index should be dropped: %v", err)
+	}
+
+cleanup:
+	for _, name := range []string{"backup-companies", "backup-partners", "backup-companies-copy"} {
+		neo.DeleteIndex(name)
+	}
+
+	neo.Close()
+}
+
 func TestAddDocument(t *testing.T) {
 	var (
 		data       []byte
@@ -0,0 +1,252 @@
+package neosearch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/index"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/search"
+)
+
+// DefaultReindexBatchSize is the number of documents indexed between
+// progress reports and cancellation checks
+const DefaultReindexBatchSize = 1000
+
+// ErrReindexCanceled is returned by Reindex when ReindexOptions.Cancel is
+// closed before every document is indexed
+var ErrReindexCanceled = errors.New("Reindex canceled")
+
+// ReindexOptions are the options of Reindex
+type ReindexOptions struct {
+	// Query selects the documents of the source index. Every document is
+	// reindexed without query.
+	Query search.DSL
+
+	// Transform changes the documents before they're indexed. Documents
+	// transformed to nil are skipped.
+	Transform func(id uint64, doc map[string]interface{}) (map[string]interface{}, error)
+
+	// Settings and Mapping of the destination index when it's created.
+	// The mapping of an existing destination is merged with Mapping.
+	Settings *index.Settings
+	Mapping  index.Metadata
+
+	// BatchSize is the number of documents indexed between progress
+	// reports and cancellation checks. Defaults to
+	// DefaultReindexBatchSize.
+	BatchSize int
+
+	// Progress is called after every batch
+	Progress func(progress ReindexProgress)
+
+	// Cancel stops the reindex when closed
+	Cancel <-chan struct{}
+}
+
+// ReindexProgress counts the documents of a reindex
+type ReindexProgress struct {
+	// Total is the number of documents of the source index
+	Total uint64 `json:"total"`
+
+	// Processed are the documents read from the source index
+	Processed uint64 `json:"processed"`
+
+	// Indexed are the documents added to the destination index
+	Indexed uint64 `json:"indexed"`
+
+	// Skipped are the documents not matched by the query or transformed
+	// to nil
+	Skipped uint64 `json:"skipped"`
+}
+
+// FieldTransform is a declarative ReindexOptions.Transform of the top
+// level fields of the documents, applied in the order: Remove, Rename,
+// Set.
+type FieldTransform struct {
+	Remove []string               `json:"remove"`
+	Rename map[string]string      `json:"rename"`
+	Set    map[string]interface{} `json:"set"`
+}
+
+// Apply transforms doc in place
+func (t FieldTransform) Apply(id uint64, doc map[string]interface{}) (map[string]interface{}, error) {
+	for _, field := range t.Remove {
+		delete(doc, field)
+	}
+
+	for from, to := range t.Rename {
+		if value, ok := doc[from]; ok {
+			delete(doc, from)
+			doc[to] = value
+		}
+	}
+
+	for field, value := range t.Set {
+		doc[field] = value
+	}
+
+	return doc, nil
+}
+
+// Reindex reads the documents of the index src, in id order, and adds them
+// with the same ids to the index dst, created when it doesn't exist.
+// Documents are filtered by opts.Query and changed by opts.Transform.
+// It returns the final progress, also when canceled or failed.
+func (neo *NeoSearch) Reindex(src, dst string, opts ReindexOptions) (ReindexProgress, error) {
+	var (
+		progress ReindexProgress
+		docIDs   []uint64
+		batch    int
+	)
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultReindexBatchSize
+	}
+
+	source, err := neo.OpenIndex(src)
+
+	if err != nil {
+		return progress, err
+	}
+
+	dest, err := neo.reindexDest(source, dst, opts)
+
+	if err != nil {
+		return progress, err
+	}
+
+	if opts.Query != nil {
+		if docIDs, err = search.MatchIDs(source, opts.Query, neo); err != nil {
+			return progress, err
+		}
+	}
+
+	allIDs, err := source.DocIDs()
+
+	if err != nil {
+		return progress, err
+	}
+
+	progress.Total = uint64(len(allIDs))
+
+	// the batch mode of the index isn't used because posting lists are
+	// merged with the stored ones, which doesn't see the batched writes
+	err = source.WalkDocs(func(id uint64, doc []byte) error {
+		if batch == opts.BatchSize {
+			batch = 0
+
+			if opts.Progress != nil {
+				opts.Progress(progress)
+			}
+
+			select {
+			case <-opts.Cancel:
+				return ErrReindexCanceled
+			default:
+			}
+		}
+
+		batch++
+		progress.Processed++
+
+		if opts.Query != nil {
+			pos := sort.Search(len(docIDs), func(i int) bool { return docIDs[i] >= id })
+
+			if pos == len(docIDs) || docIDs[pos] != id {
+				progress.Skipped++
+				return nil
+			}
+		}
+
+		if opts.Transform != nil {
+			var docObj map[string]interface{}
+
+			if err := json.Unmarshal(doc, &docObj); err != nil {
+				return fmt.Errorf("Invalid document %d: %s", id, err)
+			}
+
+			if docObj, err = opts.Transform(id, docObj); err != nil {
+				return fmt.Errorf("Transform of document %d failed: %s", id, err)
+			} else if docObj == nil {
+				progress.Skipped++
+				return nil
+			}
+
+			if doc, err = json.Marshal(docObj); err != nil {
+				return err
+			}
+		}
+
+		if err := dest.Add(id, doc, nil); err != nil {
+			return fmt.Errorf("Document %d: %s", id, err)
+		}
+
+		progress.Indexed++
+		return nil
+	})
+
+	if opts.Progress != nil {
+		opts.Progress(progress)
+	}
+
+	return progress, err
+}
+
+// reindexDest opens or creates the destination index of a reindex
+func (neo *NeoSearch) reindexDest(source *index.Index, name string, opts ReindexOptions) (*index.Index, error) {
+	if err := index.ValidateMapping(opts.Mapping); err != nil {
+		return nil, err
+	}
+
+	exists, err := neo.IndexExists(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if exists {
+		if opts.Settings != nil {
+			return nil, fmt.Errorf("Settings of the existing index '%s' can't be changed", name)
+		}
+
+		dest, err := neo.OpenIndex(name)
+
+		if err == nil && dest.Name == source.Name {
+			return nil, errors.New("Reindex source and destination are the same index")
+		}
+
+		if err == nil && len(opts.Mapping) > 0 {
+			err = dest.SetMapping(opts.Mapping)
+		}
+
+		return dest, err
+	}
+
+	if opts.Settings != nil {
+		if err = opts.Settings.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	dest, err := neo.CreateIndex(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Settings != nil {
+		if err = dest.SetSettings(*opts.Settings); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(opts.Mapping) > 0 {
+		if err = dest.SetMapping(opts.Mapping); err != nil {
+			return nil, err
+		}
+	}
+
+	return dest, nil
+}
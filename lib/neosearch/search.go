@@ -0,0 +1,134 @@
+package neosearch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/index"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/search"
+)
+
+// ResolveIndices returns the sorted names of the indices of pattern, a
+// comma separated list of index names, aliases and wildcards, eg.:
+// "companies-*,partners". Aliases are replaced by their indices. Names
+// must exist, but wildcards may match no index; patterns matching no
+// index at all fail.
+func (neo *NeoSearch) ResolveIndices(pattern string) ([]string, error) {
+	var (
+		names    []string
+		existing []string
+		seen     = make(map[string]bool)
+	)
+
+	aliases, err := neo.Aliases()
+
+	if err != nil {
+		return nil, err
+	}
+
+	add := func(name string) {
+		indices, ok := aliases[name]
+
+		if !ok {
+			indices = []string{name}
+		}
+
+		for _, name := range indices {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	for _, item := range strings.Split(pattern, ",") {
+		item = strings.TrimSpace(item)
+
+		if item == "" {
+			continue
+		}
+
+		if !strings.ContainsAny(item, "*?[") {
+			exists, err := neo.IndexExists(item)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if !exists || !index.ValidateIndexName(item) {
+				return nil, fmt.Errorf("Index '%s' doesn't exists.", item)
+			}
+
+			add(item)
+			continue
+		}
+
+		if existing == nil {
+			entries, err := ioutil.ReadDir(neo.config.DataDir)
+
+			if err != nil {
+				return nil, err
+			}
+
+			existing = make([]string, 0, len(entries)+len(aliases))
+
+			for _, entry := range entries {
+				if entry.IsDir() && index.ValidateIndexName(entry.Name()) {
+					existing = append(existing, entry.Name())
+				}
+			}
+
+			for alias := range aliases {
+				existing = append(existing, alias)
+			}
+		}
+
+		for _, name := range existing {
+			matched, err := path.Match(item, name)
+
+			if err != nil {
+				return nil, fmt.Errorf("Invalid index pattern '%s': %s", item, err)
+			}
+
+			if matched {
+				add(name)
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("No index matches '%s'", pattern)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// Search searches the indices of the pattern indices (see ResolveIndices)
+// with the query dsl. Indices are opened through the cache of open
+// indices, and their hits are merged by opts.Sort (see
+// search.SearchIndices).
+func (neo *NeoSearch) Search(indices string, dsl search.DSL, opts search.Options) (*search.Results, error) {
+	names, err := neo.ResolveIndices(indices)
+
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Indices = neo
+
+	if len(names) == 1 {
+		ind, err := neo.OpenIndex(names[0])
+
+		if err != nil {
+			return nil, err
+		}
+
+		return search.SearchResults(ind, dsl, opts)
+	}
+
+	return search.SearchIndices(names, dsl, opts)
+}
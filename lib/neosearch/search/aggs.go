@@ -0,0 +1,313 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/index"
+)
+
+// Aggregation summarizes the documents matched by a search
+type Aggregation interface {
+	// Aggregate computes the aggregation over the sorted docIDs of ind
+	Aggregate(ind *index.Index, docIDs []uint64) (AggregationResult, error)
+}
+
+// AggregationResult is the result of an aggregation over one index.
+// Results of the same aggregation over several indices are combined with
+// Merge.
+type AggregationResult interface {
+	Merge(other AggregationResult) AggregationResult
+
+	// Output returns the JSON representation of the result
+	Output() interface{}
+}
+
+// aggParsers are the parsers of the aggregation params by aggregation type
+var aggParsers = map[string]func(params map[string]interface{}) (Aggregation, error){
+	"terms":          parseTerms,
+	"stats":          parseStats,
+	MetricMin:        metricParser(MetricMin),
+	MetricMax:        metricParser(MetricMax),
+	MetricAvg:        metricParser(MetricAvg),
+	MetricSum:        metricParser(MetricSum),
+	MetricCount:      metricParser(MetricCount),
+	"histogram":      parseHistogram,
+	"date_histogram": parseDateHistogram,
+	"cardinality":    parseCardinality,
+}
+
+// ParseAggs parses the aggs option of the search request, eg.:
+//
+//	{"by_state": {"terms": {"field": "state", "size": 10}}}
+func ParseAggs(option interface{}) (map[string]Aggregation, error) {
+	if option == nil {
+		return nil, nil
+	}
+
+	aggsObj, ok := option.(map[string]interface{})
+
+	if !ok {
+		return nil, fmt.Errorf("Invalid aggs: %v", option)
+	}
+
+	aggs := make(map[string]Aggregation)
+
+	for name, value := range aggsObj {
+		aggObj, ok := value.(map[string]interface{})
+
+		if !ok || len(aggObj) != 1 {
+			return nil, fmt.Errorf("Invalid aggregation '%s': %v", name, value)
+		}
+
+		for aggType, params := range aggObj {
+			parse, ok := aggParsers[aggType]
+
+			if !ok {
+				return nil, fmt.Errorf("Unknown aggregation type '%s' of '%s'", aggType, name)
+			}
+
+			paramsObj, ok := params.(map[string]interface{})
+
+			if !ok {
+				return nil, fmt.Errorf("Invalid params of aggregation '%s': %v", name, params)
+			}
+
+			agg, err := parse(paramsObj)
+
+			if err != nil {
+				return nil, fmt.Errorf("Invalid aggregation '%s': %s", name, err)
+			}
+
+			aggs[name] = agg
+		}
+	}
+
+	return aggs, nil
+}
+
+// aggregate computes every aggregation of aggs over docIDs
+func aggregate(ind *index.Index, aggs map[string]Aggregation, docIDs []uint64) (map[string]AggregationResult, error) {
+	if len(aggs) == 0 {
+		return nil, nil
+	}
+
+	results := make(map[string]AggregationResult, len(aggs))
+
+	for name, agg := range aggs {
+		result, err := agg.Aggregate(ind, docIDs)
+
+		if err != nil {
+			return nil, fmt.Errorf("Aggregation '%s' failed: %s", name, err)
+		}
+
+		results[name] = result
+	}
+
+	return results, nil
+}
+
+// MergeAggs merges the aggregation results b into a
+func MergeAggs(a, b map[string]AggregationResult) map[string]AggregationResult {
+	if a == nil {
+		return b
+	}
+
+	for name, result := range b {
+		if current, ok := a[name]; ok {
+			a[name] = current.Merge(result)
+		} else {
+			a[name] = result
+		}
+	}
+
+	return a
+}
+
+// aggField returns the required "field" param of an aggregation
+func aggField(params map[string]interface{}) (string, error) {
+	field, ok := params["field"].(string)
+
+	if !ok || field == "" {
+		return "", fmt.Errorf("Param 'field' is required")
+	}
+
+	return field, nil
+}
+
+// aggUint returns the non-negative integer param name or def if absent
+func aggUint(params map[string]interface{}, name string, def uint64) (uint64, error) {
+	value, ok := params[name]
+
+	if !ok {
+		return def, nil
+	}
+
+	number, ok := value.(float64)
+
+	if !ok || number < 0 || number != float64(uint64(number)) {
+		return 0, fmt.Errorf("Param '%s' must be a non-negative integer", name)
+	}
+
+	return uint64(number), nil
+}
+
+// valuesField returns an error for analyzed string fields. Their postings
+// are the tokens of the values, then they can't be aggregated by value.
+func valuesField(ind *index.Index, field string) error {
+	if ind.FieldType(field) == "string" {
+		return fmt.Errorf("Field '%s' is an analyzed string, aggregate a keyword field or sub-field instead, eg.: '%s.raw'",
+			field, field)
+	}
+
+	return nil
+}
+
+// intersectCount returns the size of the intersection of the ordered sets
+// a and b
+func intersectCount(a, b []uint64) uint64 {
+	var (
+		i, j  int
+		count uint64
+	)
+
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			count++
+			i++
+			j++
+		} else if a[i] < b[j] {
+			i++
+		} else {
+			j++
+		}
+	}
+
+	return count
+}
+
+// termsAgg counts the matched documents by distinct value of a field
+type termsAgg struct {
+	field string
+	size  uint64
+}
+
+const defaultTermsSize uint64 = 10
+
+func parseTerms(params map[string]interface{}) (Aggregation, error) {
+	field, err := aggField(params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := aggUint(params, "size", defaultTermsSize)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &termsAgg{field: field, size: size}, nil
+}
+
+// Aggregate walks the postings of the field and intersects each posting
+// list with the result set.
+func (agg *termsAgg) Aggregate(ind *index.Index, docIDs []uint64) (AggregationResult, error) {
+	if err := valuesField(ind, agg.field); err != nil {
+		return nil, err
+	}
+
+	result := &TermsResult{
+		Size:   agg.size,
+		Counts: make(map[interface{}]uint64),
+	}
+
+	if len(docIDs) == 0 {
+		return result, nil
+	}
+
+	err := ind.WalkPostings(agg.field, func(term interface{}, postings []uint64) error {
+		if count := intersectCount(docIDs, postings); count > 0 {
+			result.Counts[term] += count
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// TermsResult has the document count of every term of a terms
+// aggregation. Only the top Size buckets are in the output.
+type TermsResult struct {
+	Size   uint64
+	Counts map[interface{}]uint64
+}
+
+// Bucket is a term of a terms aggregation with its document count
+type Bucket struct {
+	Key      interface{} `json:"key"`
+	DocCount uint64      `json:"doc_count"`
+}
+
+// Merge sums the term counts of other into r
+func (r *TermsResult) Merge(other AggregationResult) AggregationResult {
+	if o, ok := other.(*TermsResult); ok {
+		for term, count := range o.Counts {
+			r.Counts[term] += count
+		}
+	}
+
+	return r
+}
+
+// Buckets returns the top Size buckets, by descending count and ascending
+// key, and the sum of the counts of the other buckets.
+func (r *TermsResult) Buckets() ([]Bucket, uint64) {
+	var other uint64
+
+	buckets := make([]Bucket, 0, len(r.Counts))
+
+	for term, count := range r.Counts {
+		buckets = append(buckets, Bucket{Key: term, DocCount: count})
+	}
+
+	sort.Sort(byCount(buckets))
+
+	if uint64(len(buckets)) > r.Size {
+		for _, bucket := range buckets[r.Size:] {
+			other += bucket.DocCount
+		}
+
+		buckets = buckets[:r.Size]
+	}
+
+	return buckets, other
+}
+
+type byCount []Bucket
+
+func (b byCount) Len() int      { return len(b) }
+func (b byCount) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byCount) Less(i, j int) bool {
+	if b[i].DocCount != b[j].DocCount {
+		return b[i].DocCount > b[j].DocCount
+	}
+
+	return compareValues(b[i].Key, b[j].Key) < 0
+}
+
+// Output returns {"buckets": [{"key": ..., "doc_count": ...}],
+// "sum_other_doc_count": ...}
+func (r *TermsResult) Output() interface{} {
+	buckets, other := r.Buckets()
+
+	return map[string]interface{}{
+		"buckets":             buckets,
+		"sum_other_doc_count": other,
+	}
+}
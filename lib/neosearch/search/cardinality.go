@@ -0,0 +1,109 @@
+package search
+
+import (
+	"fmt"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/hll"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/index"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
+)
+
+// cardinalityAgg estimates the number of distinct values of a field in
+// the matched documents with a HyperLogLog++ sketch.
+type cardinalityAgg struct {
+	field     string
+	precision uint8
+}
+
+func parseCardinality(params map[string]interface{}) (Aggregation, error) {
+	field, err := aggField(params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	precision, err := aggUint(params, "precision", uint64(hll.DefaultPrecision))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if precision < uint64(hll.MinPrecision) || precision > uint64(hll.MaxPrecision) {
+		return nil, fmt.Errorf("Param 'precision' must be between %d and %d",
+			hll.MinPrecision, hll.MaxPrecision)
+	}
+
+	return &cardinalityAgg{field: field, precision: uint8(precision)}, nil
+}
+
+// termBytes encodes the term of a posting to be hashed
+func termBytes(term interface{}) []byte {
+	switch v := term.(type) {
+	case string:
+		return []byte(v)
+	case int64:
+		return utils.Int64ToBytes(v)
+	case uint64:
+		return utils.Uint64ToBytes(v)
+	case float64:
+		return utils.Float64ToBytes(v)
+	case bool:
+		return utils.BoolToBytes(v)
+	}
+
+	return []byte(fmt.Sprint(term))
+}
+
+// Aggregate walks the postings of the field and adds to the sketch every
+// term having matched documents.
+func (agg *cardinalityAgg) Aggregate(ind *index.Index, docIDs []uint64) (AggregationResult, error) {
+	if err := valuesField(ind, agg.field); err != nil {
+		return nil, err
+	}
+
+	sketch, err := hll.New(agg.precision)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CardinalityResult{Sketch: sketch}
+
+	if len(docIDs) == 0 {
+		return result, nil
+	}
+
+	err = ind.WalkPostings(agg.field, func(term interface{}, postings []uint64) error {
+		if intersectCount(docIDs, postings) > 0 {
+			sketch.Add(termBytes(term))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// CardinalityResult is the sketch of the distinct values of a field
+type CardinalityResult struct {
+	Sketch *hll.Sketch
+}
+
+// Merge adds the values of the sketch of other to r
+func (r *CardinalityResult) Merge(other AggregationResult) AggregationResult {
+	if o, ok := other.(*CardinalityResult); ok {
+		// the sketches of an aggregation have the same precision
+		r.Sketch.Merge(o.Sketch)
+	}
+
+	return r
+}
+
+// Output returns {"value": <estimated distinct values>}
+func (r *CardinalityResult) Output() interface{} {
+	return map[string]interface{}{"value": r.Sketch.Count()}
+}
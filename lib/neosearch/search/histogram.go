@@ -0,0 +1,206 @@
+package search
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/index"
+)
+
+// Calendar intervals of date histograms
+const (
+	IntervalMinute  = "minute"
+	IntervalHour    = "hour"
+	IntervalDay     = "day"
+	IntervalWeek    = "week"
+	IntervalMonth   = "month"
+	IntervalQuarter = "quarter"
+	IntervalYear    = "year"
+)
+
+// histogramAgg counts the matched documents by fixed-size intervals of the
+// values of a numeric field, or by calendar intervals of a date field.
+type histogramAgg struct {
+	field string
+
+	// interval is the bucket size of numeric histograms
+	interval float64
+
+	// calendar is the calendar interval of date histograms
+	calendar string
+}
+
+func parseHistogram(params map[string]interface{}) (Aggregation, error) {
+	field, err := aggField(params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	interval, ok := params["interval"].(float64)
+
+	if !ok || interval <= 0 {
+		return nil, fmt.Errorf("Param 'interval' must be a positive number")
+	}
+
+	return &histogramAgg{field: field, interval: interval}, nil
+}
+
+func parseDateHistogram(params map[string]interface{}) (Aggregation, error) {
+	field, err := aggField(params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	interval, ok := params["calendar_interval"].(string)
+
+	if !ok {
+		interval, _ = params["interval"].(string)
+	}
+
+	switch interval {
+	case IntervalMinute, IntervalHour, IntervalDay, IntervalWeek,
+		IntervalMonth, IntervalQuarter, IntervalYear:
+	default:
+		return nil, fmt.Errorf("Invalid calendar interval '%s'", interval)
+	}
+
+	return &histogramAgg{field: field, calendar: interval}, nil
+}
+
+// calendarBucket returns the start of the calendar interval of t, in UTC
+func calendarBucket(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	year, month, day := t.Date()
+
+	switch interval {
+	case IntervalMinute:
+		return t.Truncate(time.Minute)
+	case IntervalHour:
+		return t.Truncate(time.Hour)
+	case IntervalWeek:
+		// weeks start on monday
+		day -= (int(t.Weekday()) + 6) % 7
+	case IntervalMonth:
+		day = 1
+	case IntervalQuarter:
+		month, day = ((month-1)/3)*3+1, 1
+	case IntervalYear:
+		month, day = time.January, 1
+	}
+
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// bucketKey returns the key of the bucket of the term: the lower bound of
+// the interval, or the start of the calendar interval in unix milliseconds.
+func (agg *histogramAgg) bucketKey(term interface{}) interface{} {
+	if agg.calendar != "" {
+		nsec, _ := term.(int64)
+		start := calendarBucket(time.Unix(0, nsec), agg.calendar)
+		return start.UnixNano() / int64(time.Millisecond)
+	}
+
+	return math.Floor(toFloat64(term)/agg.interval) * agg.interval
+}
+
+// Aggregate walks the postings of the field and unites the matched
+// documents of the terms of each bucket, so multi-valued documents are
+// counted once per bucket.
+func (agg *histogramAgg) Aggregate(ind *index.Index, docIDs []uint64) (AggregationResult, error) {
+	if agg.calendar != "" {
+		if fieldType := ind.FieldType(agg.field); fieldType != "date" {
+			return nil, fmt.Errorf("Field '%s' of type '%s' isn't date", agg.field, fieldType)
+		}
+	} else if err := numericField(ind, agg.field); err != nil {
+		return nil, err
+	}
+
+	result := &HistogramResult{
+		Date:   agg.calendar != "",
+		Counts: make(map[interface{}]uint64),
+	}
+
+	if len(docIDs) == 0 {
+		return result, nil
+	}
+
+	buckets := make(map[interface{}][]uint64)
+
+	err := ind.WalkPostings(agg.field, func(term interface{}, postings []uint64) error {
+		if matched := and(docIDs, postings); len(matched) > 0 {
+			key := agg.bucketKey(term)
+			buckets[key] = or(buckets[key], matched)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for key, ids := range buckets {
+		result.Counts[key] = uint64(len(ids))
+	}
+
+	return result, nil
+}
+
+// HistogramResult has the document count of each non-empty bucket of a
+// histogram. Keys are float64, or unix milliseconds (int64) for date
+// histograms.
+type HistogramResult struct {
+	Date   bool
+	Counts map[interface{}]uint64
+}
+
+// Merge sums the bucket counts of other into r
+func (r *HistogramResult) Merge(other AggregationResult) AggregationResult {
+	if o, ok := other.(*HistogramResult); ok {
+		for key, count := range o.Counts {
+			r.Counts[key] += count
+		}
+	}
+
+	return r
+}
+
+// Output returns {"buckets": [{"key": ..., "doc_count": ...}]} ordered by
+// key. Buckets of date histograms have a "key_as_string" in RFC3339.
+func (r *HistogramResult) Output() interface{} {
+	keys := make([]interface{}, 0, len(r.Counts))
+
+	for key := range r.Counts {
+		keys = append(keys, key)
+	}
+
+	sort.Sort(byKey(keys))
+
+	buckets := make([]map[string]interface{}, len(keys))
+
+	for idx, key := range keys {
+		bucket := map[string]interface{}{
+			"key":       key,
+			"doc_count": r.Counts[key],
+		}
+
+		if msec, ok := key.(int64); ok && r.Date {
+			start := time.Unix(0, msec*int64(time.Millisecond)).UTC()
+			bucket["key_as_string"] = start.Format(time.RFC3339)
+		}
+
+		buckets[idx] = bucket
+	}
+
+	return map[string]interface{}{"buckets": buckets}
+}
+
+type byKey []interface{}
+
+func (k byKey) Len() int           { return len(k) }
+func (k byKey) Swap(i, j int)      { k[i], k[j] = k[j], k[i] }
+func (k byKey) Less(i, j int) bool { return compareValues(k[i], k[j]) < 0 }
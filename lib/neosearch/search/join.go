@@ -0,0 +1,288 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/index"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
+)
+
+// JoinClause is the key of join clauses in the query DSL
+const JoinClause = "$join"
+
+// Join types
+const (
+	// JoinInner matches the documents related to the sub-query documents
+	// and returns the related documents with the hits
+	JoinInner = "inner"
+
+	// JoinSemi matches the documents related to the sub-query documents
+	JoinSemi = "semi"
+
+	// JoinAnti matches the documents not related to the sub-query
+	// documents
+	JoinAnti = "anti"
+)
+
+// IndexOpener opens indices by name, eg.: *neosearch.NeoSearch
+type IndexOpener interface {
+	OpenIndex(name string) (*index.Index, error)
+}
+
+// joinSpec is a join clause of the query DSL:
+//
+//	{"$join": {
+//		"index": "partners",
+//		"query": {"$and": [{"role": "ceo"}]},
+//		"foreign_field": "company_id",
+//		"field": "id",
+//		"type": "inner",
+//		"as": "ceos"
+//	}}
+//
+// The values of foreign_field in the documents of index matched by query
+// are searched as terms of field in the outer index. Without query every
+// document of index is used. Type defaults to inner and "as", the name of
+// the join in the hits, defaults to the index name.
+type joinSpec struct {
+	index        string
+	query        DSL
+	field        string
+	foreignField string
+	joinType     string
+	as           string
+}
+
+func parseJoin(value interface{}) (*joinSpec, error) {
+	obj, ok := value.(map[string]interface{})
+
+	if !ok {
+		return nil, fmt.Errorf("Invalid join clause: %v", value)
+	}
+
+	spec := &joinSpec{joinType: JoinInner}
+
+	for _, param := range []struct {
+		name     string
+		value    *string
+		required bool
+	}{
+		{"index", &spec.index, true},
+		{"field", &spec.field, true},
+		{"foreign_field", &spec.foreignField, true},
+		{"type", &spec.joinType, false},
+		{"as", &spec.as, false},
+	} {
+		v, ok := obj[param.name]
+
+		if !ok && !param.required {
+			continue
+		}
+
+		if *param.value, ok = v.(string); !ok || *param.value == "" {
+			return nil, fmt.Errorf("Invalid join clause. Param '%s' must be a non-empty string", param.name)
+		}
+	}
+
+	switch spec.joinType {
+	case JoinInner, JoinSemi, JoinAnti:
+	default:
+		return nil, fmt.Errorf("Invalid join type '%s'", spec.joinType)
+	}
+
+	if spec.as == "" {
+		spec.as = spec.index
+	}
+
+	if query, ok := obj["query"]; ok {
+		queryObj, ok := query.(map[string]interface{})
+
+		if !ok {
+			return nil, fmt.Errorf("Invalid join query: %v", query)
+		}
+
+		spec.query = DSL(queryObj)
+	}
+
+	return spec, nil
+}
+
+// innerJoin has the related documents of the foreign index by outer
+// document id
+type innerJoin struct {
+	foreign *index.Index
+	related map[uint64][]uint64
+}
+
+// join returns the documents of ind matched by the join clause value.
+// The related documents of inner joins are kept in m.joins.
+func (m *matches) join(ind *index.Index, value interface{}, indices IndexOpener) ([]uint64, error) {
+	var (
+		subDocIDs []uint64
+		docIDs    []uint64
+	)
+
+	spec, err := parseJoin(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if indices == nil {
+		return nil, errors.New("Join clauses aren't supported by this search")
+	}
+
+	foreign, err := indices.OpenIndex(spec.index)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if spec.query != nil {
+		sub, err := match(foreign, spec.query, indices)
+
+		if err != nil {
+			return nil, fmt.Errorf("Join query on index '%s' failed: %s", spec.index, err)
+		}
+
+		subDocIDs = sub.docIDs
+	} else if subDocIDs, err = foreign.DocIDs(); err != nil {
+		return nil, err
+	}
+
+	var related map[uint64][]uint64
+
+	if join, ok := ind.FindJoin(spec.field, spec.index, spec.foreignField); ok {
+		docIDs, related, err = materializedJoin(foreign, join.Name, subDocIDs)
+	} else {
+		docIDs, related, err = scanJoin(ind, foreign, spec, subDocIDs)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch spec.joinType {
+	case JoinAnti:
+		allDocIDs, err := ind.DocIDs()
+
+		if err != nil {
+			return nil, err
+		}
+
+		docIDs = not(allDocIDs, docIDs)
+	case JoinInner:
+		if m.joins == nil {
+			m.joins = make(map[string]*innerJoin)
+		}
+
+		if current, ok := m.joins[spec.as]; ok && current.foreign == foreign {
+			for id, foreignIDs := range related {
+				current.related[id] = or(current.related[id], foreignIDs)
+			}
+		} else {
+			m.joins[spec.as] = &innerJoin{foreign: foreign, related: related}
+		}
+	}
+
+	return docIDs, nil
+}
+
+// scanJoin returns the documents of ind related to the documents subDocIDs
+// of foreign, and the related documents of each of them, by walking the
+// postings of the foreign field.
+func scanJoin(ind, foreign *index.Index, spec *joinSpec, subDocIDs []uint64) ([]uint64, map[uint64][]uint64, error) {
+	var docIDs []uint64
+
+	// values of the foreign field in the sub-query documents
+	termDocs := make(map[interface{}][]uint64)
+
+	if len(subDocIDs) > 0 {
+		err := foreign.WalkPostings(spec.foreignField, func(term interface{}, postings []uint64) error {
+			if related := and(subDocIDs, postings); len(related) > 0 {
+				termDocs[term] = related
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	related := make(map[uint64][]uint64)
+
+	for term, foreignIDs := range termDocs {
+		ids, err := ind.TermDocIDs(spec.field, term)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		docIDs = or(docIDs, ids)
+
+		for _, id := range ids {
+			related[id] = or(related[id], foreignIDs)
+		}
+	}
+
+	return docIDs, related, nil
+}
+
+// materializedJoin is like scanJoin, but reads the relations of the
+// documents subDocIDs from the storage of the materialized join name of
+// foreign.
+func materializedJoin(foreign *index.Index, name string, subDocIDs []uint64) ([]uint64, map[uint64][]uint64, error) {
+	var docIDs []uint64
+
+	joined, err := foreign.JoinedIDs(name, subDocIDs)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	related := make(map[uint64][]uint64)
+
+	for _, foreignID := range subDocIDs {
+		ids := joined[foreignID]
+		sort.Sort(utils.Uint64Slice(ids))
+		docIDs = or(docIDs, ids)
+
+		for _, id := range ids {
+			related[id] = or(related[id], []uint64{foreignID})
+		}
+	}
+
+	return docIDs, related, nil
+}
+
+// joinedDocs returns the related documents of the inner joins of the
+// document id, by join name
+func (m *matches) joinedDocs(id uint64) (map[string][]string, error) {
+	var joined map[string][]string
+
+	for name, join := range m.joins {
+		foreignIDs := join.related[id]
+
+		if len(foreignIDs) == 0 {
+			continue
+		}
+
+		docs, err := join.foreign.GetDocs(foreignIDs, uint(len(foreignIDs)))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if joined == nil {
+			joined = make(map[string][]string)
+		}
+
+		joined[name] = docs
+	}
+
+	return joined, nil
+}
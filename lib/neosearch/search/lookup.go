@@ -0,0 +1,225 @@
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Lookup enriches the hits with the documents of a foreign index whose
+// ForeignField has the value of Field of the hit, eg.:
+//
+//	{"index": "partners", "field": "id", "foreign_field": "company_id", "as": "partners"}
+//
+// attaches the partners of each company to the hits under "partners". The
+// search service returns them in the reserved "_lookups" field of the hits,
+// like the "_joined" documents of joins.
+type Lookup struct {
+	Index        string
+	Field        string
+	ForeignField string
+
+	// As is the key of the related documents. Defaults to Index.
+	As string
+
+	// Size is the maximum number of related documents of each hit.
+	// Zero is no limit.
+	Size uint
+}
+
+// ParseLookups parses the lookup option of the search request, a lookup
+// object or a list of them.
+func ParseLookups(option interface{}) ([]Lookup, error) {
+	var (
+		items   []interface{}
+		lookups []Lookup
+	)
+
+	switch v := option.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		items = v
+	default:
+		items = []interface{}{v}
+	}
+
+	for _, item := range items {
+		obj, ok := item.(map[string]interface{})
+
+		if !ok {
+			return nil, fmt.Errorf("Invalid lookup: %v", item)
+		}
+
+		lookup := Lookup{}
+
+		for _, param := range []struct {
+			name     string
+			value    *string
+			required bool
+		}{
+			{"index", &lookup.Index, true},
+			{"field", &lookup.Field, true},
+			{"foreign_field", &lookup.ForeignField, true},
+			{"as", &lookup.As, false},
+		} {
+			v, ok := obj[param.name]
+
+			if !ok && !param.required {
+				continue
+			}
+
+			if *param.value, ok = v.(string); !ok || *param.value == "" {
+				return nil, fmt.Errorf("Invalid lookup. Param '%s' must be a non-empty string", param.name)
+			}
+		}
+
+		size, err := aggUint(obj, "size", 0)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid lookup: %s", err)
+		}
+
+		if lookup.As == "" {
+			lookup.As = lookup.Index
+		}
+
+		lookup.Size = uint(size)
+		lookups = append(lookups, lookup)
+	}
+
+	return lookups, nil
+}
+
+// lookupHits attaches to the hits the related documents of each lookup.
+// The foreign terms of every hit are searched once per distinct value,
+// and every related document is fetched once.
+func lookupHits(hits []Hit, lookups []Lookup, indices IndexOpener) error {
+	if len(lookups) == 0 || len(hits) == 0 {
+		return nil
+	}
+
+	if indices == nil {
+		return errors.New("Lookups aren't supported by this search")
+	}
+
+	docs := make([]map[string]interface{}, len(hits))
+
+	for idx, hit := range hits {
+		if err := json.Unmarshal([]byte(hit.Doc), &docs[idx]); err != nil {
+			return err
+		}
+	}
+
+	for _, lookup := range lookups {
+		foreign, err := indices.OpenIndex(lookup.Index)
+
+		if err != nil {
+			return err
+		}
+
+		hitTerms := make([][]string, len(hits))
+		termDocIDs := make(map[string][]uint64)
+
+		for idx := range hits {
+			hitTerms[idx] = lookupTerms(docs[idx], lookup.Field)
+
+			for _, term := range hitTerms[idx] {
+				if _, ok := termDocIDs[term]; ok {
+					continue
+				}
+
+				docIDs, _, err := foreign.FilterTermID([]byte(lookup.ForeignField), []byte(term), 0)
+
+				if err != nil {
+					return err
+				}
+
+				termDocIDs[term] = docIDs
+			}
+		}
+
+		foreignDocs := make(map[uint64]string)
+
+		for idx := range hits {
+			var related []uint64
+
+			for _, term := range hitTerms[idx] {
+				related = or(related, termDocIDs[term])
+			}
+
+			if lookup.Size > 0 && uint(len(related)) > lookup.Size {
+				related = related[:lookup.Size]
+			}
+
+			if related == nil {
+				continue
+			}
+
+			if hits[idx].Lookups == nil {
+				hits[idx].Lookups = make(map[string][]string)
+			}
+
+			relatedDocs := make([]string, len(related))
+
+			for i, id := range related {
+				doc, ok := foreignDocs[id]
+
+				if !ok {
+					data, err := foreign.Get(id)
+
+					if err != nil {
+						return err
+					}
+
+					doc = string(data)
+					foreignDocs[id] = doc
+				}
+
+				relatedDocs[i] = doc
+			}
+
+			hits[idx].Lookups[lookup.As] = relatedDocs
+		}
+	}
+
+	return nil
+}
+
+// lookupTerms returns the values of the dotted field of doc as terms
+func lookupTerms(doc map[string]interface{}, field string) []string {
+	var value interface{} = doc
+
+	for _, part := range strings.Split(field, ".") {
+		obj, ok := value.(map[string]interface{})
+
+		if !ok {
+			return nil
+		}
+
+		value = obj[part]
+	}
+
+	values, ok := value.([]interface{})
+
+	if !ok {
+		values = []interface{}{value}
+	}
+
+	terms := make([]string, 0, len(values))
+
+	for _, v := range values {
+		switch v := v.(type) {
+		case string:
+			terms = append(terms, v)
+		case float64:
+			terms = append(terms, strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			terms = append(terms, strconv.FormatBool(v))
+		}
+	}
+
+	return terms
+}
@@ -0,0 +1,174 @@
+package search
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/index"
+)
+
+// Metrics of the stats aggregation that can be requested alone
+const (
+	MetricMin   = "min"
+	MetricMax   = "max"
+	MetricAvg   = "avg"
+	MetricSum   = "sum"
+	MetricCount = "value_count"
+)
+
+// statsAgg computes count, min, max, avg and sum of the values of a
+// numeric field, or only one of them if metric is set.
+type statsAgg struct {
+	field  string
+	metric string
+}
+
+func parseStats(params map[string]interface{}) (Aggregation, error) {
+	field, err := aggField(params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &statsAgg{field: field}, nil
+}
+
+// metricParser returns the parser of the single metric aggregation
+func metricParser(metric string) func(params map[string]interface{}) (Aggregation, error) {
+	return func(params map[string]interface{}) (Aggregation, error) {
+		field, err := aggField(params)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &statsAgg{field: field, metric: metric}, nil
+	}
+}
+
+// numericField returns an error if field isn't int, uint or float
+func numericField(ind *index.Index, field string) error {
+	switch fieldType := ind.FieldType(field); fieldType {
+	case "int", "uint", "float":
+		return nil
+	default:
+		return fmt.Errorf("Field '%s' of type '%s' isn't numeric", field, fieldType)
+	}
+}
+
+// toFloat64 converts the numeric term of a posting to float64
+func toFloat64(term interface{}) float64 {
+	switch v := term.(type) {
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float64:
+		return v
+	}
+
+	return math.NaN()
+}
+
+// Aggregate walks the postings of the field and accounts each term once
+// for every matched document having it.
+func (agg *statsAgg) Aggregate(ind *index.Index, docIDs []uint64) (AggregationResult, error) {
+	if err := numericField(ind, agg.field); err != nil {
+		return nil, err
+	}
+
+	result := &StatsResult{Metric: agg.metric}
+
+	if len(docIDs) == 0 {
+		return result, nil
+	}
+
+	err := ind.WalkPostings(agg.field, func(term interface{}, postings []uint64) error {
+		if count := intersectCount(docIDs, postings); count > 0 {
+			result.add(toFloat64(term), count)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// StatsResult is the result of the stats and single metric aggregations
+type StatsResult struct {
+	// Metric is the requested metric or empty for every metric
+	Metric string
+
+	Count         uint64
+	Sum, Min, Max float64
+}
+
+func (r *StatsResult) add(value float64, count uint64) {
+	if r.Count == 0 || value < r.Min {
+		r.Min = value
+	}
+
+	if r.Count == 0 || value > r.Max {
+		r.Max = value
+	}
+
+	r.Count += count
+	r.Sum += value * float64(count)
+}
+
+// Merge combines the stats of other into r
+func (r *StatsResult) Merge(other AggregationResult) AggregationResult {
+	o, ok := other.(*StatsResult)
+
+	if !ok || o.Count == 0 {
+		return r
+	}
+
+	if r.Count == 0 || o.Min < r.Min {
+		r.Min = o.Min
+	}
+
+	if r.Count == 0 || o.Max > r.Max {
+		r.Max = o.Max
+	}
+
+	r.Count += o.Count
+	r.Sum += o.Sum
+	return r
+}
+
+// Output returns {"count": ..., "min": ..., "max": ..., "avg": ...,
+// "sum": ...} or {"value": ...} for single metric aggregations. Min, max
+// and avg are null without values.
+func (r *StatsResult) Output() interface{} {
+	var min, max, avg interface{}
+
+	if r.Count > 0 {
+		min, max, avg = r.Min, r.Max, r.Sum/float64(r.Count)
+	}
+
+	switch r.Metric {
+	case MetricMin:
+		return map[string]interface{}{"value": min}
+	case MetricMax:
+		return map[string]interface{}{"value": max}
+	case MetricAvg:
+		return map[string]interface{}{"value": avg}
+	case MetricSum:
+		return map[string]interface{}{"value": r.Sum}
+	case MetricCount:
+		return map[string]interface{}{"value": r.Count}
+	}
+
+	return map[string]interface{}{
+		"count": r.Count,
+		"min":   min,
+		"max":   max,
+		"avg":   avg,
+		"sum":   r.Sum,
+	}
+}
@@ -0,0 +1,69 @@
+package search
+
+import (
+	"errors"
+	"sort"
+)
+
+// SearchIndices searches the indices names, opened by opts.Indices one at
+// a time, and merges their results: hits are sorted by opts.Sort across
+// the indices, totals are summed and aggregations are merged. Hits are
+// tagged with their index (Hit.Index); hits of different indices with the
+// same sort values and id are ordered by index name.
+func SearchIndices(names []string, dsl DSL, opts Options) (*Results, error) {
+	if opts.Indices == nil {
+		return nil, errors.New("Searches of several indices require an index opener")
+	}
+
+	// every index returns the hits up to the end of the requested page
+	indexOpts := opts
+	indexOpts.From = 0
+	indexOpts.Limit = opts.From + opts.Limit
+	indexOpts.Lookups = nil
+
+	merged := &Results{}
+
+	for _, name := range names {
+		ind, err := opts.Indices.OpenIndex(name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		results, err := SearchResults(ind, dsl, indexOpts)
+
+		if err != nil {
+			return nil, err
+		}
+
+		merged.Hits = append(merged.Hits, results.Hits...)
+		merged.Total += results.Total
+		merged.Remaining += results.Remaining
+		merged.Aggs = MergeAggs(merged.Aggs, results.Aggs)
+	}
+
+	sortFields := opts.Sort
+
+	if len(sortFields) == 0 {
+		sortFields = []SortField{{Field: SortScore, Desc: true}}
+	}
+
+	sort.Sort(byFields{merged.Hits, sortFields})
+
+	if opts.From >= uint(len(merged.Hits)) {
+		merged.Hits = merged.Hits[:0]
+	} else {
+		merged.Hits = merged.Hits[opts.From:]
+	}
+
+	if uint(len(merged.Hits)) > opts.Limit {
+		merged.Remaining += uint64(uint(len(merged.Hits)) - opts.Limit)
+		merged.Hits = merged.Hits[0:opts.Limit]
+	}
+
+	if err := lookupHits(merged.Hits, opts.Lookups, opts.Indices); err != nil {
+		return nil, err
+	}
+
+	return merged, nil
+}
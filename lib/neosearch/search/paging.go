@@ -0,0 +1,124 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Cursor returns the search_after cursor of hit: the values of its sort
+// keys followed by its document id. The cursor of the last hit of a page
+// is used to fetch the next page with a stable order, even if documents
+// are added between the requests.
+func Cursor(hit Hit) []interface{} {
+	cursor := make([]interface{}, 0, len(hit.Sort)+1)
+	cursor = append(cursor, hit.Sort...)
+	return append(cursor, hit.ID)
+}
+
+// IndexCursor is like Cursor, followed by the index of the hit. It's the
+// cursor of searches of several indices, where ids aren't unique.
+func IndexCursor(hit Hit) []interface{} {
+	return append(Cursor(hit), hit.Index)
+}
+
+// searchAfter returns the hits, sorted by fields, that come after cursor
+func searchAfter(hits []Hit, cursor []interface{}, fields []SortField) ([]Hit, error) {
+	var index string
+
+	if len(cursor) == len(fields)+2 {
+		var ok bool
+
+		if index, ok = cursor[len(fields)+1].(string); !ok || index == "" {
+			return nil, fmt.Errorf("Invalid search_after index: %v", cursor[len(fields)+1])
+		}
+
+		cursor = cursor[:len(fields)+1]
+	}
+
+	if len(cursor) != len(fields)+1 {
+		return nil, fmt.Errorf("Invalid search_after cursor: %v. Expected the values of %d sort keys and the document id",
+			cursor, len(fields))
+	}
+
+	id, err := cursorValue(cursor[len(fields)], uint64(0))
+
+	if err != nil {
+		return nil, err
+	}
+
+	last := Hit{
+		ID:    id.(uint64),
+		Sort:  make([]interface{}, len(fields)),
+		Index: index,
+	}
+
+	for fieldIdx := range fields {
+		var like interface{}
+
+		for _, hit := range hits {
+			if hit.Sort[fieldIdx] != nil {
+				like = hit.Sort[fieldIdx]
+				break
+			}
+		}
+
+		if like == nil {
+			// no hit has a value to compare with
+			last.Sort[fieldIdx] = cursor[fieldIdx]
+			continue
+		}
+
+		if last.Sort[fieldIdx], err = cursorValue(cursor[fieldIdx], like); err != nil {
+			return nil, err
+		}
+	}
+
+	start := sort.Search(len(hits), func(idx int) bool {
+		return compareHits(hits[idx], last, fields) > 0
+	})
+
+	return hits[start:], nil
+}
+
+// cursorValue converts the JSON value of a cursor to the type of the sort
+// values it's compared with. Numbers are expected as json.Number to keep
+// the precision of int64 and uint64 values, but float64 is accepted.
+func cursorValue(value, like interface{}) (interface{}, error) {
+	var num json.Number
+
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case json.Number:
+		num = v
+	case float64:
+		num = json.Number(strconv.FormatFloat(v, 'f', -1, 64))
+	}
+
+	switch like.(type) {
+	case float64:
+		if num != "" {
+			return num.Float64()
+		}
+	case int64:
+		if num != "" {
+			return num.Int64()
+		}
+	case uint64:
+		if num != "" {
+			return strconv.ParseUint(string(num), 10, 64)
+		}
+	case string:
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+	case bool:
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Invalid search_after value: %v", value)
+}
@@ -3,6 +3,7 @@ package search
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/NeowayLabs/neosearch/lib/neosearch/index"
 )
@@ -15,11 +16,208 @@ type (
 
 func (d DSL) Map() map[string]interface{} { return map[string]interface{}(d) }
 
+// Hit is a document matched by the search with its relevance score
+type Hit struct {
+	ID    uint64
+	Score float64
+	Doc   string
+
+	// Index is the name of the index of the hit
+	Index string
+
+	// Sort are the values of the sort keys of the hit
+	Sort []interface{}
+
+	// Joined are the documents of the inner joins of the query related to
+	// the hit, by join name
+	Joined map[string][]string
+
+	// Lookups are the related documents of the lookups, by Lookup.As
+	Lookups map[string][]string
+}
+
+// Options of the search
+type Options struct {
+	// Limit is the maximum number of hits returned
+	Limit uint
+
+	// From is the number of hits skipped
+	From uint
+
+	// SearchAfter is the cursor of the last hit of the previous page (see
+	// Cursor). Only the hits sorted after it are returned.
+	SearchAfter []interface{}
+
+	// Sort are the sort keys of the hits. Hits are sorted by descending
+	// score by default.
+	Sort []SortField
+
+	// Aggs are the aggregations computed over every matched document
+	Aggs map[string]Aggregation
+
+	// Lookups enrich the hits with related documents of other indices
+	Lookups []Lookup
+
+	// Indices opens the indices of the join clauses and lookups
+	Indices IndexOpener
+}
+
+// Results of a search
+type Results struct {
+	// Hits is the requested page of hits
+	Hits []Hit
+
+	// Total is the number of matched documents
+	Total uint64
+
+	// Remaining is the number of hits sorted after the page
+	Remaining uint64
+
+	// Aggs are the results of Options.Aggs by aggregation name
+	Aggs map[string]AggregationResult
+}
+
+// Search returns the documents matched by dsl, ordered by relevance and
+// limited by limit, and the total number of matched documents.
 func Search(ind *index.Index, dsl DSL, limit uint) ([]string, uint64, error) {
+	hits, total, err := SearchHits(ind, dsl, Options{Limit: limit})
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	docs := make([]string, len(hits))
+
+	for idx, hit := range hits {
+		docs[idx] = hit.Doc
+	}
+
+	return docs, total, nil
+}
+
+// SearchHits returns a page of the hits matched by dsl sorted by opts.Sort,
+// and the total number of matched documents.
+func SearchHits(ind *index.Index, dsl DSL, opts Options) ([]Hit, uint64, error) {
+	results, err := SearchResults(ind, dsl, opts)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return results.Hits, results.Total, nil
+}
+
+// SearchResults returns a page of the hits matched by dsl sorted by
+// opts.Sort, the total number of matched documents and the results of the
+// aggregations of opts.
+func SearchResults(ind *index.Index, dsl DSL, opts Options) (*Results, error) {
+	matched, err := match(ind, dsl, opts.Indices)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resultDocIDs, scores := matched.docIDs, matched.scores
+
+	hits := make([]Hit, len(resultDocIDs))
+
+	for idx, docID := range resultDocIDs {
+		hits[idx] = Hit{
+			ID:    docID,
+			Score: scores[docID],
+			Index: ind.Name,
+		}
+	}
+
+	aggs, err := aggregate(ind, opts.Aggs, resultDocIDs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sortFields := opts.Sort
+
+	if len(sortFields) == 0 {
+		sortFields = []SortField{{Field: SortScore, Desc: true}}
+	}
+
+	if err = sortHits(ind, hits, sortFields); err != nil {
+		return nil, err
+	}
+
+	if opts.SearchAfter != nil {
+		if hits, err = searchAfter(hits, opts.SearchAfter, sortFields); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.From >= uint(len(hits)) {
+		hits = hits[:0]
+	} else {
+		hits = hits[opts.From:]
+	}
+
+	var remaining uint64
+
+	if uint(len(hits)) > opts.Limit {
+		remaining = uint64(uint(len(hits)) - opts.Limit)
+		hits = hits[0:opts.Limit]
+	}
+
+	for idx := range hits {
+		doc, err := ind.Get(hits[idx].ID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		hits[idx].Doc = string(doc)
+
+		if hits[idx].Joined, err = matched.joinedDocs(hits[idx].ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = lookupHits(hits, opts.Lookups, opts.Indices); err != nil {
+		return nil, err
+	}
+
+	return &Results{
+		Hits:      hits,
+		Total:     uint64(len(resultDocIDs)),
+		Remaining: remaining,
+		Aggs:      aggs,
+	}, nil
+}
+
+// MatchIDs returns the sorted ids of the documents of ind matched by dsl
+func MatchIDs(ind *index.Index, dsl DSL, indices IndexOpener) ([]uint64, error) {
+	matched, err := match(ind, dsl, indices)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return matched.docIDs, nil
+}
+
+// matches are the documents matched by a query with their scores
+type matches struct {
+	docIDs []uint64
+	scores map[uint64]float64
+
+	// joins are the inner joins of the query by name
+	joins map[string]*innerJoin
+}
+
+// match returns the documents matched by the $and or $or clauses of dsl.
+// The score of a document is the sum of the BM25 scores of the clauses it
+// matches, multiplied by the clause boost ({"field": "value", "$boost": 2}).
+// Join clauses score the boost for every matched document.
+func match(ind *index.Index, dsl DSL, indices IndexOpener) (*matches, error) {
 	var (
 		listOp        []interface{}
 		hasAnd, hasOr bool
-		resultDocIDs  []uint64
 	)
 
 	listOp, hasAnd = dsl["$and"].([]interface{})
@@ -29,46 +227,124 @@ func Search(ind *index.Index, dsl DSL, limit uint) ([]string, uint64, error) {
 	}
 
 	if !hasAnd && !hasOr {
-		return nil, 0, errors.New("Invalid search DSL. No $and or $or clause found.")
+		return nil, errors.New("Invalid search DSL. No $and or $or clause found.")
 	}
 
-	for _, clause := range listOp {
-		filter, ok := clause.(map[string]interface{})
+	matched := &matches{
+		scores: make(map[uint64]float64),
+	}
 
-		if !ok {
-			return nil, 0, fmt.Errorf("Invalid clause '%s'.", clause)
-		}
+	for idx, clause := range listOp {
+		var (
+			field      string
+			value      interface{}
+			boost      = float64(1)
+			docIDs     []uint64
+			termScores []float64
+			err        error
+		)
 
-		field, value := getFieldValue(filter)
+		switch filter := clause.(type) {
+		case string:
+			// field-less clauses search the catch-all field
+			field, value = index.AllField, filter
+		case map[string]interface{}:
+			field, value = getFieldValue(filter)
 
-		if field == "" || value == nil {
-			return nil, 0, fmt.Errorf("Invalid clause '%s'.", clause)
-		}
+			if b, ok := filter["$boost"]; ok {
+				if boost, ok = b.(float64); !ok {
+					return nil, fmt.Errorf("Invalid boost: %v", b)
+				}
+			}
 
-		strValue, ok := value.(string)
+			if spec, ok := filter["$join"]; ok {
+				field, value = JoinClause, spec
+			}
+		default:
+			return nil, fmt.Errorf("Invalid clause '%s'.", clause)
+		}
 
-		if !ok {
-			return nil, 0, fmt.Errorf("Invalid field value: %s", value)
+		if field == "" || value == nil {
+			return nil, fmt.Errorf("Invalid clause '%s'.", clause)
 		}
 
-		docIDs, _, err := ind.FilterTermID([]byte(field), []byte(strValue), 0)
+		if field == JoinClause {
+			docIDs, err = matched.join(ind, value, indices)
+		} else {
+			docIDs, termScores, err = matchTerm(ind, field, value)
+		}
 
 		if err != nil {
-			return nil, 0, err
+			return nil, err
 		}
 
-		if len(resultDocIDs) == 0 {
-			resultDocIDs = docIDs
-			continue
+		for i, docID := range docIDs {
+			if termScores != nil {
+				matched.scores[docID] += boost * termScores[i]
+			} else {
+				matched.scores[docID] += boost
+			}
 		}
 
-		if hasAnd {
-			resultDocIDs = and(resultDocIDs, docIDs)
+		if idx == 0 {
+			matched.docIDs = docIDs
+		} else if hasAnd {
+			matched.docIDs = and(matched.docIDs, docIDs)
+		} else {
+			matched.docIDs = or(matched.docIDs, docIDs)
+		}
+	}
+
+	return matched, nil
+}
+
+// matchTerm returns the documents having the term value in field and their
+// scores
+func matchTerm(ind *index.Index, field string, value interface{}) ([]uint64, []float64, error) {
+	strValue, ok := value.(string)
+
+	if !ok {
+		return nil, nil, fmt.Errorf("Invalid field value: %s", value)
+	}
+
+	docIDs, _, err := ind.FilterTermID([]byte(field), []byte(strValue), 0)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	termScores, err := ind.ScoreTerm([]byte(field), []byte(strValue), docIDs)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return docIDs, termScores, nil
+}
+
+// or returns the union of the ordered sets a and b
+func or(a, b []uint64) []uint64 {
+	var (
+		i, j   int
+		result = make([]uint64, 0, len(a)+len(b))
+	)
+
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			result = append(result, a[i])
+			i++
+			j++
+		} else if a[i] < b[j] {
+			result = append(result, a[i])
+			i++
+		} else {
+			result = append(result, b[j])
+			j++
 		}
 	}
 
-	results, err := ind.GetDocs(resultDocIDs, limit)
-	return results, uint64(len(resultDocIDs)), err
+	result = append(result, a[i:]...)
+	return append(result, b[j:]...)
 }
 
 // TODO: we need benchmark this algorithm and optimize
@@ -105,10 +381,36 @@ func and(a, b []uint64) []uint64 {
 	return result[0:resIdx]
 }
 
+// getFieldValue returns the field and value of the clause. Keys starting
+// with $ are clause options.
 func getFieldValue(filter map[string]interface{}) (string, interface{}) {
 	for field, value := range filter {
-		return field, value
+		if !strings.HasPrefix(field, "$") {
+			return field, value
+		}
 	}
 
 	return "", nil
 }
+
+// not returns the ordered set a without the elements of the ordered set b
+func not(a, b []uint64) []uint64 {
+	var (
+		j      int
+		result = make([]uint64, 0, len(a))
+	)
+
+	for _, v := range a {
+		for j < len(b) && b[j] < v {
+			j++
+		}
+
+		if j < len(b) && b[j] == v {
+			continue
+		}
+
+		result = append(result, v)
+	}
+
+	return result
+}
@@ -0,0 +1,211 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/index"
+)
+
+// Special sort fields
+const (
+	SortScore string = "_score"
+	SortID    string = "_id"
+)
+
+// SortField is a sort key of the search results
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort parses the sort option of the search request. The option is
+// a list of field names or {"<field>": "asc|desc"} objects, eg.:
+//
+//	["_score", {"revenue": "desc"}, {"founded_at": "asc"}]
+//
+// A single field name or object is accepted too. Fields are sorted in
+// ascending order by default, except _score.
+func ParseSort(option interface{}) ([]SortField, error) {
+	var (
+		keys   []interface{}
+		fields []SortField
+	)
+
+	switch v := option.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		keys = v
+	default:
+		keys = []interface{}{v}
+	}
+
+	for _, key := range keys {
+		switch v := key.(type) {
+		case string:
+			fields = append(fields, SortField{Field: v, Desc: v == SortScore})
+		case map[string]interface{}:
+			if len(v) != 1 {
+				return nil, fmt.Errorf("Invalid sort key: %v", v)
+			}
+
+			for field, order := range v {
+				orderStr, _ := order.(string)
+
+				switch strings.ToLower(orderStr) {
+				case "asc":
+					fields = append(fields, SortField{Field: field})
+				case "desc":
+					fields = append(fields, SortField{Field: field, Desc: true})
+				default:
+					return nil, fmt.Errorf("Invalid sort order of field '%s': %v", field, order)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("Invalid sort key: %v", key)
+		}
+	}
+
+	return fields, nil
+}
+
+// sortHits loads the sort values of each hit and sort the hits by them.
+// Ties are always broken by ascending document id.
+func sortHits(ind *index.Index, hits []Hit, sortFields []SortField) error {
+	docIDs := make([]uint64, len(hits))
+
+	for idx, hit := range hits {
+		docIDs[idx] = hit.ID
+		hits[idx].Sort = make([]interface{}, len(sortFields))
+	}
+
+	for fieldIdx, sortField := range sortFields {
+		var (
+			values []interface{}
+			err    error
+		)
+
+		switch sortField.Field {
+		case SortScore:
+			values = make([]interface{}, len(hits))
+
+			for idx, hit := range hits {
+				values[idx] = hit.Score
+			}
+		case SortID:
+			values = make([]interface{}, len(hits))
+
+			for idx, hit := range hits {
+				values[idx] = hit.ID
+			}
+		default:
+			if values, err = ind.DocValues(sortField.Field, docIDs); err != nil {
+				return err
+			}
+		}
+
+		for idx := range hits {
+			hits[idx].Sort[fieldIdx] = values[idx]
+		}
+	}
+
+	sort.Sort(byFields{hits, sortFields})
+	return nil
+}
+
+type byFields struct {
+	hits   []Hit
+	fields []SortField
+}
+
+func (h byFields) Len() int      { return len(h.hits) }
+func (h byFields) Swap(i, j int) { h.hits[i], h.hits[j] = h.hits[j], h.hits[i] }
+func (h byFields) Less(i, j int) bool {
+	return compareHits(h.hits[i], h.hits[j], h.fields) < 0
+}
+
+// compareHits compares the sort values of a and b. Documents without
+// value are sorted last in both orders.
+func compareHits(a, b Hit, fields []SortField) int {
+	for idx, field := range fields {
+		va, vb := a.Sort[idx], b.Sort[idx]
+
+		if va == nil || vb == nil {
+			if va == nil && vb != nil {
+				return 1
+			} else if va != nil && vb == nil {
+				return -1
+			}
+
+			continue
+		}
+
+		cmp := compareValues(va, vb)
+
+		if field.Desc {
+			cmp = -cmp
+		}
+
+		if cmp != 0 {
+			return cmp
+		}
+	}
+
+	if a.ID < b.ID {
+		return -1
+	} else if a.ID > b.ID {
+		return 1
+	}
+
+	// hits of several indices, see SearchIndices
+	if a.Index != "" && b.Index != "" {
+		return strings.Compare(a.Index, b.Index)
+	}
+
+	return 0
+}
+
+// compareValues compares two sort values of the same field
+func compareValues(a, b interface{}) int {
+	switch va := a.(type) {
+	case float64:
+		vb, _ := b.(float64)
+
+		if va < vb {
+			return -1
+		} else if va > vb {
+			return 1
+		}
+	case int64:
+		vb, _ := b.(int64)
+
+		if va < vb {
+			return -1
+		} else if va > vb {
+			return 1
+		}
+	case uint64:
+		vb, _ := b.(uint64)
+
+		if va < vb {
+			return -1
+		} else if va > vb {
+			return 1
+		}
+	case string:
+		vb, _ := b.(string)
+		return strings.Compare(va, vb)
+	case bool:
+		vb, _ := b.(bool)
+
+		if !va && vb {
+			return -1
+		} else if va && !vb {
+			return 1
+		}
+	}
+
+	return 0
+}
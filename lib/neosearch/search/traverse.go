@@ -0,0 +1,336 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch/index"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/utils"
+)
+
+// Limits of traversals
+const (
+	DefaultMaxDepth   uint = 2
+	DefaultFanOut     uint = 100
+	DefaultMaxVisited uint = 1000
+
+	// MaxTraversalDepth is the maximum accepted max_depth
+	MaxTraversalDepth uint = 10
+)
+
+// Relation is a reference from Field of the documents of Index to the
+// documents of ForeignIndex having the same term in ForeignField, eg.:
+//
+//	{"index": "companies", "field": "id", "foreign_index": "partners", "foreign_field": "company_id"}
+//
+// Relations are followed only from Index to ForeignIndex. The indices may
+// be the same.
+type Relation struct {
+	Index        string
+	Field        string
+	ForeignIndex string
+	ForeignField string
+}
+
+// Traversal follows the relations from the documents matched by a query,
+// breadth-first, up to MaxDepth hops.
+type Traversal struct {
+	Relations []Relation
+
+	// MaxDepth is the maximum number of hops from the matched documents
+	MaxDepth uint
+
+	// FanOut is the maximum number of documents visited from each
+	// document by each relation
+	FanOut uint
+
+	// MaxVisited is the maximum number of visited documents, including
+	// the matched ones
+	MaxVisited uint
+}
+
+// Step is a document of a traversal path
+type Step struct {
+	Index string `json:"index"`
+	ID    uint64 `json:"id"`
+}
+
+// Visit is a document visited by a traversal
+type Visit struct {
+	Index string
+	ID    uint64
+	Depth uint
+	Doc   string
+
+	// Path are the documents followed from the matched document up to
+	// this one, inclusive
+	Path []Step
+}
+
+// TraversalResults are the visited documents of a traversal, by depth
+type TraversalResults struct {
+	Visits []Visit
+
+	// Truncated is true when related documents weren't visited because of
+	// the FanOut or MaxVisited limits
+	Truncated bool
+}
+
+// ParseTraversal parses the traversal options of a traverse request:
+//
+//	{"relations": [...], "max_depth": 2, "fan_out": 100, "max_visited": 1000}
+func ParseTraversal(obj map[string]interface{}) (*Traversal, error) {
+	items, ok := obj["relations"].([]interface{})
+
+	if !ok || len(items) == 0 {
+		return nil, errors.New("Traversal 'relations' field must be a non-empty list")
+	}
+
+	traversal := &Traversal{}
+
+	for _, item := range items {
+		relObj, ok := item.(map[string]interface{})
+
+		if !ok {
+			return nil, fmt.Errorf("Invalid relation: %v", item)
+		}
+
+		relation := Relation{}
+
+		for _, param := range []struct {
+			name  string
+			value *string
+		}{
+			{"index", &relation.Index},
+			{"field", &relation.Field},
+			{"foreign_index", &relation.ForeignIndex},
+			{"foreign_field", &relation.ForeignField},
+		} {
+			if *param.value, ok = relObj[param.name].(string); !ok || *param.value == "" {
+				return nil, fmt.Errorf("Invalid relation. Param '%s' must be a non-empty string", param.name)
+			}
+		}
+
+		traversal.Relations = append(traversal.Relations, relation)
+	}
+
+	for _, param := range []struct {
+		name  string
+		value *uint
+		def   uint
+	}{
+		{"max_depth", &traversal.MaxDepth, DefaultMaxDepth},
+		{"fan_out", &traversal.FanOut, DefaultFanOut},
+		{"max_visited", &traversal.MaxVisited, DefaultMaxVisited},
+	} {
+		value, err := aggUint(obj, param.name, uint64(param.def))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid traversal: %s", err)
+		}
+
+		*param.value = uint(value)
+	}
+
+	if traversal.MaxDepth > MaxTraversalDepth {
+		return nil, fmt.Errorf("Invalid traversal. Param 'max_depth' must be at most %d", MaxTraversalDepth)
+	}
+
+	if traversal.FanOut == 0 || traversal.MaxVisited == 0 {
+		return nil, errors.New("Invalid traversal. Params 'fan_out' and 'max_visited' must be positive")
+	}
+
+	return traversal, nil
+}
+
+// Traverse visits the documents of ind matched by dsl, at depth zero, and
+// then the documents related to the visits of each depth by the relations
+// of t. Each document is visited once, by one of its shortest paths.
+func Traverse(ind *index.Index, dsl DSL, t *Traversal, indices IndexOpener) (*TraversalResults, error) {
+	if indices == nil {
+		return nil, errors.New("Traversals aren't supported by this search")
+	}
+
+	matched, err := match(ind, dsl, indices)
+
+	if err != nil {
+		return nil, err
+	}
+
+	results := &TraversalResults{}
+
+	// visited ids by index
+	visited := map[string]map[uint64]bool{ind.Name: make(map[uint64]bool)}
+
+	visit := func(v Visit) bool {
+		if uint(len(results.Visits)) >= t.MaxVisited {
+			results.Truncated = true
+			return false
+		}
+
+		if visited[v.Index] == nil {
+			visited[v.Index] = make(map[uint64]bool)
+		}
+
+		visited[v.Index][v.ID] = true
+		results.Visits = append(results.Visits, v)
+		return true
+	}
+
+	for _, id := range matched.docIDs {
+		if !visit(Visit{Index: ind.Name, ID: id, Path: []Step{{ind.Name, id}}}) {
+			break
+		}
+	}
+
+	frontier := results.Visits
+
+	for depth := uint(1); depth <= t.MaxDepth && len(frontier) > 0; depth++ {
+		// visits of the previous depth by index
+		byIndex := make(map[string][]Visit)
+
+		for _, v := range frontier {
+			byIndex[v.Index] = append(byIndex[v.Index], v)
+		}
+
+		names := make([]string, 0, len(byIndex))
+
+		for name := range byIndex {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		next := len(results.Visits)
+
+		for _, name := range names {
+			if err = t.hop(results, byIndex[name], depth, visited, visit, indices); err != nil {
+				return nil, err
+			}
+		}
+
+		frontier = results.Visits[next:]
+	}
+
+	for idx := range results.Visits {
+		v := &results.Visits[idx]
+
+		target, err := indices.OpenIndex(v.Index)
+
+		if err != nil {
+			return nil, err
+		}
+
+		doc, err := target.Get(v.ID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		v.Doc = string(doc)
+	}
+
+	return results, nil
+}
+
+// hop visits the documents related to the visits of the same index by
+// every relation starting on that index
+func (t *Traversal) hop(results *TraversalResults, from []Visit, depth uint,
+	visited map[string]map[uint64]bool, visit func(Visit) bool, indices IndexOpener) error {
+	var docIDs []uint64
+
+	fromIndex := from[0].Index
+
+	for _, v := range from {
+		docIDs = append(docIDs, v.ID)
+	}
+
+	sort.Sort(utils.Uint64Slice(docIDs))
+
+	for _, relation := range t.Relations {
+		if relation.Index != fromIndex {
+			continue
+		}
+
+		source, err := indices.OpenIndex(relation.Index)
+
+		if err != nil {
+			return err
+		}
+
+		target, err := indices.OpenIndex(relation.ForeignIndex)
+
+		if err != nil {
+			return err
+		}
+
+		related, err := relate(source, relation.Field, target, relation.ForeignField, docIDs)
+
+		if err != nil {
+			return err
+		}
+
+		for _, v := range from {
+			var count uint
+
+			for _, id := range related[v.ID] {
+				if visited[target.Name][id] {
+					continue
+				}
+
+				if count == t.FanOut {
+					results.Truncated = true
+					break
+				}
+
+				path := append(append([]Step{}, v.Path...), Step{target.Name, id})
+
+				if !visit(Visit{Index: target.Name, ID: id, Depth: depth, Path: path}) {
+					return nil
+				}
+
+				count++
+			}
+		}
+	}
+
+	return nil
+}
+
+// relate returns the sorted ids of the documents of to related to each
+// document of the sorted docIDs of from, using the materialized join
+// between the fields when there is one.
+func relate(from *index.Index, fromField string, to *index.Index, toField string, docIDs []uint64) (map[uint64][]uint64, error) {
+	if join, ok := from.FindJoin(fromField, to.Name, toField); ok {
+		related, err := from.JoinedIDs(join.Name, docIDs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ids := range related {
+			sort.Sort(utils.Uint64Slice(ids))
+		}
+
+		return related, nil
+	}
+
+	spec := &joinSpec{field: toField, foreignField: fromField}
+	toIDs, reverse, err := scanJoin(to, from, spec, docIDs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	related := make(map[uint64][]uint64)
+
+	// toIDs are sorted, then the related ids are appended in order
+	for _, toID := range toIDs {
+		for _, fromID := range reverse[toID] {
+			related[fromID] = append(related[fromID], toID)
+		}
+	}
+
+	return related, nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/filter"
 	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 // KVName is the name of goleveldb data store
@@ -163,3 +164,48 @@ func (lvdb *LVDB) Writer() store.KVWriter {
 	})
 	return lvdb.defWriter
 }
+
+// CompactRange compacts the keys from start to limit, nil bounds are the
+// first and last keys of the database
+func (lvdb *LVDB) CompactRange(start, limit []byte) error {
+	return lvdb.db.CompactRange(util.Range{Start: start, Limit: limit})
+}
+
+// ApproximateSize returns the size on disk of the keys from start to
+// limit, nil bounds are the first and last keys of the database. Keys not
+// yet written to the tables aren't counted.
+func (lvdb *LVDB) ApproximateSize(start, limit []byte) (uint64, error) {
+	if limit == nil {
+		if limit = lvdb.lastLimit(); limit == nil {
+			return 0, nil
+		}
+	}
+
+	sizes, err := lvdb.db.SizeOf([]util.Range{{Start: start, Limit: limit}})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(sizes.Sum()), nil
+}
+
+// lastLimit returns the key following the last key of the database, nil
+// when the database is empty
+func (lvdb *LVDB) lastLimit() []byte {
+	reader := lvdb.Reader()
+	it := reader.GetIterator()
+
+	defer func() {
+		it.Close()
+		reader.Close()
+	}()
+
+	it.SeekToLast()
+
+	if !it.Valid() {
+		return nil
+	}
+
+	return append(append([]byte{}, it.Key()...), 0)
+}
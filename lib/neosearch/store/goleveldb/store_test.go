@@ -221,3 +221,37 @@ func TestStoreIterator(t *testing.T) {
 	kv.Close()
 	os.RemoveAll(DataDirTmp + "/" + testDb)
 }
+
+func TestStoreCompactRange(t *testing.T) {
+	var (
+		kv     store.KVStore
+		testDb = "test_compact.db"
+	)
+
+	os.Mkdir(DataDirTmp+string(filepath.Separator)+"sample-store-compact", 0755)
+	if kv = openDatabase(t, "sample-store-compact", testDb); kv == nil {
+		return
+	}
+
+	test.CommonTestStoreCompactRange(t, kv)
+
+	kv.Close()
+	os.RemoveAll(DataDirTmp + "/" + testDb)
+}
+
+func TestStoreApproximateSize(t *testing.T) {
+	var (
+		kv     store.KVStore
+		testDb = "test_size.db"
+	)
+
+	os.Mkdir(DataDirTmp+string(filepath.Separator)+"sample-store-size", 0755)
+	if kv = openDatabase(t, "sample-store-size", testDb); kv == nil {
+		return
+	}
+
+	test.CommonTestStoreApproximateSize(t, kv)
+
+	kv.Close()
+	os.RemoveAll(DataDirTmp + "/" + testDb)
+}
@@ -143,3 +143,44 @@ func (lvdb *LVDB) Writer() store.KVWriter {
 	})
 	return lvdb.defWriter
 }
+
+// CompactRange compacts the keys from start to limit, nil bounds are the
+// first and last keys of the database
+func (lvdb *LVDB) CompactRange(start, limit []byte) error {
+	lvdb.db.CompactRange(levigo.Range{Start: start, Limit: limit})
+	return nil
+}
+
+// ApproximateSize returns the size on disk of the keys from start to
+// limit, nil bounds are the first and last keys of the database. Keys not
+// yet written to the tables aren't counted.
+func (lvdb *LVDB) ApproximateSize(start, limit []byte) (uint64, error) {
+	if limit == nil {
+		if limit = lvdb.lastLimit(); limit == nil {
+			return 0, nil
+		}
+	}
+
+	sizes := lvdb.db.GetApproximateSizes([]levigo.Range{{Start: start, Limit: limit}})
+	return sizes[0], nil
+}
+
+// lastLimit returns the key following the last key of the database, nil
+// when the database is empty
+func (lvdb *LVDB) lastLimit() []byte {
+	reader := lvdb.Reader()
+	it := reader.GetIterator()
+
+	defer func() {
+		it.Close()
+		reader.Close()
+	}()
+
+	it.SeekToLast()
+
+	if !it.Valid() {
+		return nil
+	}
+
+	return append(append([]byte{}, it.Key()...), 0)
+}
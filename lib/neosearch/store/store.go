@@ -32,6 +32,24 @@ type KVStore interface {
 	Close() error
 }
 
+// KVCompacter is the optional capability of the stores able to compact
+// their data, discarding deleted and overwritten entries.
+type KVCompacter interface {
+	// CompactRange compacts the keys from start to limit. Nil bounds are
+	// the first and last keys of the database.
+	CompactRange(start, limit []byte) error
+}
+
+// KVSizer is the optional capability of the stores able to estimate the
+// size of their data without reading it.
+type KVSizer interface {
+	// ApproximateSize returns the approximate size on disk of the keys
+	// from start to limit. Nil bounds are the first and last keys of the
+	// database. Recent writes may be counted only after they're written
+	// to the tables of the store.
+	ApproximateSize(start, limit []byte) (uint64, error)
+}
+
 // KVIterator expose the interface for database iterators.
 // This was Based on leveldb interface
 type KVIterator interface {
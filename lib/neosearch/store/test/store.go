@@ -371,3 +371,89 @@ func CommonTestStoreIterator(t *testing.T, kv store.KVStore) {
 		t.Fatal(err)
 	}
 }
+
+func CommonTestStoreCompactRange(t *testing.T, kv store.KVStore) {
+	compacter, ok := kv.(store.KVCompacter)
+
+	if !ok {
+		t.Fatal("Store doesn't implement KVCompacter")
+	}
+
+	writer := kv.Writer()
+
+	for i := uint64(0); i < 100; i++ {
+		if err := writer.Set(utils.Uint64ToBytes(i), []byte("compact")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := uint64(0); i < 100; i += 2 {
+		if err := writer.Delete(utils.Uint64ToBytes(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := compacter.CompactRange(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := compacter.CompactRange(utils.Uint64ToBytes(10), utils.Uint64ToBytes(20)); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := uint64(0); i < 100; i++ {
+		data, err := writer.Get(utils.Uint64ToBytes(i))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if (i%2 == 0) != (len(data) == 0) {
+			t.Errorf("Unexpected value of key %d after compaction: %q", i, data)
+		}
+	}
+}
+
+func CommonTestStoreApproximateSize(t *testing.T, kv store.KVStore) {
+	sizer, ok := kv.(store.KVSizer)
+
+	if !ok {
+		t.Fatal("Store doesn't implement KVSizer")
+	}
+
+	if size, err := sizer.ApproximateSize(nil, nil); err != nil || size != 0 {
+		t.Fatalf("Unexpected size of an empty store: %d, %v", size, err)
+	}
+
+	writer := kv.Writer()
+	value := make([]byte, 1000)
+
+	for i := range value {
+		value[i] = byte(i * 7)
+	}
+
+	for i := uint64(0); i < 1000; i++ {
+		if err := writer.Set(utils.Uint64ToBytes(i), value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the keys are written to the tables
+	if compacter, ok := kv.(store.KVCompacter); ok {
+		if err := compacter.CompactRange(nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	total, err := sizer.ApproximateSize(nil, nil)
+
+	if err != nil || total == 0 {
+		t.Fatalf("Unexpected size of the store: %d, %v", total, err)
+	}
+
+	half, err := sizer.ApproximateSize(nil, utils.Uint64ToBytes(500))
+
+	if err != nil || half == 0 || half >= total {
+		t.Errorf("Unexpected size of half of the store: %d of %d, %v", half, total, err)
+	}
+}
@@ -110,7 +110,7 @@ func GetUint64Array(data []byte) []uint64 {
 
 	for i = 0; i < lenBytes; i += 8 {
 		v = BytesToUint64(data[i : i+8])
-		uints[i] = v
+		uints[i/8] = v
 	}
 
 	return uints
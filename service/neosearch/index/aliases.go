@@ -0,0 +1,101 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch"
+	"github.com/NeowayLabs/neosearch/service/neosearch/handler"
+	"github.com/julienschmidt/httprouter"
+)
+
+type AliasesHandler struct {
+	handler.DefaultHandler
+	search *neosearch.NeoSearch
+}
+
+func NewAliasesHandler(search *neosearch.NeoSearch) *AliasesHandler {
+	return &AliasesHandler{
+		search: search,
+	}
+}
+
+// ServeHTTP returns the aliases on GET and updates them on POST, eg.:
+//
+//	{"actions": [
+//		{"remove": {"index": "companies_v1", "alias": "companies"}},
+//		{"add": {"index": "companies_v2", "alias": "companies"}}
+//	]}
+//
+// The actions are applied at once.
+func (handler *AliasesHandler) ServeHTTP(res http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	if req.Method == "POST" {
+		actions, err := parseAliasActions(req)
+
+		if err != nil {
+			res.WriteHeader(http.StatusBadRequest)
+			handler.Error(res, err.Error())
+			return
+		}
+
+		if err = handler.search.UpdateAliases(actions); err != nil {
+			res.WriteHeader(http.StatusBadRequest)
+			handler.Error(res, err.Error())
+			return
+		}
+	}
+
+	aliases, err := handler.search.Aliases()
+
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		handler.Error(res, err.Error())
+		return
+	}
+
+	handler.WriteJSONObject(res, aliases)
+}
+
+func parseAliasActions(req *http.Request) ([]neosearch.AliasAction, error) {
+	var (
+		request struct {
+			Actions []map[string]struct {
+				Index string `json:"index"`
+				Alias string `json:"alias"`
+			} `json:"actions"`
+		}
+		actions []neosearch.AliasAction
+	)
+
+	body, err := ioutil.ReadAll(req.Body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(body, &request); err != nil {
+		return nil, err
+	}
+
+	if len(request.Actions) == 0 {
+		return nil, fmt.Errorf("No alias actions specified")
+	}
+
+	for _, item := range request.Actions {
+		if len(item) != 1 {
+			return nil, fmt.Errorf("Invalid alias action: %v", item)
+		}
+
+		for action, params := range item {
+			actions = append(actions, neosearch.AliasAction{
+				Action: action,
+				Index:  params.Index,
+				Alias:  params.Alias,
+			})
+		}
+	}
+
+	return actions, nil
+}
@@ -0,0 +1,148 @@
+package index
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestAliases(t *testing.T) {
+	searchHandler := getSearchHandler()
+	handler := NewAliasesHandler(searchHandler.search)
+	addHandler := NewAddHandler(searchHandler.search)
+
+	router := httprouter.New()
+	router.Handle("GET", "/_aliases", handler.ServeHTTP)
+	router.Handle("POST", "/_aliases", handler.ServeHTTP)
+	router.Handle("POST", "/search/:index", searchHandler.ServeHTTP)
+	router.Handle("POST", "/add/:index/:id", addHandler.ServeHTTP)
+	ts := httptest.NewServer(router)
+
+	defer func() {
+		handler.search.DeleteIndex("aliases-v1")
+		handler.search.DeleteIndex("aliases-v2")
+		ts.Close()
+		handler.search.Close()
+	}()
+
+	post := func(url, body string) (int, map[string]interface{}) {
+		resObj := map[string]interface{}{}
+		res, err := http.Post(ts.URL+url, "application/json", bytes.NewBufferString(body))
+
+		if err != nil {
+			t.Error(err)
+			return 0, nil
+		}
+
+		defer res.Body.Close()
+
+		if err = json.NewDecoder(res.Body).Decode(&resObj); err != nil {
+			t.Error(err)
+		}
+
+		return res.StatusCode, resObj
+	}
+
+	for name, doc := range map[string]string{
+		"aliases-v1": `{"id": 1, "name": "Neoway v1"}`,
+		"aliases-v2": `{"id": 1, "name": "Neoway v2"}`,
+	} {
+		ind, err := handler.search.CreateIndex(name)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if err = ind.Add(1, []byte(doc), nil); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	status, resObj := post("/_aliases", `{"actions": [
+		{"add": {"index": "aliases-v1", "alias": "aliases-current"}},
+		{"add": {"index": "aliases-v1", "alias": "aliases-all"}},
+		{"add": {"index": "aliases-v2", "alias": "aliases-all"}}
+	]}`)
+
+	if status != http.StatusOK || !reflect.DeepEqual(resObj, map[string]interface{}{
+		"aliases-current": []interface{}{"aliases-v1"},
+		"aliases-all":     []interface{}{"aliases-v1", "aliases-v2"},
+	}) {
+		t.Errorf("Unexpected aliases: %d, %v", status, resObj)
+		return
+	}
+
+	searchNames := func(alias string) []interface{} {
+		_, resObj := post("/search/"+alias, `{"query": {"$and": [{"name": "neoway"}]}}`)
+		results, _ := resObj["results"].([]interface{})
+		names := make([]interface{}, len(results))
+
+		for i, result := range results {
+			names[i] = result.(map[string]interface{})["name"]
+		}
+
+		return names
+	}
+
+	if names := searchNames("aliases-all"); len(names) != 2 {
+		t.Errorf("Unexpected results of alias: %v", names)
+	}
+
+	// swap the alias
+	status, resObj = post("/_aliases", `{"actions": [
+		{"remove": {"index": "aliases-v1", "alias": "aliases-current"}},
+		{"add": {"index": "aliases-v2", "alias": "aliases-current"}}
+	]}`)
+
+	if status != http.StatusOK {
+		t.Errorf("Alias swap failed: %v", resObj)
+		return
+	}
+
+	if names := searchNames("aliases-current"); !reflect.DeepEqual(names, []interface{}{"Neoway v2"}) {
+		t.Errorf("Unexpected results of swapped alias: %v", names)
+	}
+
+	// writes through aliases of one index only
+	if _, resObj = post("/add/aliases-current/2", `{"doc": {"id": 2, "name": "Neoway"}}`); resObj["error"] != nil {
+		t.Errorf("Writes through alias failed: %v", resObj)
+	}
+
+	if _, resObj = post("/add/aliases-all/3", `{"doc": {"id": 3, "name": "Neoway"}}`); resObj["error"] == nil {
+		t.Error("Writes through aliases of several indices should fail")
+	}
+
+	for _, body := range []string{
+		`{"actions": [{"add": {"index": "missing", "alias": "aliases-x"}}]}`,
+		`{"actions": [{"remove": {"index": "aliases-v1", "alias": "aliases-current"}}]}`,
+		`{"actions": [{"add": {"index": "aliases-v1", "alias": "aliases-v2"}}]}`,
+		`{"actions": [{"rename": {"index": "aliases-v1", "alias": "aliases-x"}}]}`,
+		`{"actions": []}`,
+	} {
+		if status, _ = post("/_aliases", body); status != http.StatusBadRequest {
+			t.Errorf("Invalid alias actions should fail: %s", body)
+		}
+	}
+
+	res, err := http.Get(ts.URL + "/_aliases")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	resObj = map[string]interface{}{}
+	err = json.NewDecoder(res.Body).Decode(&resObj)
+	res.Body.Close()
+
+	if err != nil || len(resObj) != 2 || resObj["aliases-current"] == nil {
+		t.Errorf("Unexpected aliases: %v, %v", resObj, err)
+	}
+}
@@ -0,0 +1,83 @@
+package index
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch"
+	"github.com/NeowayLabs/neosearch/service/neosearch/handler"
+	"github.com/julienschmidt/httprouter"
+)
+
+type BackupHandler struct {
+	handler.DefaultHandler
+	search *neosearch.NeoSearch
+}
+
+func NewBackupHandler(search *neosearch.NeoSearch) *BackupHandler {
+	return &BackupHandler{
+		search: search,
+	}
+}
+
+// tarResponse sets the headers of the tar archive on the first write,
+// then errors before the archive is written are still returned as JSON
+type tarResponse struct {
+	res      http.ResponseWriter
+	filename string
+	written  bool
+}
+
+func (w *tarResponse) Write(p []byte) (int, error) {
+	if !w.written {
+		w.written = true
+		w.res.Header().Set("Content-Type", "application/x-tar")
+		w.res.Header().Set("Content-Disposition", `attachment; filename="`+w.filename+`"`)
+	}
+
+	return w.res.Write(p)
+}
+
+// ServeHTTP streams a backup of the index as a tar archive on
+// POST /:index/_backup and restores a backup sent as the body of
+// POST /:index/_restore in the new index :index.
+func (handler *BackupHandler) ServeHTTP(res http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	handler.ProcessVars(ps)
+	indexName := handler.GetIndexName()
+
+	if handler.GetDocumentID() == "_restore" {
+		manifest, err := handler.search.Restore(indexName, req.Body)
+
+		if err != nil {
+			res.WriteHeader(http.StatusBadRequest)
+			handler.Error(res, err.Error())
+			return
+		}
+
+		handler.WriteJSONObject(res, manifest)
+		return
+	}
+
+	if exists, err := handler.search.IndexExists(indexName); err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		handler.Error(res, err.Error())
+		return
+	} else if !exists {
+		res.WriteHeader(http.StatusNotFound)
+		handler.Error(res, "Index '"+indexName+"' doesn't exists.")
+		return
+	}
+
+	writer := &tarResponse{res: res, filename: indexName + ".tar"}
+
+	if _, err := handler.search.Backup(indexName, writer); err != nil {
+		if writer.written {
+			// the client detects the truncated archive by its manifest
+			log.Printf("Backup of index '%s' failed: %s", indexName, err)
+			return
+		}
+
+		res.WriteHeader(http.StatusInternalServerError)
+		handler.Error(res, err.Error())
+	}
+}
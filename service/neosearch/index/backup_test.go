@@ -0,0 +1,93 @@
+package index
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestBackup(t *testing.T) {
+	searchHandler := getSearchHandler()
+	handler := NewBackupHandler(searchHandler.search)
+
+	router := httprouter.New()
+	router.Handle("POST", "/:index/:id", handler.ServeHTTP)
+	ts := httptest.NewServer(router)
+
+	defer func() {
+		handler.search.DeleteIndex("backup-svc")
+		handler.search.DeleteIndex("backup-svc-copy")
+		ts.Close()
+		handler.search.Close()
+	}()
+
+	ind, err := handler.search.CreateIndex("backup-svc")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = ind.Add(1, []byte(`{"name": "Neoway"}`), nil); err != nil {
+		t.Error(err)
+		return
+	}
+
+	post := func(url string, body []byte) (*http.Response, []byte) {
+		res, err := http.Post(ts.URL+url, "application/x-tar", bytes.NewReader(body))
+
+		if err != nil {
+			t.Error(err)
+			return nil, nil
+		}
+
+		defer res.Body.Close()
+
+		content, err := ioutil.ReadAll(res.Body)
+
+		if err != nil {
+			t.Error(err)
+		}
+
+		return res, content
+	}
+
+	res, backup := post("/backup-svc/_backup", nil)
+
+	if res == nil || res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "application/x-tar" {
+		t.Errorf("Unexpected backup response: %v, %s", res, backup)
+		return
+	}
+
+	res, content := post("/backup-svc-copy/_restore", backup)
+	manifest := map[string]interface{}{}
+
+	if err = json.Unmarshal(content, &manifest); err != nil || res.StatusCode != http.StatusOK || manifest["index"] != "backup-svc" {
+		t.Errorf("Unexpected restore response: %s, %v", content, err)
+		return
+	}
+
+	copied, err := handler.search.OpenIndex("backup-svc-copy")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if doc, err := copied.Get(1); err != nil || string(doc) != `{"name": "Neoway"}` {
+		t.Errorf("Unexpected restored document: %s, %v", doc, err)
+	}
+
+	if res, _ = post("/backup-svc-missing/_backup", nil); res == nil || res.StatusCode != http.StatusNotFound {
+		t.Errorf("Backup of missing index should fail: %v", res)
+	}
+
+	if res, _ = post("/backup-svc-copy/_restore", backup); res == nil || res.StatusCode != http.StatusBadRequest {
+		t.Errorf("Restore of existing index should fail: %v", res)
+	}
+}
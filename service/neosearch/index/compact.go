@@ -0,0 +1,134 @@
+package index
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch"
+	nsindex "github.com/NeowayLabs/neosearch/lib/neosearch/index"
+	"github.com/NeowayLabs/neosearch/service/neosearch/handler"
+	"github.com/julienschmidt/httprouter"
+)
+
+type CompactHandler struct {
+	handler.DefaultHandler
+	search *neosearch.NeoSearch
+	tasks  *taskRegistry
+}
+
+func NewCompactHandler(search *neosearch.NeoSearch) *CompactHandler {
+	return &CompactHandler{
+		search: search,
+		tasks:  newTaskRegistry(),
+	}
+}
+
+// compactRequest is the optional body of POST /:index/_compact:
+//
+//	{"fields": ["name", "state"], "wait_for_completion": false}
+type compactRequest struct {
+	Fields            []string `json:"fields"`
+	WaitForCompletion *bool    `json:"wait_for_completion"`
+}
+
+// ServeHTTP compacts the index on POST /:index/_compact. Compactions
+// with wait_for_completion false run in background: their status is
+// returned by GET /_compact/:task and they're canceled by
+// DELETE /_compact/:task.
+func (handler *CompactHandler) ServeHTTP(res http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	var request compactRequest
+
+	handler.ProcessVars(ps)
+	indexName := handler.GetIndexName()
+
+	if indexName == "_compact" {
+		handler.serveTask(res, req, handler.GetDocumentID())
+		return
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+
+	if err == nil && len(body) > 0 {
+		err = json.Unmarshal(body, &request)
+	}
+
+	if err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		handler.Error(res, err.Error())
+		return
+	}
+
+	if exists, err := handler.search.IndexExists(indexName); err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		handler.Error(res, err.Error())
+		return
+	} else if !exists {
+		res.WriteHeader(http.StatusNotFound)
+		handler.Error(res, "Index '"+indexName+"' doesn't exists.")
+		return
+	}
+
+	index, err := handler.search.OpenIndex(indexName)
+
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		handler.Error(res, err.Error())
+		return
+	}
+
+	t := handler.tasks.start(func(t *task) error {
+		_, err := index.CompactWithOptions(nsindex.CompactOptions{
+			Fields: request.Fields,
+			Cancel: t.cancel,
+			Progress: func(progress nsindex.CompactProgress) {
+				t.setProgress(progress)
+			},
+		})
+
+		return err
+	})
+
+	if request.WaitForCompletion != nil && !*request.WaitForCompletion {
+		handler.WriteJSONObject(res, t.status())
+		return
+	}
+
+	t.wait()
+	handler.tasks.remove(t.id)
+
+	status := t.status()
+	delete(status, "task")
+
+	if status["error"] != nil {
+		res.WriteHeader(http.StatusBadRequest)
+	}
+
+	handler.WriteJSONObject(res, status)
+}
+
+func (handler *CompactHandler) serveTask(res http.ResponseWriter, req *http.Request, taskID string) {
+	var (
+		t  *task
+		ok bool
+	)
+
+	switch req.Method {
+	case "GET":
+		t, ok = handler.tasks.get(taskID)
+	case "DELETE":
+		t, ok = handler.tasks.remove(taskID)
+	default:
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		handler.Error(res, "Compaction tasks expect a GET or DELETE request")
+		return
+	}
+
+	if !ok {
+		res.WriteHeader(http.StatusNotFound)
+		handler.Error(res, "Compaction task '"+taskID+"' not found")
+		return
+	}
+
+	handler.WriteJSONObject(res, t.status())
+}
@@ -0,0 +1,128 @@
+package index
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	nsindex "github.com/NeowayLabs/neosearch/lib/neosearch/index"
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestCompact(t *testing.T) {
+	searchHandler := getSearchHandler()
+	handler := NewCompactHandler(searchHandler.search)
+
+	router := httprouter.New()
+	router.Handle("POST", "/:index/:id", handler.ServeHTTP)
+	router.Handle("GET", "/:index/:id", handler.ServeHTTP)
+	router.Handle("DELETE", "/:index/:id", handler.ServeHTTP)
+	ts := httptest.NewServer(router)
+
+	defer func() {
+		handler.search.DeleteIndex("compact-svc")
+		ts.Close()
+		handler.search.Close()
+	}()
+
+	request := func(method, url, body string) (int, map[string]interface{}) {
+		resObj := map[string]interface{}{}
+		req, err := http.NewRequest(method, ts.URL+url, bytes.NewBufferString(body))
+
+		if err != nil {
+			t.Error(err)
+			return 0, nil
+		}
+
+		res, err := http.DefaultClient.Do(req)
+
+		if err != nil {
+			t.Error(err)
+			return 0, nil
+		}
+
+		defer res.Body.Close()
+
+		if err = json.NewDecoder(res.Body).Decode(&resObj); err != nil {
+			t.Error(err)
+		}
+
+		return res.StatusCode, resObj
+	}
+
+	ind, err := handler.search.CreateIndex("compact-svc")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = ind.SetMapping(nsindex.Metadata{"state": nsindex.Metadata{"type": "keyword"}}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for id, doc := range []string{`{"state": "SC"}`, `{"state": "SP"}`} {
+		if err = ind.Add(uint64(id), []byte(doc), nil); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	if err = ind.Delete(0); err != nil {
+		t.Error(err)
+		return
+	}
+
+	status, resObj := request("POST", "/compact-svc/_compact", `{"fields": ["state"]}`)
+	progress, _ := resObj["progress"].(map[string]interface{})
+
+	if status != http.StatusOK || resObj["done"] != true || progress["compacted"] != float64(1) || progress["pruned"] != float64(1) {
+		t.Errorf("Unexpected compaction: %d, %v", status, resObj)
+		return
+	}
+
+	status, resObj = request("POST", "/compact-svc/_compact", `{"wait_for_completion": false}`)
+	taskID, _ := resObj["task"].(string)
+
+	if status != http.StatusOK || taskID == "" {
+		t.Errorf("Unexpected background compaction: %d, %v", status, resObj)
+		return
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if _, resObj = request("GET", "/_compact/"+taskID, ""); resObj["done"] == true {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Errorf("Compaction task not done: %v", resObj)
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if progress, _ = resObj["progress"].(map[string]interface{}); progress["compacted"] != progress["total"] || resObj["error"] != nil {
+		t.Errorf("Unexpected background compaction status: %v", resObj)
+	}
+
+	if status, _ = request("DELETE", "/_compact/"+taskID, ""); status != http.StatusOK {
+		t.Errorf("Removal of the task failed: %d", status)
+	}
+
+	if status, _ = request("GET", "/_compact/"+taskID, ""); status != http.StatusNotFound {
+		t.Errorf("Removed tasks should not be found: %d", status)
+	}
+
+	if status, _ = request("POST", "/compact-svc/_compact", `{"fields": ["unmapped"]}`); status != http.StatusBadRequest {
+		t.Errorf("Compaction of unmapped fields should fail: %d", status)
+	}
+
+	if status, _ = request("POST", "/compact-missing/_compact", ""); status != http.StatusNotFound {
+		t.Errorf("Compaction of missing index should fail: %d", status)
+	}
+}
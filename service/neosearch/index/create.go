@@ -1,10 +1,13 @@
 package index
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 
 	"github.com/NeowayLabs/neosearch/lib/neosearch"
+	nsindex "github.com/NeowayLabs/neosearch/lib/neosearch/index"
 	"github.com/NeowayLabs/neosearch/service/neosearch/handler"
 	"github.com/julienschmidt/httprouter"
 )
@@ -36,23 +39,73 @@ func (handler *CreateIndexHandler) ServeHTTP(res http.ResponseWriter, req *http.
 		return
 	}
 
-	body, err := handler.createIndex(indexName)
+	options, err := handler.readOptions(req)
 
 	if err != nil {
-		handler.Error(res, string(body))
+		res.WriteHeader(http.StatusBadRequest)
+		handler.Error(res, err.Error())
+		return
+	}
+
+	body, err := handler.createIndex(indexName, options)
+
+	if err != nil {
+		handler.Error(res, err.Error())
 		return
 	}
 
 	handler.WriteJSON(res, body)
 }
 
-func (handler *CreateIndexHandler) createIndex(name string) ([]byte, error) {
-	_, err := handler.search.CreateIndex(name)
+// indexOptions are the optional index settings and mapping of the
+// request body:
+//
+//	{"settings": {"dynamic": true, "conflict": "reject"},
+//	 "mapping": {"<field>": {"type": "<type>"}}}
+type indexOptions struct {
+	Settings nsindex.Settings `json:"settings"`
+	Mapping  nsindex.Metadata `json:"mapping"`
+}
+
+func (handler *CreateIndexHandler) readOptions(req *http.Request) (indexOptions, error) {
+	options := indexOptions{}
+	content, err := ioutil.ReadAll(req.Body)
+
+	if err != nil || len(content) == 0 {
+		return options, err
+	}
+
+	err = json.Unmarshal(content, &options)
+	return options, err
+}
+
+func (handler *CreateIndexHandler) createIndex(name string, options indexOptions) ([]byte, error) {
+	if err := nsindex.ValidateMapping(options.Mapping); err != nil {
+		return nil, err
+	}
+
+	if err := options.Settings.Validate(); err != nil {
+		return nil, err
+	}
+
+	index, err := handler.search.CreateIndex(name)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if options.Settings != (nsindex.Settings{}) {
+		if err = index.SetSettings(options.Settings); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(options.Mapping) > 0 {
+		if err = index.SetMapping(options.Mapping); err != nil {
+			return nil, err
+		}
+	}
+
 	response := []byte(fmt.Sprintf("{\"status\": \"Index '%s' created.\"}", name))
 	return response, nil
 }
@@ -33,7 +33,7 @@ func TestCreateIndexOK(t *testing.T) {
 		"apple",
 		"sucks",
 	} {
-		body, err := handler.createIndex(name)
+		body, err := handler.createIndex(name, indexOptions{})
 
 		if err != nil {
 			t.Error(err)
@@ -65,7 +65,7 @@ func TestCreateIndexFail(t *testing.T) {
 		"a",
 		"aa",
 	} {
-		body, err := handler.createIndex(name)
+		body, err := handler.createIndex(name, indexOptions{})
 
 		if err == nil {
 			t.Errorf("Invalid index name '%s' should fail", name)
@@ -0,0 +1,50 @@
+package index
+
+import (
+	"net/http"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch"
+	"github.com/NeowayLabs/neosearch/service/neosearch/handler"
+	"github.com/julienschmidt/httprouter"
+)
+
+type MappingHandler struct {
+	handler.DefaultHandler
+	search *neosearch.NeoSearch
+}
+
+func NewMappingHandler(search *neosearch.NeoSearch) *MappingHandler {
+	return &MappingHandler{
+		search: search,
+	}
+}
+
+func (handler *MappingHandler) ServeHTTP(res http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	handler.ProcessVars(ps)
+	indexName := handler.GetIndexName()
+
+	if exists, err := handler.search.IndexExists(indexName); exists != true && err == nil {
+		response := map[string]string{
+			"error": "Index '" + indexName + "' doesn't exists.",
+		}
+
+		handler.WriteJSONObject(res, response)
+		return
+	} else if exists == false && err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		handler.Error(res, err.Error())
+		return
+	}
+
+	index, err := handler.search.OpenIndex(indexName)
+
+	if err != nil {
+		handler.Error(res, err.Error())
+		return
+	}
+
+	handler.WriteJSONObject(res, map[string]interface{}{
+		"mapping":  index.Mapping(),
+		"settings": index.Settings(),
+	})
+}
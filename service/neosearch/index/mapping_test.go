@@ -0,0 +1,124 @@
+package index
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/config"
+	nsindex "github.com/NeowayLabs/neosearch/lib/neosearch/index"
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestCreateAndGetMapping(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.Option(config.DataDir(dataDirTmp))
+	ns := neosearch.New(cfg)
+
+	createHandler := NewCreateHandler(ns)
+	mappingHandler := NewMappingHandler(ns)
+
+	router := httprouter.New()
+
+	router.Handle("PUT", "/:index", createHandler.ServeHTTP)
+	router.Handle("GET", "/:index/_mapping", mappingHandler.ServeHTTP)
+
+	ts := httptest.NewServer(router)
+
+	defer func() {
+		ns.DeleteIndex("test-mapping")
+		ts.Close()
+		ns.Close()
+	}()
+
+	client := &http.Client{}
+
+	req, err := http.NewRequest("PUT", ts.URL+"/test-mapping",
+		bytes.NewBufferString(`{
+			"settings": {"dynamic": true, "conflict": "reject"},
+			"mapping": {"id": {"type": "uint"}}
+		}`))
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res, err := client.Do(req)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res.Body.Close()
+
+	res, err = http.Get(ts.URL + "/test-mapping/_mapping")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	content, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	resObj := struct {
+		Mapping  map[string]map[string]string `json:"mapping"`
+		Settings nsindex.Settings             `json:"settings"`
+	}{}
+
+	if err = json.Unmarshal(content, &resObj); err != nil {
+		t.Errorf("Invalid mapping response: %s", string(content))
+		return
+	}
+
+	if resObj.Mapping["id"]["type"] != "uint" {
+		t.Errorf("Unexpected mapping: %s", string(content))
+	}
+
+	if !resObj.Settings.Dynamic || resObj.Settings.Conflict != nsindex.ConflictReject {
+		t.Errorf("Unexpected settings: %s", string(content))
+	}
+}
+
+func TestCreateIndexInvalidMapping(t *testing.T) {
+	handler := getCreateHandler()
+
+	defer func() {
+		handler.search.Close()
+	}()
+
+	body, err := handler.createIndex("test-invalid-mapping", indexOptions{
+		Mapping: map[string]interface{}{
+			"id": map[string]interface{}{
+				"type": "complex",
+			},
+		},
+	})
+
+	if err == nil || body != nil {
+		t.Error("Invalid mapping should fail")
+		deleteIndex(t, handler.search, "test-invalid-mapping")
+	}
+
+	body, err = handler.createIndex("test-invalid-settings", indexOptions{
+		Settings: nsindex.Settings{
+			Conflict: "ignore",
+		},
+	})
+
+	if err == nil || body != nil {
+		t.Error("Invalid conflict policy should fail")
+		deleteIndex(t, handler.search, "test-invalid-settings")
+	}
+}
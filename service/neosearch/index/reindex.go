@@ -0,0 +1,164 @@
+package index
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch"
+	nsindex "github.com/NeowayLabs/neosearch/lib/neosearch/index"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/search"
+	"github.com/NeowayLabs/neosearch/service/neosearch/handler"
+	"github.com/julienschmidt/httprouter"
+)
+
+type ReindexHandler struct {
+	handler.DefaultHandler
+	search *neosearch.NeoSearch
+	tasks  *taskRegistry
+}
+
+func NewReindexHandler(search *neosearch.NeoSearch) *ReindexHandler {
+	return &ReindexHandler{
+		search: search,
+		tasks:  newTaskRegistry(),
+	}
+}
+
+// reindexRequest is the body of POST /_reindex:
+//
+//	{"source": {"index": "companies", "query": {"$and": [{"kind": "company"}]}},
+//	 "dest": {"index": "companies_v2", "settings": {...}, "mapping": {...}},
+//	 "transform": {"remove": ["tmp"], "rename": {"title": "name"}, "set": {"version": 2}},
+//	 "batch_size": 1000,
+//	 "wait_for_completion": false}
+type reindexRequest struct {
+	Source struct {
+		Index string     `json:"index"`
+		Query search.DSL `json:"query"`
+	} `json:"source"`
+
+	Dest struct {
+		Index    string            `json:"index"`
+		Settings *nsindex.Settings `json:"settings"`
+		Mapping  nsindex.Metadata  `json:"mapping"`
+	} `json:"dest"`
+
+	Transform         *neosearch.FieldTransform `json:"transform"`
+	BatchSize         int                       `json:"batch_size"`
+	WaitForCompletion *bool                     `json:"wait_for_completion"`
+}
+
+// ServeHTTP reindexes on POST /_reindex. Reindexes not waited for run in
+// background: their status is returned by GET /_reindex/:task and they're
+// canceled by DELETE /_reindex/:task.
+func (handler *ReindexHandler) ServeHTTP(res http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	handler.ProcessVars(ps)
+
+	if taskID := handler.GetDocumentID(); taskID != "" {
+		handler.serveTask(res, req, taskID)
+		return
+	}
+
+	if req.Method != "POST" {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		handler.Error(res, "Reindex expect a POST request")
+		return
+	}
+
+	request, err := readReindexRequest(req)
+
+	if err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		handler.Error(res, err.Error())
+		return
+	}
+
+	opts := neosearch.ReindexOptions{
+		Query:     request.Source.Query,
+		Settings:  request.Dest.Settings,
+		Mapping:   request.Dest.Mapping,
+		BatchSize: request.BatchSize,
+	}
+
+	if request.Transform != nil {
+		opts.Transform = request.Transform.Apply
+	}
+
+	t := handler.tasks.start(func(t *task) error {
+		opts.Cancel = t.cancel
+		opts.Progress = func(progress neosearch.ReindexProgress) {
+			t.setProgress(progress)
+		}
+
+		_, err := handler.search.Reindex(request.Source.Index, request.Dest.Index, opts)
+		return err
+	})
+
+	if request.WaitForCompletion != nil && !*request.WaitForCompletion {
+		handler.WriteJSONObject(res, t.status())
+		return
+	}
+
+	t.wait()
+	handler.tasks.remove(t.id)
+
+	status := t.status()
+	delete(status, "task")
+
+	if status["error"] != nil {
+		res.WriteHeader(http.StatusBadRequest)
+	}
+
+	handler.WriteJSONObject(res, status)
+}
+
+func (handler *ReindexHandler) serveTask(res http.ResponseWriter, req *http.Request, taskID string) {
+	var (
+		t  *task
+		ok bool
+	)
+
+	switch req.Method {
+	case "GET":
+		t, ok = handler.tasks.get(taskID)
+	case "DELETE":
+		t, ok = handler.tasks.remove(taskID)
+	default:
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		handler.Error(res, "Reindex tasks expect a GET or DELETE request")
+		return
+	}
+
+	if !ok {
+		res.WriteHeader(http.StatusNotFound)
+		handler.Error(res, "Reindex task '"+taskID+"' not found")
+		return
+	}
+
+	handler.WriteJSONObject(res, t.status())
+}
+
+func readReindexRequest(req *http.Request) (*reindexRequest, error) {
+	request := &reindexRequest{}
+	body, err := ioutil.ReadAll(req.Body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(body, request); err != nil {
+		return nil, err
+	}
+
+	if request.Source.Index == "" || request.Dest.Index == "" {
+		return nil, errors.New("Reindex 'source' and 'dest' require an index")
+	}
+
+	if !nsindex.ValidateIndexName(request.Dest.Index) {
+		return nil, errors.New("Invalid index name: " + request.Dest.Index)
+	}
+
+	return request, nil
+}
@@ -0,0 +1,146 @@
+package index
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestReindex(t *testing.T) {
+	searchHandler := getSearchHandler()
+	handler := NewReindexHandler(searchHandler.search)
+
+	router := httprouter.New()
+	router.Handle("POST", "/_reindex", handler.ServeHTTP)
+	router.Handle("GET", "/_reindex/:id", handler.ServeHTTP)
+	router.Handle("DELETE", "/_reindex/:id", handler.ServeHTTP)
+	router.Handle("POST", "/search/:index", searchHandler.ServeHTTP)
+	ts := httptest.NewServer(router)
+
+	defer func() {
+		for _, name := range []string{"reindex-src", "reindex-dst", "reindex-bg"} {
+			handler.search.DeleteIndex(name)
+		}
+
+		ts.Close()
+		handler.search.Close()
+	}()
+
+	request := func(method, url, body string) (int, map[string]interface{}) {
+		resObj := map[string]interface{}{}
+		req, err := http.NewRequest(method, ts.URL+url, bytes.NewBufferString(body))
+
+		if err != nil {
+			t.Error(err)
+			return 0, nil
+		}
+
+		res, err := http.DefaultClient.Do(req)
+
+		if err != nil {
+			t.Error(err)
+			return 0, nil
+		}
+
+		defer res.Body.Close()
+
+		if err = json.NewDecoder(res.Body).Decode(&resObj); err != nil {
+			t.Error(err)
+		}
+
+		return res.StatusCode, resObj
+	}
+
+	ind, err := handler.search.CreateIndex("reindex-src")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for i, doc := range []string{
+		`{"kind": "company", "title": "Neoway"}`,
+		`{"kind": "person", "title": "Alice"}`,
+		`{"kind": "company", "title": "Google"}`,
+	} {
+		if err = ind.Add(uint64(i), []byte(doc), nil); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	status, resObj := request("POST", "/_reindex", `{
+		"source": {"index": "reindex-src", "query": {"$and": [{"kind": "company"}]}},
+		"dest": {"index": "reindex-dst", "mapping": {"name": {"type": "string"}}},
+		"transform": {"rename": {"title": "name"}, "set": {"version": 2}}
+	}`)
+
+	progress, _ := resObj["progress"].(map[string]interface{})
+
+	if status != http.StatusOK || resObj["done"] != true || progress["indexed"] != float64(2) || progress["skipped"] != float64(1) {
+		t.Errorf("Unexpected reindex: %d, %v", status, resObj)
+		return
+	}
+
+	resObj = doSearch(t, ts.URL+"/search/reindex-dst", `{"query": {"$and": [{"name": "google"}]}}`)
+	results, _ := resObj["results"].([]interface{})
+
+	if len(results) != 1 || results[0].(map[string]interface{})["version"] != float64(2) {
+		t.Errorf("Unexpected results of reindexed index: %v", resObj)
+		return
+	}
+
+	status, resObj = request("POST", "/_reindex", `{
+		"source": {"index": "reindex-src"},
+		"dest": {"index": "reindex-bg"},
+		"batch_size": 1,
+		"wait_for_completion": false
+	}`)
+
+	taskID, _ := resObj["task"].(string)
+
+	if status != http.StatusOK || taskID == "" {
+		t.Errorf("Unexpected background reindex: %d, %v", status, resObj)
+		return
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if _, resObj = request("GET", "/_reindex/"+taskID, ""); resObj["done"] == true {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Errorf("Reindex task not done: %v", resObj)
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if progress, _ = resObj["progress"].(map[string]interface{}); progress["indexed"] != float64(3) || resObj["error"] != nil {
+		t.Errorf("Unexpected background reindex status: %v", resObj)
+	}
+
+	if status, _ = request("DELETE", "/_reindex/"+taskID, ""); status != http.StatusOK {
+		t.Errorf("Removal of the task failed: %d", status)
+	}
+
+	if status, _ = request("GET", "/_reindex/"+taskID, ""); status != http.StatusNotFound {
+		t.Errorf("Removed tasks should not be found: %d", status)
+	}
+
+	for _, body := range []string{
+		`{"source": {"index": "reindex-src"}}`,
+		`{"source": {"index": "reindex-missing"}, "dest": {"index": "reindex-x"}}`,
+		`{"source": {"index": "reindex-src"}, "dest": {"index": "reindex-src"}}`,
+	} {
+		if status, _ = request("POST", "/_reindex", body); status != http.StatusBadRequest {
+			t.Errorf("Invalid reindex should fail: %s", body)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package index
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -12,6 +13,14 @@ import (
 	"github.com/julienschmidt/httprouter"
 )
 
+const (
+	// defaultSize is the page size of search requests without "size"
+	defaultSize uint = 10
+
+	// maxSize is the largest page size of search requests
+	maxSize uint = 10000
+)
+
 type SearchHandler struct {
 	handler.DefaultHandler
 	search *neosearch.NeoSearch
@@ -26,23 +35,17 @@ func NewSearchHandler(search *neosearch.NeoSearch) *SearchHandler {
 func (handler *SearchHandler) ServeHTTP(res http.ResponseWriter, req *http.Request, ps httprouter.Params) {
 	var (
 		err        error
-		exists     bool
 		documents  []map[string]interface{}
 		outputJSON []byte
 	)
 
 	handler.ProcessVars(ps)
-	indexName := handler.GetIndexName()
 
-	if exists, err = handler.search.IndexExists(indexName); exists != true && err == nil {
-		response := map[string]string{
-			"error": "Index '" + indexName + "' doesn't exists.",
-		}
+	// comma separated list of indices and wildcards, eg.: companies-*
+	indexName := handler.GetIndexName()
+	indices, err := handler.search.ResolveIndices(indexName)
 
-		handler.WriteJSONObject(res, response)
-		return
-	} else if exists == false && err != nil {
-		res.WriteHeader(http.StatusInternalServerError)
+	if err != nil {
 		handler.Error(res, err.Error())
 		return
 	}
@@ -65,13 +68,6 @@ func (handler *SearchHandler) ServeHTTP(res http.ResponseWriter, req *http.Reque
 		return
 	}
 
-	index, err := handler.search.OpenIndex(indexName)
-
-	if err != nil {
-		handler.Error(res, err.Error())
-		return
-	}
-
 	if dsl["query"] == nil {
 		res.WriteHeader(http.StatusBadRequest)
 		handler.Error(res, "No query field specified")
@@ -87,9 +83,20 @@ func (handler *SearchHandler) ServeHTTP(res http.ResponseWriter, req *http.Reque
 	}
 
 	output := make(map[string]interface{})
-	var total uint64
+	var (
+		hits  []search.Hit
+		total uint64
+	)
+
+	sortFields, err := search.ParseSort(dsl["sort"])
+
+	if err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		handler.Error(res, err.Error())
+		return
+	}
 
-	docs, total, err := search.Search(index, query, 10)
+	opts, err := pagingOptions(dsl, dslBytes)
 
 	if err != nil {
 		res.WriteHeader(http.StatusBadRequest)
@@ -97,23 +104,79 @@ func (handler *SearchHandler) ServeHTTP(res http.ResponseWriter, req *http.Reque
 		return
 	}
 
-	documents = make([]map[string]interface{}, len(docs))
+	opts.Sort = sortFields
 
-	for idx, doc := range docs {
+	if opts.Aggs, err = search.ParseAggs(dsl["aggs"]); err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		handler.Error(res, err.Error())
+		return
+	}
+
+	if opts.Lookups, err = search.ParseLookups(dsl["lookup"]); err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		handler.Error(res, err.Error())
+		return
+	}
+
+	results, err := handler.search.Search(indexName, query, opts)
+
+	if err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		handler.Error(res, err.Error())
+		return
+	}
+
+	hits, total = results.Hits, results.Total
+	documents = make([]map[string]interface{}, len(hits))
+
+	for idx, hit := range hits {
 		obj := make(map[string]interface{})
-		err = json.Unmarshal([]byte(doc), &obj)
+		err = json.Unmarshal([]byte(hit.Doc), &obj)
 
 		if err != nil {
-			fmt.Println("Failed to unmarshal: ", doc)
+			fmt.Println("Failed to unmarshal: ", hit.Doc)
 			goto error
 		}
 
+		obj["_score"] = hit.Score
+		obj["_index"] = hit.Index
+
+		if len(hit.Joined) > 0 {
+			if obj["_joined"], err = decodeRelated(hit.Joined); err != nil {
+				goto error
+			}
+		}
+
+		if len(hit.Lookups) > 0 {
+			if obj["_lookups"], err = decodeRelated(hit.Lookups); err != nil {
+				goto error
+			}
+		}
+
 		documents[idx] = obj
 	}
 
 	output["total"] = total
 	output["results"] = documents
 
+	if len(results.Aggs) > 0 {
+		aggs := make(map[string]interface{}, len(results.Aggs))
+
+		for name, result := range results.Aggs {
+			aggs[name] = result.Output()
+		}
+
+		output["aggregations"] = aggs
+	}
+
+	if len(hits) > 0 && results.Remaining > 0 {
+		if len(indices) > 1 {
+			output["next"] = search.IndexCursor(hits[len(hits)-1])
+		} else {
+			output["next"] = search.Cursor(hits[len(hits)-1])
+		}
+	}
+
 	outputJSON, err = json.Marshal(output)
 
 	if err != nil {
@@ -132,3 +195,82 @@ error:
 		return
 	}
 }
+
+// pagingOptions returns the search options of the "from", "size" and
+// "search_after" fields of the request. The cursor is decoded again from
+// the raw request to keep the precision of big integers.
+func pagingOptions(dsl map[string]interface{}, dslBytes []byte) (search.Options, error) {
+	var (
+		opts = search.Options{Limit: defaultSize}
+		err  error
+	)
+
+	if opts.From, err = uintOption(dsl, "from", 0); err != nil {
+		return opts, err
+	}
+
+	if opts.Limit, err = uintOption(dsl, "size", defaultSize); err != nil {
+		return opts, err
+	}
+
+	if opts.Limit > maxSize {
+		return opts, fmt.Errorf("Search 'size' field must not exceed %d", maxSize)
+	}
+
+	if dsl["search_after"] == nil {
+		return opts, nil
+	}
+
+	if _, ok := dsl["search_after"].([]interface{}); !ok {
+		return opts, fmt.Errorf("Search 'search_after' field is not a JSON array")
+	}
+
+	var paging struct {
+		SearchAfter []interface{} `json:"search_after"`
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(dslBytes))
+	decoder.UseNumber()
+
+	if err = decoder.Decode(&paging); err != nil {
+		return opts, err
+	}
+
+	opts.SearchAfter = paging.SearchAfter
+	return opts, nil
+}
+
+// uintOption returns the non-negative integer field name of dsl
+func uintOption(dsl map[string]interface{}, name string, def uint) (uint, error) {
+	value, ok := dsl[name]
+
+	if !ok || value == nil {
+		return def, nil
+	}
+
+	number, ok := value.(float64)
+
+	if !ok || number < 0 || number != float64(uint(number)) {
+		return 0, fmt.Errorf("Search '%s' field must be a non-negative integer", name)
+	}
+
+	return uint(number), nil
+}
+
+// decodeRelated decodes the related documents of a hit, from joins or
+// lookups, by name
+func decodeRelated(related map[string][]string) (map[string][]interface{}, error) {
+	objs := make(map[string][]interface{}, len(related))
+
+	for name, docs := range related {
+		objs[name] = make([]interface{}, len(docs))
+
+		for idx, doc := range docs {
+			if err := json.Unmarshal([]byte(doc), &objs[name][idx]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return objs, nil
+}
@@ -3,13 +3,16 @@ package index
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 
 	"github.com/NeowayLabs/neosearch/lib/neosearch"
 	"github.com/NeowayLabs/neosearch/lib/neosearch/config"
+	nsindex "github.com/NeowayLabs/neosearch/lib/neosearch/index"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -241,3 +244,1069 @@ func TestSimpleANDSearch(t *testing.T) {
 		t.Errorf("Invalid result: %+v", r)
 	}
 }
+
+// doSearch posts the dsl to searchURL and returns the decoded response
+func doSearch(t *testing.T, searchURL, dsl string) map[string]interface{} {
+	res, err := http.Post(searchURL, "application/json", bytes.NewBufferString(dsl))
+
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+
+	content, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+
+	resObj := map[string]interface{}{}
+
+	if err = json.Unmarshal(content, &resObj); err != nil {
+		t.Errorf("Invalid response: %s", string(content))
+		return nil
+	}
+
+	if resObj["error"] != nil {
+		t.Error(resObj["error"])
+		return nil
+	}
+
+	return resObj
+}
+
+func TestFieldlessSearch(t *testing.T) {
+	handler := getSearchHandler()
+
+	router := httprouter.New()
+	router.Handle("POST", "/:index", handler.ServeHTTP)
+	ts := httptest.NewServer(router)
+
+	defer func() {
+		handler.search.DeleteIndex("search-fieldless")
+		ts.Close()
+		handler.search.Close()
+	}()
+
+	ind, err := handler.search.CreateIndex("search-fieldless")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = ind.SetSettings(nsindex.Settings{All: true}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for i, doc := range []string{
+		`{"name": "Neoway Business Solution", "city": "Florianopolis"}`,
+		`{"name": "Google Inc", "city": "Mountain View"}`,
+	} {
+		if err = ind.Add(uint64(i), []byte(doc), nil); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	resObj := doSearch(t, ts.URL+"/search-fieldless", `{"query": {"$and": ["florianopolis"]}}`)
+
+	if resObj == nil {
+		return
+	}
+
+	if total, _ := resObj["total"].(float64); total != 1 {
+		t.Errorf("Field-less clause must search every field: %+v", resObj)
+	}
+
+	resObj = doSearch(t, ts.URL+"/search-fieldless", `{"query": {"$and": ["google", {"city": "view"}]}}`)
+
+	if resObj == nil {
+		return
+	}
+
+	if total, _ := resObj["total"].(float64); total != 1 {
+		t.Errorf("Unexpected result: %+v", resObj)
+	}
+}
+
+func TestSearchScoring(t *testing.T) {
+	handler := getSearchHandler()
+
+	router := httprouter.New()
+	router.Handle("POST", "/:index", handler.ServeHTTP)
+	ts := httptest.NewServer(router)
+
+	defer func() {
+		handler.search.DeleteIndex("search-scoring")
+		ts.Close()
+		handler.search.Close()
+	}()
+
+	ind, err := handler.search.CreateIndex("search-scoring")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = ind.SetSettings(nsindex.Settings{Similarity: nsindex.SimilarityBM25}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for i, doc := range []string{
+		`{"id": 0, "name": "Neoway Business Solution", "city": "Florianopolis"}`,
+		`{"id": 1, "name": "Neoway", "city": "Sao Paulo"}`,
+		`{"id": 2, "name": "Google Inc", "city": "Florianopolis"}`,
+	} {
+		if err = ind.Add(uint64(i), []byte(doc), nil); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	for _, tc := range []struct {
+		dsl      string
+		expected []float64
+	}{
+		// shorter fields rank first
+		{`{"query": {"$and": [{"name": "neoway"}]}}`, []float64{1, 0}},
+		{`{"query": {"$or": [{"name": "neoway"}, {"city": "florianopolis"}]}}`, []float64{0, 1, 2}},
+		{`{"query": {"$or": [{"name": "neoway"}, {"city": "florianopolis", "$boost": 10}]}}`, []float64{0, 2, 1}},
+	} {
+		resObj := doSearch(t, ts.URL+"/search-scoring", tc.dsl)
+
+		if resObj == nil {
+			return
+		}
+
+		results, _ := resObj["results"].([]interface{})
+
+		if len(results) != len(tc.expected) {
+			t.Errorf("Unexpected results for %s: %+v", tc.dsl, resObj)
+			return
+		}
+
+		for i, result := range results {
+			doc := result.(map[string]interface{})
+
+			if doc["id"] != tc.expected[i] {
+				t.Errorf("Unexpected order for %s: %+v", tc.dsl, results)
+				return
+			}
+
+			if score, ok := doc["_score"].(float64); !ok || score <= 0 {
+				t.Errorf("Invalid _score: %+v", doc)
+				return
+			}
+		}
+	}
+}
+
+func TestSearchSort(t *testing.T) {
+	handler := getSearchHandler()
+
+	router := httprouter.New()
+	router.Handle("POST", "/:index", handler.ServeHTTP)
+	ts := httptest.NewServer(router)
+
+	defer func() {
+		handler.search.DeleteIndex("search-sort")
+		ts.Close()
+		handler.search.Close()
+	}()
+
+	ind, err := handler.search.CreateIndex("search-sort")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = ind.SetMapping(nsindex.Metadata{
+		"id":         nsindex.Metadata{"type": "uint"},
+		"revenue":    nsindex.Metadata{"type": "float", "doc_values": true},
+		"founded_at": nsindex.Metadata{"type": "date", "format": nsindex.DateISO8601, "doc_values": true},
+		"city":       nsindex.Metadata{"type": "string"},
+	})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for i, doc := range []string{
+		`{"id": 0, "revenue": 10.5, "founded_at": "2002-01-01", "city": "Florianopolis"}`,
+		`{"id": 1, "revenue": 300, "founded_at": "1998-09-04", "city": "Florianopolis"}`,
+		`{"id": 2, "revenue": 10.5, "founded_at": "2004-02-04", "city": "Florianopolis"}`,
+		`{"id": 3, "city": "Florianopolis"}`,
+	} {
+		if err = ind.Add(uint64(i), []byte(doc), nil); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	for _, tc := range []struct {
+		dsl      string
+		expected []float64
+	}{
+		{`{"query": {"$and": [{"city": "florianopolis"}]}, "sort": "revenue"}`, []float64{0, 2, 1, 3}},
+		{`{"query": {"$and": [{"city": "florianopolis"}]}, "sort": {"revenue": "desc"}}`, []float64{1, 0, 2, 3}},
+		{`{"query": {"$and": [{"city": "florianopolis"}]}, "sort": [{"revenue": "desc"}, {"founded_at": "desc"}]}`, []float64{1, 2, 0, 3}},
+		{`{"query": {"$and": [{"city": "florianopolis"}]}, "sort": [{"founded_at": "asc"}]}`, []float64{1, 0, 2, 3}},
+	} {
+		resObj := doSearch(t, ts.URL+"/search-sort", tc.dsl)
+
+		if resObj == nil {
+			return
+		}
+
+		results, _ := resObj["results"].([]interface{})
+
+		if len(results) != len(tc.expected) {
+			t.Errorf("Unexpected results for %s: %+v", tc.dsl, resObj)
+			return
+		}
+
+		for i, result := range results {
+			if result.(map[string]interface{})["id"] != tc.expected[i] {
+				t.Errorf("Unexpected order for %s: %+v", tc.dsl, results)
+				return
+			}
+		}
+	}
+
+	res, err := http.Post(ts.URL+"/search-sort", "application/json",
+		bytes.NewBufferString(`{"query": {"$and": [{"city": "florianopolis"}]}, "sort": "city"}`))
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res.Body.Close()
+
+	if res.StatusCode == http.StatusOK {
+		t.Error("Sorting by fields without doc values should fail")
+	}
+}
+
+func TestSearchPaging(t *testing.T) {
+	handler := getSearchHandler()
+
+	router := httprouter.New()
+	router.Handle("POST", "/:index", handler.ServeHTTP)
+	ts := httptest.NewServer(router)
+
+	defer func() {
+		handler.search.DeleteIndex("search-paging")
+		ts.Close()
+		handler.search.Close()
+	}()
+
+	ind, err := handler.search.CreateIndex("search-paging")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = ind.SetMapping(nsindex.Metadata{
+		"id":      nsindex.Metadata{"type": "uint"},
+		"revenue": nsindex.Metadata{"type": "int", "doc_values": true},
+		"city":    nsindex.Metadata{"type": "string"},
+	})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for i := 0; i < 7; i++ {
+		doc := fmt.Sprintf(`{"id": %d, "revenue": %d, "city": "Florianopolis"}`, i, 100-(i%3))
+
+		if err = ind.Add(uint64(i), []byte(doc), nil); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	pageIDs := func(resObj map[string]interface{}) []float64 {
+		ids := []float64{}
+		results, _ := resObj["results"].([]interface{})
+
+		for _, result := range results {
+			ids = append(ids, result.(map[string]interface{})["id"].(float64))
+		}
+
+		return ids
+	}
+
+	query := `"query": {"$and": [{"city": "florianopolis"}]}, "sort": {"revenue": "asc"}`
+
+	resObj := doSearch(t, ts.URL+"/search-paging", `{`+query+`, "from": 2, "size": 3}`)
+
+	if resObj == nil {
+		return
+	}
+
+	// revenue: 98 (2, 5), 99 (1, 4), 100 (0, 3, 6)
+	if ids := pageIDs(resObj); !reflect.DeepEqual(ids, []float64{1, 4, 0}) || resObj["total"] != float64(7) {
+		t.Errorf("Unexpected page: %+v", resObj)
+		return
+	}
+
+	var (
+		ids    []float64
+		cursor = "null"
+		second string
+	)
+
+	for page := 0; page < 5; page++ {
+		resObj = doSearch(t, ts.URL+"/search-paging", `{`+query+`, "size": 3, "search_after": `+cursor+`}`)
+
+		if resObj == nil {
+			return
+		}
+
+		ids = append(ids, pageIDs(resObj)...)
+
+		if resObj["next"] == nil {
+			break
+		}
+
+		next, _ := json.Marshal(resObj["next"])
+		cursor = string(next)
+
+		if page == 0 {
+			second = cursor
+		}
+	}
+
+	if !reflect.DeepEqual(ids, []float64{2, 5, 1, 4, 0, 3, 6}) {
+		t.Errorf("Unexpected search_after pages: %v", ids)
+		return
+	}
+
+	// a full last page has no cursor
+	resObj = doSearch(t, ts.URL+"/search-paging", `{`+query+`, "size": 4, "search_after": `+second+`}`)
+
+	if resObj == nil {
+		return
+	}
+
+	if ids := pageIDs(resObj); !reflect.DeepEqual(ids, []float64{4, 0, 3, 6}) || resObj["next"] != nil {
+		t.Errorf("Unexpected last page: %+v", resObj)
+		return
+	}
+
+	for _, dsl := range []string{
+		`{` + query + `, "size": -1}`,
+		`{` + query + `, "size": 10001}`,
+		`{` + query + `, "from": 1.5}`,
+		`{` + query + `, "search_after": [100]}`,
+		`{` + query + `, "search_after": ["a", 1]}`,
+	} {
+		res, err := http.Post(ts.URL+"/search-paging", "application/json", bytes.NewBufferString(dsl))
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("Invalid paging should fail: %s", dsl)
+		}
+	}
+}
+
+func TestSearchTermsAggregation(t *testing.T) {
+	handler := getSearchHandler()
+
+	router := httprouter.New()
+	router.Handle("POST", "/:index", handler.ServeHTTP)
+	ts := httptest.NewServer(router)
+
+	defer func() {
+		handler.search.DeleteIndex("search-terms-agg")
+		ts.Close()
+		handler.search.Close()
+	}()
+
+	ind, err := handler.search.CreateIndex("search-terms-agg")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = ind.SetMapping(nsindex.Metadata{
+		"kind":  nsindex.Metadata{"type": "string"},
+		"state": nsindex.Metadata{"type": "keyword"},
+		"city": nsindex.Metadata{
+			"type":   "string",
+			"fields": nsindex.Metadata{"raw": nsindex.Metadata{"type": "keyword"}},
+		},
+	})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for i, doc := range []string{
+		`{"kind": "company", "state": "SC", "city": "Florianopolis"}`,
+		`{"kind": "company", "state": "SP", "city": "Sao Paulo"}`,
+		`{"kind": "company", "state": "SC", "city": "Florianopolis"}`,
+		`{"kind": "company", "state": "RJ", "city": "Rio de Janeiro"}`,
+		`{"kind": "company", "state": "SP", "city": "Sao Jose"}`,
+		`{"kind": "company", "state": "SC", "city": "Blumenau"}`,
+		`{"kind": "person", "state": "RJ", "city": "Rio de Janeiro"}`,
+	} {
+		if err = ind.Add(uint64(i), []byte(doc), nil); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	resObj := doSearch(t, ts.URL+"/search-terms-agg", `{
+		"query": {"$and": [{"kind": "company"}]},
+		"size": 0,
+		"aggs": {"by_state": {"terms": {"field": "state", "size": 2}}}
+	}`)
+
+	if resObj == nil {
+		return
+	}
+
+	aggs, _ := resObj["aggregations"].(map[string]interface{})
+	expected := map[string]interface{}{
+		"buckets": []interface{}{
+			map[string]interface{}{"key": "SC", "doc_count": float64(3)},
+			map[string]interface{}{"key": "SP", "doc_count": float64(2)},
+		},
+		"sum_other_doc_count": float64(1),
+	}
+
+	if !reflect.DeepEqual(aggs["by_state"], expected) || resObj["total"] != float64(6) {
+		t.Errorf("Unexpected aggregation: %+v", resObj)
+	}
+
+	// analyzed strings are aggregated by their keyword sub-field
+	resObj = doSearch(t, ts.URL+"/search-terms-agg", `{
+		"query": {"$and": [{"state": "SP"}]},
+		"aggs": {"by_city": {"terms": {"field": "city.raw"}}}
+	}`)
+
+	if resObj == nil {
+		return
+	}
+
+	aggs, _ = resObj["aggregations"].(map[string]interface{})
+	expected = map[string]interface{}{
+		"buckets": []interface{}{
+			map[string]interface{}{"key": "Sao Jose", "doc_count": float64(1)},
+			map[string]interface{}{"key": "Sao Paulo", "doc_count": float64(1)},
+		},
+		"sum_other_doc_count": float64(0),
+	}
+
+	if !reflect.DeepEqual(aggs["by_city"], expected) {
+		t.Errorf("Unexpected aggregation of the keyword sub-field: %+v", resObj)
+	}
+
+	for _, dsl := range []string{
+		`{"query": {"$and": [{"kind": "company"}]}, "aggs": {"by_state": {"terms": {}}}}`,
+		`{"query": {"$and": [{"kind": "company"}]}, "aggs": {"by_city": {"terms": {"field": "city"}}}}`,
+		`{"query": {"$and": [{"kind": "company"}]}, "aggs": {"by_state": {"unknown": {"field": "state"}}}}`,
+	} {
+		res, err := http.Post(ts.URL+"/search-terms-agg", "application/json", bytes.NewBufferString(dsl))
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("Invalid aggregation should fail: %s", dsl)
+		}
+	}
+}
+
+func TestSearchMetricAggregations(t *testing.T) {
+	handler := getSearchHandler()
+
+	router := httprouter.New()
+	router.Handle("POST", "/:index", handler.ServeHTTP)
+	ts := httptest.NewServer(router)
+
+	defer func() {
+		handler.search.DeleteIndex("search-metric-agg")
+		ts.Close()
+		handler.search.Close()
+	}()
+
+	ind, err := handler.search.CreateIndex("search-metric-agg")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = ind.SetMapping(nsindex.Metadata{
+		"kind":       nsindex.Metadata{"type": "string"},
+		"employees":  nsindex.Metadata{"type": "int"},
+		"revenue":    nsindex.Metadata{"type": "float"},
+		"founded_at": nsindex.Metadata{"type": "date", "format": nsindex.DateISO8601},
+	})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for i, doc := range []string{
+		`{"kind": "company", "employees": 10, "revenue": 1.5, "founded_at": "2010-01-10"}`,
+		`{"kind": "company", "employees": 25, "revenue": 12, "founded_at": "2010-02-20"}`,
+		`{"kind": "company", "employees": 10, "revenue": 19.5, "founded_at": "2010-05-01"}`,
+		`{"kind": "company", "employees": 5, "founded_at": "2012-12-31T23:59:00Z"}`,
+		`{"kind": "person", "employees": 1000, "revenue": 1000, "founded_at": "2010-01-01"}`,
+	} {
+		if err = ind.Add(uint64(i), []byte(doc), nil); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	resObj := doSearch(t, ts.URL+"/search-metric-agg", `{
+		"query": {"$and": [{"kind": "company"}]},
+		"size": 0,
+		"aggs": {
+			"employees": {"stats": {"field": "employees"}},
+			"max_revenue": {"max": {"field": "revenue"}},
+			"revenue": {"histogram": {"field": "revenue", "interval": 10}},
+			"by_quarter": {"date_histogram": {"field": "founded_at", "calendar_interval": "quarter"}}
+		}
+	}`)
+
+	if resObj == nil {
+		return
+	}
+
+	aggs, _ := resObj["aggregations"].(map[string]interface{})
+
+	for name, expected := range map[string]interface{}{
+		"employees": map[string]interface{}{
+			"count": float64(4), "min": float64(5), "max": float64(25), "avg": float64(12.5), "sum": float64(50),
+		},
+		"max_revenue": map[string]interface{}{"value": 19.5},
+		"revenue": map[string]interface{}{
+			"buckets": []interface{}{
+				map[string]interface{}{"key": float64(0), "doc_count": float64(1)},
+				map[string]interface{}{"key": float64(10), "doc_count": float64(2)},
+			},
+		},
+		"by_quarter": map[string]interface{}{
+			"buckets": []interface{}{
+				map[string]interface{}{"key": float64(1262304000000), "key_as_string": "2010-01-01T00:00:00Z", "doc_count": float64(2)},
+				map[string]interface{}{"key": float64(1270080000000), "key_as_string": "2010-04-01T00:00:00Z", "doc_count": float64(1)},
+				map[string]interface{}{"key": float64(1349049600000), "key_as_string": "2012-10-01T00:00:00Z", "doc_count": float64(1)},
+			},
+		},
+	} {
+		if !reflect.DeepEqual(aggs[name], expected) {
+			t.Errorf("Unexpected aggregation %s: %+v", name, aggs[name])
+		}
+	}
+
+	for _, dsl := range []string{
+		`{"query": {"$and": [{"kind": "company"}]}, "aggs": {"a": {"stats": {"field": "kind"}}}}`,
+		`{"query": {"$and": [{"kind": "company"}]}, "aggs": {"a": {"histogram": {"field": "revenue"}}}}`,
+		`{"query": {"$and": [{"kind": "company"}]}, "aggs": {"a": {"date_histogram": {"field": "founded_at", "calendar_interval": "century"}}}}`,
+		`{"query": {"$and": [{"kind": "company"}]}, "aggs": {"a": {"date_histogram": {"field": "revenue", "calendar_interval": "year"}}}}`,
+	} {
+		res, err := http.Post(ts.URL+"/search-metric-agg", "application/json", bytes.NewBufferString(dsl))
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("Invalid aggregation should fail: %s", dsl)
+		}
+	}
+}
+
+func TestSearchCardinalityAggregation(t *testing.T) {
+	handler := getSearchHandler()
+
+	router := httprouter.New()
+	router.Handle("POST", "/:index", handler.ServeHTTP)
+	ts := httptest.NewServer(router)
+
+	defer func() {
+		handler.search.DeleteIndex("search-cardinality-agg")
+		ts.Close()
+		handler.search.Close()
+	}()
+
+	ind, err := handler.search.CreateIndex("search-cardinality-agg")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = ind.SetMapping(nsindex.Metadata{
+		"kind":      nsindex.Metadata{"type": "string"},
+		"city":      nsindex.Metadata{"type": "keyword"},
+		"employees": nsindex.Metadata{"type": "int"},
+		"address": nsindex.Metadata{
+			"type":   "string",
+			"fields": nsindex.Metadata{"raw": nsindex.Metadata{"type": "keyword"}},
+		},
+	})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for i, doc := range []string{
+		`{"kind": "company", "city": "Florianopolis", "employees": 10, "address": "Rua Sao Jose"}`,
+		`{"kind": "company", "city": "Sao Paulo", "employees": 10, "address": "Rua Sao Paulo"}`,
+		`{"kind": "company", "city": "Florianopolis", "employees": 20, "address": "Rua Sao Jose"}`,
+		`{"kind": "company", "city": "Curitiba", "employees": 30, "address": "Rua Sao Paulo"}`,
+		`{"kind": "person", "city": "Recife", "employees": 40, "address": "Rua Recife"}`,
+	} {
+		if err = ind.Add(uint64(i), []byte(doc), nil); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	resObj := doSearch(t, ts.URL+"/search-cardinality-agg", `{
+		"query": {"$and": [{"kind": "company"}]},
+		"aggs": {
+			"cities": {"cardinality": {"field": "city"}},
+			"sizes": {"cardinality": {"field": "employees", "precision": 10}},
+			"addresses": {"cardinality": {"field": "address.raw"}}
+		}
+	}`)
+
+	if resObj == nil {
+		return
+	}
+
+	aggs, _ := resObj["aggregations"].(map[string]interface{})
+
+	if !reflect.DeepEqual(aggs["cities"], map[string]interface{}{"value": float64(3)}) ||
+		!reflect.DeepEqual(aggs["sizes"], map[string]interface{}{"value": float64(3)}) ||
+		!reflect.DeepEqual(aggs["addresses"], map[string]interface{}{"value": float64(2)}) {
+		t.Errorf("Unexpected aggregations: %+v", aggs)
+	}
+
+	for _, dsl := range []string{
+		`{"query": {"$and": [{"kind": "company"}]}, "aggs": {"a": {"cardinality": {"field": "city", "precision": 30}}}}`,
+		`{"query": {"$and": [{"kind": "company"}]}, "aggs": {"a": {"cardinality": {"field": "address"}}}}`,
+	} {
+		res, err := http.Post(ts.URL+"/search-cardinality-agg", "application/json", bytes.NewBufferString(dsl))
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("Invalid cardinality should fail: %s", dsl)
+		}
+	}
+}
+
+func TestSearchJoin(t *testing.T) {
+	handler := getSearchHandler()
+
+	router := httprouter.New()
+	router.Handle("POST", "/:index", handler.ServeHTTP)
+	ts := httptest.NewServer(router)
+
+	defer func() {
+		handler.search.DeleteIndex("join-companies")
+		handler.search.DeleteIndex("join-partners")
+		ts.Close()
+		handler.search.Close()
+	}()
+
+	for name, docs := range map[string][]string{
+		"join-companies": {
+			`{"id": 1, "kind": "company", "name": "Neoway"}`,
+			`{"id": 2, "kind": "company", "name": "Google"}`,
+			`{"id": 3, "kind": "company", "name": "Facebook"}`,
+		},
+		"join-partners": {
+			`{"company_id": 1, "role": "ceo", "name": "Alice"}`,
+			`{"company_id": 1, "role": "cto", "name": "Bob"}`,
+			`{"company_id": 2, "role": "ceo", "name": "Carol"}`,
+			`{"company_id": 3, "role": "cto", "name": "Dave"}`,
+		},
+	} {
+		ind, err := handler.search.CreateIndex(name)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		err = ind.SetMapping(nsindex.Metadata{
+			"id":         nsindex.Metadata{"type": "uint"},
+			"company_id": nsindex.Metadata{"type": "uint"},
+			"kind":       nsindex.Metadata{"type": "string"},
+			"role":       nsindex.Metadata{"type": "keyword"},
+			"name":       nsindex.Metadata{"type": "string"},
+		})
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		for i, doc := range docs {
+			if err = ind.Add(uint64(i), []byte(doc), nil); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}
+
+	join := func(joinType string) string {
+		return `{"query": {"$and": [{"kind": "company"}, {"$join": {
+			"index": "join-partners",
+			"query": {"$and": [{"role": "ceo"}]},
+			"foreign_field": "company_id",
+			"field": "id",
+			"type": "` + joinType + `",
+			"as": "ceos"
+		}}]}, "sort": "_id"}`
+	}
+
+	// the second pass evaluates the joins from the materialized join
+	for _, materialized := range []bool{false, true} {
+		if materialized {
+			err := handler.search.CreateJoin("partners", "join-companies", "id", "join-partners", "company_id")
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+		}
+
+		for _, tc := range []struct {
+			joinType string
+			expected []float64
+			ceos     []string
+		}{
+			{"inner", []float64{1, 2}, []string{"Alice", "Carol"}},
+			{"semi", []float64{1, 2}, nil},
+			{"anti", []float64{3}, nil},
+		} {
+			resObj := doSearch(t, ts.URL+"/join-companies", join(tc.joinType))
+
+			if resObj == nil {
+				return
+			}
+
+			results, _ := resObj["results"].([]interface{})
+
+			if len(results) != len(tc.expected) {
+				t.Errorf("Unexpected %s join results (materialized: %v): %+v", tc.joinType, materialized, resObj)
+				continue
+			}
+
+			for i, result := range results {
+				doc := result.(map[string]interface{})
+
+				if doc["id"] != tc.expected[i] {
+					t.Errorf("Unexpected %s join results (materialized: %v): %+v", tc.joinType, materialized, results)
+					break
+				}
+
+				joined, _ := doc["_joined"].(map[string]interface{})
+
+				if tc.ceos == nil {
+					if joined != nil {
+						t.Errorf("Only inner joins return related documents: %+v", doc)
+					}
+
+					continue
+				}
+
+				ceos, _ := joined["ceos"].([]interface{})
+
+				if len(ceos) != 1 || ceos[0].(map[string]interface{})["name"] != tc.ceos[i] {
+					t.Errorf("Unexpected related documents: %+v", doc)
+				}
+			}
+		}
+	}
+
+	res, err := http.Post(ts.URL+"/join-companies", "application/json", bytes.NewBufferString(join("outer")))
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Error("Invalid join type should fail")
+	}
+}
+
+func TestSearchLookup(t *testing.T) {
+	handler := getSearchHandler()
+
+	router := httprouter.New()
+	router.Handle("POST", "/:index", handler.ServeHTTP)
+	ts := httptest.NewServer(router)
+
+	defer func() {
+		handler.search.DeleteIndex("lookup-companies")
+		handler.search.DeleteIndex("lookup-partners")
+		ts.Close()
+		handler.search.Close()
+	}()
+
+	for name, docs := range map[string][]string{
+		"lookup-companies": {
+			`{"id": 1, "kind": "company", "name": "Neoway", "partners": 2}`,
+			`{"id": 2, "kind": "company", "name": "Google"}`,
+			`{"id": 3, "kind": "company", "name": "Facebook"}`,
+		},
+		"lookup-partners": {
+			`{"company_id": 1, "name": "Alice"}`,
+			`{"company_id": 1, "name": "Bob"}`,
+			`{"company_id": 2, "name": "Carol"}`,
+		},
+	} {
+		ind, err := handler.search.CreateIndex(name)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		err = ind.SetMapping(nsindex.Metadata{
+			"id":         nsindex.Metadata{"type": "uint"},
+			"company_id": nsindex.Metadata{"type": "uint"},
+			"kind":       nsindex.Metadata{"type": "string"},
+			"name":       nsindex.Metadata{"type": "string"},
+		})
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		for i, doc := range docs {
+			if err = ind.Add(uint64(i), []byte(doc), nil); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}
+
+	resObj := doSearch(t, ts.URL+"/lookup-companies", `{
+		"query": {"$and": [{"kind": "company"}]},
+		"sort": "_id",
+		"lookup": {"index": "lookup-partners", "field": "id", "foreign_field": "company_id", "as": "partners"}
+	}`)
+
+	if resObj == nil {
+		return
+	}
+
+	results, _ := resObj["results"].([]interface{})
+
+	if len(results) != 3 {
+		t.Errorf("Unexpected results: %+v", resObj)
+		return
+	}
+
+	for i, expected := range [][]string{{"Alice", "Bob"}, {"Carol"}, nil} {
+		doc := results[i].(map[string]interface{})
+		lookups, _ := doc["_lookups"].(map[string]interface{})
+		partners, _ := lookups["partners"].([]interface{})
+		names := []string{}
+
+		for _, partner := range partners {
+			names = append(names, partner.(map[string]interface{})["name"].(string))
+		}
+
+		if len(names) != len(expected) || (len(expected) > 0 && !reflect.DeepEqual(names, expected)) {
+			t.Errorf("Unexpected partners of %+v", doc)
+		}
+	}
+
+	// fields of the documents aren't overwritten by lookups
+	if partners := results[0].(map[string]interface{})["partners"]; partners != float64(2) {
+		t.Errorf("Unexpected partners field: %v", partners)
+	}
+
+	res, err := http.Post(ts.URL+"/lookup-companies", "application/json",
+		bytes.NewBufferString(`{"query": {"$and": [{"kind": "company"}]}, "lookup": {"index": "lookup-partners"}}`))
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Error("Invalid lookup should fail")
+	}
+}
+
+func TestSearchMultiIndex(t *testing.T) {
+	handler := getSearchHandler()
+
+	router := httprouter.New()
+	router.Handle("POST", "/:index", handler.ServeHTTP)
+	ts := httptest.NewServer(router)
+
+	defer func() {
+		handler.search.DeleteIndex("multi-br")
+		handler.search.DeleteIndex("multi-us")
+		handler.search.DeleteIndex("other-multi")
+		ts.Close()
+		handler.search.Close()
+	}()
+
+	for name, docs := range map[string][]string{
+		"multi-br": {
+			`{"kind": "company", "revenue": 10, "state": "SC"}`,
+			`{"kind": "company", "revenue": 30, "state": "SP"}`,
+			`{"kind": "company", "revenue": 20, "state": "SC"}`,
+		},
+		"multi-us": {
+			`{"kind": "company", "revenue": 30, "state": "NY"}`,
+			`{"kind": "company", "revenue": 5, "state": "CA"}`,
+			`{"kind": "company", "revenue": 20, "state": "NY"}`,
+		},
+		"other-multi": {
+			`{"kind": "company", "revenue": 50, "state": "SC"}`,
+		},
+	} {
+		ind, err := handler.search.CreateIndex(name)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		err = ind.SetMapping(nsindex.Metadata{
+			"kind":    nsindex.Metadata{"type": "string"},
+			"revenue": nsindex.Metadata{"type": "float", "doc_values": true},
+			"state":   nsindex.Metadata{"type": "keyword"},
+		})
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		for i, doc := range docs {
+			if err = ind.Add(uint64(i), []byte(doc), nil); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}
+
+	hitNames := func(resObj map[string]interface{}) []string {
+		results, _ := resObj["results"].([]interface{})
+		names := make([]string, len(results))
+
+		for i, result := range results {
+			doc := result.(map[string]interface{})
+			names[i] = fmt.Sprintf("%v/%v", doc["_index"], doc["revenue"])
+		}
+
+		return names
+	}
+
+	dsl := `{"query": {"$and": [{"kind": "company"}]}, "sort": [{"revenue": "desc"}], "size": 3,
+		"aggs": {"states": {"terms": {"field": "state"}}}`
+
+	for _, pattern := range []string{"multi-*", "multi-us,multi-br", "multi-br,multi-*"} {
+		resObj := doSearch(t, ts.URL+"/"+pattern, dsl+`}`)
+
+		if resObj == nil {
+			return
+		}
+
+		expected := []string{"multi-us/30", "multi-br/30", "multi-br/20"}
+
+		if names := hitNames(resObj); !reflect.DeepEqual(names, expected) || resObj["total"] != float64(6) {
+			t.Errorf("Unexpected results of '%s': %v, total: %v", pattern, names, resObj["total"])
+			return
+		}
+
+		aggs, _ := resObj["aggregations"].(map[string]interface{})
+		buckets, _ := aggs["states"].(map[string]interface{})["buckets"].([]interface{})
+
+		if len(buckets) != 4 || fmt.Sprint(buckets[0]) != "map[doc_count:2 key:NY]" {
+			t.Errorf("Unexpected aggregation of '%s': %v", pattern, aggs)
+		}
+
+		// the cursor keeps the index of the hits with the same id
+		next, _ := json.Marshal(resObj["next"])
+
+		if string(next) != `[20,2,"multi-br"]` {
+			t.Errorf("Unexpected cursor: %s", next)
+			return
+		}
+
+		resObj = doSearch(t, ts.URL+"/"+pattern, dsl+`, "search_after": `+string(next)+`}`)
+
+		if resObj == nil {
+			return
+		}
+
+		expected = []string{"multi-us/20", "multi-br/10", "multi-us/5"}
+
+		if names := hitNames(resObj); !reflect.DeepEqual(names, expected) {
+			t.Errorf("Unexpected next page of '%s': %v", pattern, names)
+		}
+	}
+
+	resObj := map[string]interface{}{}
+	res, err := http.Post(ts.URL+"/multi-br,missing-multi", "application/json", bytes.NewBufferString(dsl+`}`))
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&resObj)
+	res.Body.Close()
+
+	if err != nil || resObj["error"] != "Index 'missing-multi' doesn't exists." {
+		t.Errorf("Missing indices should fail: %v, %v", resObj, err)
+	}
+}
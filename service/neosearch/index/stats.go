@@ -0,0 +1,69 @@
+package index
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch"
+	"github.com/NeowayLabs/neosearch/service/neosearch/handler"
+	"github.com/julienschmidt/httprouter"
+)
+
+type StatsHandler struct {
+	handler.DefaultHandler
+	search *neosearch.NeoSearch
+}
+
+func NewStatsHandler(search *neosearch.NeoSearch) *StatsHandler {
+	return &StatsHandler{
+		search: search,
+	}
+}
+
+// ServeHTTP returns the statistics of the index on GET /:index/_stats.
+// The sizes are estimated by the stores unless the query parameter exact
+// is true, then the keys, terms and postings are counted from a scan of
+// the index.
+func (handler *StatsHandler) ServeHTTP(res http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	handler.ProcessVars(ps)
+	indexName := handler.GetIndexName()
+	exact := false
+
+	if exists, err := handler.search.IndexExists(indexName); err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		handler.Error(res, err.Error())
+		return
+	} else if !exists {
+		res.WriteHeader(http.StatusNotFound)
+		handler.Error(res, "Index '"+indexName+"' doesn't exists.")
+		return
+	}
+
+	if param := req.URL.Query().Get("exact"); param != "" {
+		var err error
+
+		if exact, err = strconv.ParseBool(param); err != nil {
+			res.WriteHeader(http.StatusBadRequest)
+			handler.Error(res, "Invalid exact: "+param)
+			return
+		}
+	}
+
+	index, err := handler.search.OpenIndex(indexName)
+
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		handler.Error(res, err.Error())
+		return
+	}
+
+	stats, err := index.Stats(exact)
+
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		handler.Error(res, err.Error())
+		return
+	}
+
+	handler.WriteJSONObject(res, stats)
+}
@@ -0,0 +1,89 @@
+package index
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	nsindex "github.com/NeowayLabs/neosearch/lib/neosearch/index"
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestStats(t *testing.T) {
+	searchHandler := getSearchHandler()
+	handler := NewStatsHandler(searchHandler.search)
+
+	router := httprouter.New()
+	router.Handle("GET", "/:index/_stats", handler.ServeHTTP)
+	ts := httptest.NewServer(router)
+
+	defer func() {
+		handler.search.DeleteIndex("stats-svc")
+		ts.Close()
+		handler.search.Close()
+	}()
+
+	ind, err := handler.search.CreateIndex("stats-svc")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for id, doc := range []string{`{"name": "Neoway"}`, `{"name": "Neoway Labs"}`} {
+		if err = ind.Add(uint64(id), []byte(doc), nil); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	res, err := http.Get(ts.URL + "/stats-svc/_stats?exact=true")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	content, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	stats := nsindex.Stats{}
+
+	if err != nil || res.StatusCode != http.StatusOK {
+		t.Errorf("Unexpected stats response: %d, %s, %v", res.StatusCode, content, err)
+		return
+	}
+
+	if err = json.Unmarshal(content, &stats); err != nil || stats.Index != "stats-svc" || !stats.Exact ||
+		stats.Documents != 2 || len(stats.Storages) == 0 || len(stats.TopPostings) == 0 {
+		t.Errorf("Unexpected stats: %s, %v", content, err)
+		return
+	}
+
+	if res, err = http.Get(ts.URL + "/stats-svc/_stats"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	content, err = ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	stats = nsindex.Stats{}
+
+	if err = json.Unmarshal(content, &stats); err != nil || res.StatusCode != http.StatusOK ||
+		stats.Exact || stats.Documents != 2 || len(stats.Storages) == 0 || len(stats.TopPostings) != 0 {
+		t.Errorf("Unexpected approximate stats: %d, %s, %v", res.StatusCode, content, err)
+		return
+	}
+
+	if res, err = http.Get(ts.URL + "/stats-svc/_stats?exact=yes"); err != nil || res.StatusCode != http.StatusBadRequest {
+		t.Errorf("Stats with invalid exact should fail: %v, %v", res, err)
+		return
+	}
+
+	if res, err = http.Get(ts.URL + "/stats-svc-missing/_stats"); err != nil || res.StatusCode != http.StatusNotFound {
+		t.Errorf("Stats of missing index should fail: %v, %v", res, err)
+	}
+}
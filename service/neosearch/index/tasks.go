@@ -0,0 +1,150 @@
+package index
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// taskTTL is how long the status of finished tasks is kept
+const taskTTL = 10 * time.Minute
+
+// taskRegistry keeps the background tasks of a handler by id. Finished
+// tasks are evicted after ttl.
+type taskRegistry struct {
+	mutex  sync.Mutex
+	lastID uint64
+	ttl    time.Duration
+	tasks  map[string]*task
+}
+
+// task is a request running in background. Its status is polled with GET
+// and it's canceled with DELETE.
+type task struct {
+	mutex    sync.Mutex
+	id       string
+	progress interface{}
+	done     bool
+	err      error
+	canceled bool
+	cancel   chan struct{}
+	finished chan struct{}
+
+	// finishedAt is when fn returned
+	finishedAt time.Time
+}
+
+func newTaskRegistry() *taskRegistry {
+	return &taskRegistry{
+		ttl:   taskTTL,
+		tasks: make(map[string]*task),
+	}
+}
+
+// start runs fn in a new task and returns the task
+func (r *taskRegistry) start(fn func(t *task) error) *task {
+	r.mutex.Lock()
+	r.evict()
+	r.lastID++
+	t := &task{
+		id:       strconv.FormatUint(r.lastID, 10),
+		cancel:   make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+	r.tasks[t.id] = t
+	r.mutex.Unlock()
+
+	go func() {
+		err := fn(t)
+
+		t.mutex.Lock()
+		t.done, t.err = true, err
+		t.finishedAt = time.Now()
+		t.mutex.Unlock()
+
+		close(t.finished)
+	}()
+
+	return t
+}
+
+func (r *taskRegistry) get(id string) (*task, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.evict()
+	t, ok := r.tasks[id]
+	return t, ok
+}
+
+// evict forgets the tasks finished for longer than ttl. The registry mutex
+// must be locked.
+func (r *taskRegistry) evict() {
+	expired := time.Now().Add(-r.ttl)
+
+	for id, t := range r.tasks {
+		t.mutex.Lock()
+		evicted := t.done && t.finishedAt.Before(expired)
+		t.mutex.Unlock()
+
+		if evicted {
+			delete(r.tasks, id)
+		}
+	}
+}
+
+// remove forgets the task id, canceling it if it's running
+func (r *taskRegistry) remove(id string) (*task, bool) {
+	r.mutex.Lock()
+	t, ok := r.tasks[id]
+	delete(r.tasks, id)
+	r.mutex.Unlock()
+
+	if ok {
+		t.stop()
+	}
+
+	return t, ok
+}
+
+func (t *task) setProgress(progress interface{}) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.progress = progress
+}
+
+// wait blocks until the task is done
+func (t *task) wait() {
+	<-t.finished
+}
+
+// stop cancels the task if it's running
+func (t *task) stop() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if !t.done && !t.canceled {
+		t.canceled = true
+		close(t.cancel)
+	}
+}
+
+// status returns the JSON representation of the task
+func (t *task) status() map[string]interface{} {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	status := map[string]interface{}{
+		"task":     t.id,
+		"done":     t.done,
+		"canceled": t.canceled,
+		"progress": t.progress,
+	}
+
+	if t.err != nil {
+		status["error"] = t.err.Error()
+	}
+
+	return status
+}
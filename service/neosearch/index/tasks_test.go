@@ -0,0 +1,40 @@
+package index
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTaskRegistryEviction(t *testing.T) {
+	registry := newTaskRegistry()
+	registry.ttl = 50 * time.Millisecond
+
+	release := make(chan struct{})
+	running := registry.start(func(t *task) error {
+		<-release
+		return nil
+	})
+	finished := registry.start(func(t *task) error {
+		return nil
+	})
+
+	finished.wait()
+
+	if _, ok := registry.get(finished.id); !ok {
+		t.Error("Finished tasks should be kept until the ttl")
+		return
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if _, ok := registry.get(finished.id); ok {
+		t.Error("Finished tasks should be evicted after the ttl")
+	}
+
+	if _, ok := registry.get(running.id); !ok {
+		t.Error("Running tasks shouldn't be evicted")
+	}
+
+	close(release)
+	running.wait()
+}
@@ -0,0 +1,103 @@
+package index
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch"
+	nsindex "github.com/NeowayLabs/neosearch/lib/neosearch/index"
+	"github.com/NeowayLabs/neosearch/service/neosearch/handler"
+	"github.com/julienschmidt/httprouter"
+)
+
+type TermsHandler struct {
+	handler.DefaultHandler
+	search *neosearch.NeoSearch
+}
+
+func NewTermsHandler(search *neosearch.NeoSearch) *TermsHandler {
+	return &TermsHandler{
+		search: search,
+	}
+}
+
+// ServeHTTP returns the terms of a field with their document frequencies
+// on GET /:index/_terms/:field. The terms are filtered by the query
+// parameters prefix, regexp, from and to, and paged by size and after,
+// the "next" term of the previous page.
+func (handler *TermsHandler) ServeHTTP(res http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	var opts nsindex.TermsOptions
+
+	handler.ProcessVars(ps)
+	indexName := handler.GetIndexName()
+	query := req.URL.Query()
+
+	if exists, err := handler.search.IndexExists(indexName); err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		handler.Error(res, err.Error())
+		return
+	} else if !exists {
+		res.WriteHeader(http.StatusNotFound)
+		handler.Error(res, "Index '"+indexName+"' doesn't exists.")
+		return
+	}
+
+	opts.Prefix = query.Get("prefix")
+	opts.Regexp = query.Get("regexp")
+
+	for param, bound := range map[string]*interface{}{
+		"from":  &opts.From,
+		"to":    &opts.To,
+		"after": &opts.After,
+	} {
+		if _, ok := query[param]; ok {
+			*bound = query.Get(param)
+		}
+	}
+
+	if size := query.Get("size"); size != "" {
+		var err error
+
+		if opts.Size, err = strconv.Atoi(size); err != nil || opts.Size <= 0 {
+			res.WriteHeader(http.StatusBadRequest)
+			handler.Error(res, "Invalid size: "+size)
+			return
+		}
+	}
+
+	index, err := handler.search.OpenIndex(indexName)
+
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		handler.Error(res, err.Error())
+		return
+	}
+
+	terms, err := index.Terms(ps.ByName("field"), opts)
+
+	if err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		handler.Error(res, err.Error())
+		return
+	}
+
+	if terms == nil {
+		terms = []nsindex.TermFreq{}
+	}
+
+	response := map[string]interface{}{
+		"terms": terms,
+	}
+
+	size := opts.Size
+
+	if size == 0 {
+		size = nsindex.DefaultTermsSize
+	}
+
+	if len(terms) == size {
+		response["next"] = terms[len(terms)-1].Term
+	}
+
+	handler.WriteJSONObject(res, response)
+}
@@ -0,0 +1,87 @@
+package index
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	nsindex "github.com/NeowayLabs/neosearch/lib/neosearch/index"
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestTerms(t *testing.T) {
+	searchHandler := getSearchHandler()
+	handler := NewTermsHandler(searchHandler.search)
+
+	router := httprouter.New()
+	router.Handle("GET", "/:index/_terms/:field", handler.ServeHTTP)
+	ts := httptest.NewServer(router)
+
+	defer func() {
+		handler.search.DeleteIndex("terms-svc")
+		ts.Close()
+		handler.search.Close()
+	}()
+
+	ind, err := handler.search.CreateIndex("terms-svc")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = ind.SetMapping(nsindex.Metadata{"city": nsindex.Metadata{"type": "keyword"}}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for id, doc := range []string{
+		`{"city": "Florianopolis"}`,
+		`{"city": "Sao Paulo"}`,
+		`{"city": "Florianopolis"}`,
+		`{"city": "Sao Jose"}`,
+	} {
+		if err = ind.Add(uint64(id), []byte(doc), nil); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	for _, test := range []struct {
+		query    string
+		status   int
+		expected string
+	}{
+		{"", http.StatusOK, `{"terms":[{"term":"Florianopolis","docs":2},{"term":"Sao Jose","docs":1},{"term":"Sao Paulo","docs":1}]}`},
+		{"?prefix=Sao&size=1", http.StatusOK, `{"next":"Sao Jose","terms":[{"term":"Sao Jose","docs":1}]}`},
+		{"?prefix=Sao&size=1&after=Sao+Jose", http.StatusOK, `{"next":"Sao Paulo","terms":[{"term":"Sao Paulo","docs":1}]}`},
+		{"?prefix=Sao&size=1&after=Sao+Paulo", http.StatusOK, `{"terms":[]}`},
+		{"?from=G&to=Z&regexp=Jo", http.StatusOK, `{"terms":[{"term":"Sao Jose","docs":1}]}`},
+		{"?size=0", http.StatusBadRequest, `{"error":"Invalid size: 0"}`},
+		{"?regexp=(", http.StatusBadRequest, ""},
+	} {
+		res, err := http.Get(ts.URL + "/terms-svc/_terms/city" + test.query)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		content, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		if err != nil || res.StatusCode != test.status || (test.expected != "" && string(content) != test.expected) {
+			t.Errorf("Unexpected terms of %q: %d, %s, %v", test.query, res.StatusCode, content, err)
+		}
+	}
+
+	res, err := http.Get(ts.URL + "/terms-svc-missing/_terms/city")
+
+	if err != nil || res.StatusCode != http.StatusNotFound {
+		t.Errorf("Terms of missing index should fail: %v, %v", res, err)
+		return
+	}
+
+	res.Body.Close()
+}
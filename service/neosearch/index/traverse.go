@@ -0,0 +1,122 @@
+package index
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/NeowayLabs/neosearch/lib/neosearch"
+	"github.com/NeowayLabs/neosearch/lib/neosearch/search"
+	"github.com/NeowayLabs/neosearch/service/neosearch/handler"
+	"github.com/julienschmidt/httprouter"
+)
+
+type TraverseHandler struct {
+	handler.DefaultHandler
+	search *neosearch.NeoSearch
+}
+
+func NewTraverseHandler(search *neosearch.NeoSearch) *TraverseHandler {
+	return &TraverseHandler{
+		search: search,
+	}
+}
+
+// ServeHTTP follows the relations of the request from the documents
+// matched by its query, eg.:
+//
+//	{"query": {"$and": [{"name": "neoway"}]},
+//	 "relations": [
+//		{"index": "companies", "field": "id", "foreign_index": "partners", "foreign_field": "company_id"},
+//		{"index": "partners", "field": "person", "foreign_index": "partners", "foreign_field": "person"},
+//		{"index": "partners", "field": "company_id", "foreign_index": "companies", "foreign_field": "id"}
+//	 ],
+//	 "max_depth": 3, "fan_out": 100, "max_visited": 1000}
+func (handler *TraverseHandler) ServeHTTP(res http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	handler.ProcessVars(ps)
+	indexName := handler.GetIndexName()
+
+	if exists, err := handler.search.IndexExists(indexName); exists != true && err == nil {
+		response := map[string]string{
+			"error": "Index '" + indexName + "' doesn't exists.",
+		}
+
+		handler.WriteJSONObject(res, response)
+		return
+	} else if exists == false && err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		handler.Error(res, err.Error())
+		return
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+
+	if err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		handler.Error(res, err.Error())
+		return
+	}
+
+	request := make(map[string]interface{})
+
+	if err = json.Unmarshal(body, &request); err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		handler.Error(res, err.Error())
+		return
+	}
+
+	query, ok := request["query"].(map[string]interface{})
+
+	if !ok {
+		res.WriteHeader(http.StatusBadRequest)
+		handler.Error(res, "Traverse 'query' field is not a JSON object")
+		return
+	}
+
+	traversal, err := search.ParseTraversal(request)
+
+	if err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		handler.Error(res, err.Error())
+		return
+	}
+
+	index, err := handler.search.OpenIndex(indexName)
+
+	if err != nil {
+		handler.Error(res, err.Error())
+		return
+	}
+
+	results, err := search.Traverse(index, query, traversal, handler.search)
+
+	if err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		handler.Error(res, err.Error())
+		return
+	}
+
+	documents := make([]map[string]interface{}, len(results.Visits))
+
+	for idx, visit := range results.Visits {
+		obj := make(map[string]interface{})
+
+		if err = json.Unmarshal([]byte(visit.Doc), &obj); err != nil {
+			res.WriteHeader(http.StatusInternalServerError)
+			handler.Error(res, err.Error())
+			return
+		}
+
+		obj["_index"] = visit.Index
+		obj["_id"] = visit.ID
+		obj["_depth"] = visit.Depth
+		obj["_path"] = visit.Path
+		documents[idx] = obj
+	}
+
+	handler.WriteJSONObject(res, map[string]interface{}{
+		"total":     len(documents),
+		"truncated": results.Truncated,
+		"results":   documents,
+	})
+}
@@ -0,0 +1,129 @@
+package index
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	nsindex "github.com/NeowayLabs/neosearch/lib/neosearch/index"
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestTraverse(t *testing.T) {
+	searchHandler := getSearchHandler()
+	handler := NewTraverseHandler(searchHandler.search)
+
+	router := httprouter.New()
+	router.Handle("POST", "/:index/:id", handler.ServeHTTP)
+	ts := httptest.NewServer(router)
+
+	defer func() {
+		handler.search.DeleteIndex("traverse-companies")
+		handler.search.DeleteIndex("traverse-partners")
+		ts.Close()
+		handler.search.Close()
+	}()
+
+	for name, docs := range map[string][]string{
+		"traverse-companies": {
+			`{"id": 1, "name": "Neoway"}`,
+			`{"id": 2, "name": "Google"}`,
+			`{"id": 3, "name": "Facebook"}`,
+			`{"id": 4, "name": "Twitter"}`,
+		},
+		"traverse-partners": {
+			`{"company_id": 1, "person": "alice"}`,
+			`{"company_id": 1, "person": "bob"}`,
+			`{"company_id": 2, "person": "alice"}`,
+			`{"company_id": 3, "person": "bob"}`,
+			`{"company_id": 2, "person": "carol"}`,
+			`{"company_id": 4, "person": "dave"}`,
+		},
+	} {
+		ind, err := handler.search.CreateIndex(name)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		err = ind.SetMapping(nsindex.Metadata{
+			"id":         nsindex.Metadata{"type": "uint"},
+			"company_id": nsindex.Metadata{"type": "uint"},
+			"person":     nsindex.Metadata{"type": "keyword"},
+			"name":       nsindex.Metadata{"type": "string"},
+		})
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		for i, doc := range docs {
+			if err = ind.Add(uint64(i), []byte(doc), nil); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}
+
+	// companies of the partners of neoway
+	traverse := func(fanOut string) string {
+		return `{"query": {"$and": [{"name": "neoway"}]},
+			"relations": [
+				{"index": "traverse-companies", "field": "id", "foreign_index": "traverse-partners", "foreign_field": "company_id"},
+				{"index": "traverse-partners", "field": "person", "foreign_index": "traverse-partners", "foreign_field": "person"},
+				{"index": "traverse-partners", "field": "company_id", "foreign_index": "traverse-companies", "foreign_field": "id"}
+			],
+			"max_depth": 3, "fan_out": ` + fanOut + `}`
+	}
+
+	for _, tc := range []struct {
+		fanOut    string
+		visits    []string
+		truncated bool
+		path      string
+	}{
+		{"100", []string{
+			"traverse-companies/0",
+			"traverse-partners/0", "traverse-partners/1",
+			"traverse-partners/2", "traverse-partners/3",
+			"traverse-companies/1", "traverse-companies/2",
+		}, false, "[map[id:0 index:traverse-companies] map[id:1 index:traverse-partners] " +
+			"map[id:3 index:traverse-partners] map[id:2 index:traverse-companies]]"},
+		{"1", []string{
+			"traverse-companies/0",
+			"traverse-partners/0",
+			"traverse-partners/2",
+			"traverse-companies/1",
+		}, true, "[map[id:0 index:traverse-companies] map[id:0 index:traverse-partners] " +
+			"map[id:2 index:traverse-partners] map[id:1 index:traverse-companies]]"},
+	} {
+		resObj := doSearch(t, ts.URL+"/traverse-companies/_traverse", traverse(tc.fanOut))
+
+		if resObj == nil {
+			return
+		}
+
+		results, _ := resObj["results"].([]interface{})
+		visits := make([]string, len(results))
+
+		for i, result := range results {
+			doc := result.(map[string]interface{})
+			visits[i] = doc["_index"].(string) + "/" + fmt.Sprint(doc["_id"])
+		}
+
+		if !reflect.DeepEqual(visits, tc.visits) || resObj["truncated"] != tc.truncated {
+			t.Errorf("Unexpected visits with fan out %s: %v, truncated: %v", tc.fanOut, visits, resObj["truncated"])
+			continue
+		}
+
+		last := results[len(results)-1].(map[string]interface{})
+		path := fmt.Sprint(last["_path"])
+
+		if last["_depth"] != float64(3) || path != tc.path {
+			t.Errorf("Unexpected last visit: %+v", last)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// actionRouter dispatches requests where a wildcard path segment names an
+// API action (eg.: GET /:index/_mapping) instead of a document id or index
+// name. httprouter doesn't allow static segments conflicting with
+// wildcards at the same position, then these routes share the handle.
+type actionRouter struct {
+	param    string
+	actions  map[string]httprouter.Handle
+	fallback httprouter.Handle
+}
+
+func newActionRouter(param string, fallback httprouter.Handle) *actionRouter {
+	return &actionRouter{
+		param:    param,
+		actions:  make(map[string]httprouter.Handle),
+		fallback: fallback,
+	}
+}
+
+// Action adds the handle for requests with the wildcard equals to name
+func (router *actionRouter) Action(name string, handle httprouter.Handle) *actionRouter {
+	router.actions[name] = handle
+	return router
+}
+
+func (router *actionRouter) ServeHTTP(res http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	if handle, ok := router.actions[ps.ByName(router.param)]; ok {
+		handle(res, req, ps)
+		return
+	}
+
+	router.fallback(res, req, ps)
+}
+
+// notFound is the fallback of routes with only actions
+func notFound(res http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	http.NotFound(res, req)
+}
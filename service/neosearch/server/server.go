@@ -46,15 +46,50 @@ func (server *HTTPServer) createRoutes() {
 	getAnalyzeIndexHandler := index.NewGetAnalyzeHandler(server.search)
 	addIndexHandler := index.NewAddHandler(server.search)
 	searchIndexHandler := index.NewSearchHandler(server.search)
+	mappingIndexHandler := index.NewMappingHandler(server.search)
+	traverseIndexHandler := index.NewTraverseHandler(server.search)
+	aliasesHandler := index.NewAliasesHandler(server.search)
+	reindexHandler := index.NewReindexHandler(server.search)
+	backupHandler := index.NewBackupHandler(server.search)
+	statsHandler := index.NewStatsHandler(server.search)
+	termsHandler := index.NewTermsHandler(server.search)
+	compactHandler := index.NewCompactHandler(server.search)
+
+	getIndexActions := newActionRouter("index", indexHandler.ServeHTTP).
+		Action("_aliases", aliasesHandler.ServeHTTP)
+	postIndexActions := newActionRouter("index", searchIndexHandler.ServeHTTP).
+		Action("_aliases", aliasesHandler.ServeHTTP).
+		Action("_reindex", reindexHandler.ServeHTTP)
+	getActions := newActionRouter("id", getIndexHandler.ServeHTTP).
+		Action("_mapping", mappingIndexHandler.ServeHTTP).
+		Action("_stats", statsHandler.ServeHTTP)
+	getTaskActions := newActionRouter("index", getActions.ServeHTTP).
+		Action("_reindex", reindexHandler.ServeHTTP).
+		Action("_compact", compactHandler.ServeHTTP)
+	deleteTaskActions := newActionRouter("index", notFound).
+		Action("_reindex", reindexHandler.ServeHTTP).
+		Action("_compact", compactHandler.ServeHTTP)
+	postActions := newActionRouter("id", addIndexHandler.ServeHTTP).
+		Action("_traverse", traverseIndexHandler.ServeHTTP).
+		Action("_backup", backupHandler.ServeHTTP).
+		Action("_restore", backupHandler.ServeHTTP).
+		Action("_compact", compactHandler.ServeHTTP)
+
+	// GET /:index/:id/_analyze and GET /:index/_terms/:field
+	fieldActions := newActionRouter("field", notFound).
+		Action("_analyze", getAnalyzeIndexHandler.ServeHTTP)
+	getFieldActions := newActionRouter("id", fieldActions.ServeHTTP).
+		Action("_terms", termsHandler.ServeHTTP)
 
 	server.router.Handle("GET", "/", homeHandler.ServeHTTP)
-	server.router.Handle("GET", "/:index", indexHandler.ServeHTTP)
+	server.router.Handle("GET", "/:index", getIndexActions.ServeHTTP)
 	server.router.Handle("PUT", "/:index", createIndexHandler.ServeHTTP)
 	server.router.Handle("DELETE", "/:index", deleteIndexHandler.ServeHTTP)
-	server.router.Handle("POST", "/:index", searchIndexHandler.ServeHTTP)
-	server.router.Handle("GET", "/:index/:id", getIndexHandler.ServeHTTP)
-	server.router.Handle("GET", "/:index/:id/_analyze", getAnalyzeIndexHandler.ServeHTTP)
-	server.router.Handle("POST", "/:index/:id", addIndexHandler.ServeHTTP)
+	server.router.Handle("POST", "/:index", postIndexActions.ServeHTTP)
+	server.router.Handle("GET", "/:index/:id", getTaskActions.ServeHTTP)
+	server.router.Handle("DELETE", "/:index/:id", deleteTaskActions.ServeHTTP)
+	server.router.Handle("GET", "/:index/:id/:field", getFieldActions.ServeHTTP)
+	server.router.Handle("POST", "/:index/:id", postActions.ServeHTTP)
 }
 
 func (server *HTTPServer) GetRoutes() *httprouter.Router {
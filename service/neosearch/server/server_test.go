@@ -257,3 +257,47 @@ func TestRESTGetDocuments(t *testing.T) {
 
 	deleteIndex(t, search, "company")
 }
+
+func TestRESTGetMapping(t *testing.T) {
+	ts, search, _ := getServer(t)
+	defer func() {
+		deleteIndex(t, search, "company")
+		ts.Close()
+		search.Close()
+	}()
+
+	addDocs(t, ts)
+
+	// _mapping shares the route of /:index/:id
+	res, err := http.Get(ts.URL + "/company/_mapping")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	content, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	resObj := map[string]interface{}{}
+
+	err = json.Unmarshal(content, &resObj)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if resObj["error"] != nil {
+		t.Error(resObj["error"])
+		return
+	}
+
+	if _, ok := resObj["mapping"].(map[string]interface{}); !ok {
+		t.Errorf("Invalid mapping response: %s", string(content))
+	}
+}